@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log"
 	"os"
 	"os/signal"
@@ -10,8 +11,10 @@ import (
 	authentication "telegram-api-with-go/internal/auth"
 	"telegram-api-with-go/internal/bot"
 	"telegram-api-with-go/internal/config"
+	"telegram-api-with-go/internal/lifecycle"
 	"telegram-api-with-go/internal/logger"
 	"telegram-api-with-go/internal/session"
+	"telegram-api-with-go/internal/store"
 	"telegram-api-with-go/internal/telegram"
 )
 
@@ -25,8 +28,11 @@ func main() {
 	logger.InitLogger()
 	log := logger.Log
 
-	// Создаем контекст с отменой
-	ctx, cancel := context.WithCancel(context.Background())
+	// Сигнал завершения отменяет signalCtx; компоненты работают в runCtx,
+	// который отменяется только после их остановки
+	signalCtx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+	runCtx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
 	log.Info("Запуск приложения")
@@ -36,43 +42,116 @@ func main() {
 	log.Debug("Инициализировано хранилище сессии")
 
 	// Создаем Telegram клиент
-	client := telegram.NewClient(sessionStorage)
+	client, err := telegram.NewClient(sessionStorage)
+	if err != nil {
+		log.Error("Ошибка создания Telegram клиента", "error", err)
+		os.Exit(1)
+	}
 	log.Debug("Создан Telegram клиент")
 
 	// Создаем аутентификатор
 	authenticator := &authentication.Auth{}
 
-	// Создаем и запускаем Telegram клиент в отдельной горутине
-	go func() {
-		log.Info("Запуск Telegram клиента")
-		if err := client.Run(ctx, authenticator); err != nil {
-			log.Error("Ошибка Telegram клиента", "error", err)
-			os.Exit(1)
-		}
-	}()
-
-	// Создаем бота
-	bot, err := bot.New(client)
-	if err != nil {
-		log.Error("Ошибка создания бота", "error", err)
-		os.Exit(1)
+	// Компоненты запускаются в порядке зависимостей и останавливаются в обратном:
+	// бот прекращает прием обновлений, дожидается обработчиков и записи истории,
+	// затем закрывается хранилище истории и последним отключается MTProto клиент
+	app := lifecycle.New()
+
+	// Telegram клиент работает под управлением супервизора: временные ошибки
+	// приводят к перезапуску, а при неустранимых бот продолжает работать в деградированном режиме
+	supervisor := telegram.NewSupervisor(client, authenticator)
+	clientCtx, disconnect := context.WithCancel(runCtx)
+	clientDone := make(chan struct{})
+	app.Add(lifecycle.Component{
+		Name: "telegram",
+		Start: func(context.Context) error {
+			go func() {
+				defer close(clientDone)
+				if err := supervisor.Run(clientCtx); err != nil && clientCtx.Err() == nil {
+					log.Error("Telegram клиент остановлен, бот работает в деградированном режиме", "error", err)
+				}
+			}()
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			disconnect()
+			select {
+			case <-clientDone:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	})
+
+	// Хранилище истории статусов
+	var presenceStore store.PresenceStore
+	app.Add(lifecycle.Component{
+		Name: "store",
+		Start: func(context.Context) error {
+			var err error
+			presenceStore, err = store.FromConfig()
+			if err != nil {
+				return err
+			}
+			log.Debug("Открыто хранилище истории", "kind", config.PresenceStore, "path", config.PresenceStorePath)
+			return nil
+		},
+		Stop: func(context.Context) error {
+			return presenceStore.Close()
+		},
+	})
+
+	// Бот
+	var b *bot.Bot
+	app.Add(lifecycle.Component{
+		Name: "bot",
+		Start: func(context.Context) error {
+			var err error
+			b, err = bot.New(client, supervisor, presenceStore)
+			if err != nil {
+				return err
+			}
+			log.Info("Бот создан успешно")
+			go func() {
+				err := b.Start(runCtx)
+				if err != nil && !errors.Is(err, context.Canceled) {
+					log.Error("Ошибка работы бота", "error", err)
+				}
+				app.Exited("bot", err)
+			}()
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			return b.Shutdown(ctx)
+		},
+	})
+
+	exitCode := 0
+	if err := app.Start(runCtx); err != nil {
+		log.Error("Ошибка запуска приложения", "error", err)
+		exitCode = 1
+	} else if err := app.Wait(signalCtx); err != nil {
+		log.Error("Компонент завершил работу, остановка приложения", "error", err)
+		exitCode = 1
+	} else {
+		log.Info("Получен сигнал завершения")
+	}
+
+	// Повторный сигнал завершает процесс, не дожидаясь плавной остановки
+	stopSignals()
+
+	log.Info("Завершение работы...", "timeout", config.ShutdownTimeout)
+	report := app.Shutdown(config.ShutdownTimeout)
+	if report.OK() {
+		log.Info("Приложение остановлено", "elapsed", report.Duration, "components", report.String())
+	} else {
+		log.Error("Приложение остановлено с ошибками", "elapsed", report.Duration, "components", report.String())
+		exitCode = 1
+	}
+
+	cancel()
+	if exitCode != 0 {
+		os.Exit(exitCode)
 	}
-	log.Info("Бот создан успешно")
-
-	// Запускаем бота в отдельной горутине
-	go func() {
-		log.Info("Запуск бота")
-		if err := bot.Start(ctx); err != nil {
-			log.Error("Ошибка запуска бота", "error", err)
-			os.Exit(1)
-		}
-	}()
-
-	// Ожидаем сигнала завершения
-	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
-	<-sigCh
-
-	log.Info("Получен сигнал завершения")
-	log.Info("Завершение работы...")
-} 
\ No newline at end of file
+}
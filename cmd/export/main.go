@@ -0,0 +1,118 @@
+// Команда export выгружает историю присутствия пользователя в CSV, JSON или iCalendar.
+//
+// Пример:
+//
+//	go run ./cmd/export -user 123456 -data sessions -format ics -range 2024-05-01..2024-05-31 -tz Europe/Moscow -o may.ics
+//
+// Хранилище по умолчанию берется из PRESENCE_STORE и PRESENCE_STORE_PATH. База bolt
+// блокируется запущенным ботом, поэтому для нее выгрузку удобнее делать командой /export.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+	_ "time/tzdata" // часовые пояса на системах без базы tzdata
+
+	"telegram-api-with-go/internal/config"
+	"telegram-api-with-go/internal/export"
+	"telegram-api-with-go/internal/store"
+
+	"github.com/joho/godotenv"
+)
+
+// errUsage возвращается, если не заданы обязательные флаги
+var errUsage = errors.New("не задан пользователь")
+
+func main() {
+	// Выход выполняется после run, чтобы отложенные закрытия хранилища и файла успели отработать
+	if err := run(); err != nil {
+		if errors.Is(err, errUsage) {
+			flag.Usage()
+			os.Exit(2)
+		}
+		log.Printf("Ошибка: %v", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	// Переменные окружения из .env используются как значения флагов по умолчанию
+	_ = godotenv.Load()
+
+	userID := flag.Int64("user", 0, "ID пользователя")
+	data := flag.String("data", string(export.DataSessions), "что выгружать: sessions или transitions")
+	format := flag.String("format", string(export.FormatCSV), "формат: csv, json или ics")
+	period := flag.String("range", "", "период: 7d, 2w, 12h или YYYY-MM-DD..YYYY-MM-DD (по умолчанию 7d)")
+	tz := flag.String("tz", envOr("TIMEZONE", "UTC"), "часовой пояс времени в выгрузке")
+	storeKind := flag.String("store", envOr("PRESENCE_STORE", store.KindJSONL), "вид хранилища: jsonl или bolt")
+	storePath := flag.String("path", os.Getenv("PRESENCE_STORE_PATH"), "путь к хранилищу (по умолчанию presence или presence.db)")
+	output := flag.String("o", "", "файл выгрузки (по умолчанию стандартный вывод)")
+	flag.Parse()
+
+	if *userID == 0 {
+		return errUsage
+	}
+
+	opts := export.Options{UserID: *userID}
+	var ok bool
+	if opts.Data, ok = export.ParseData(*data); !ok {
+		return fmt.Errorf("некорректный вид данных %q", *data)
+	}
+	if opts.Format, ok = export.ParseFormat(*format); !ok {
+		return fmt.Errorf("некорректный формат %q", *format)
+	}
+	loc, err := time.LoadLocation(*tz)
+	if err != nil {
+		return fmt.Errorf("некорректный часовой пояс: %w", err)
+	}
+	opts.Location = loc
+	if opts.From, opts.To, err = export.ParseRange(*period, time.Now(), loc); err != nil {
+		return fmt.Errorf("некорректный период: %w", err)
+	}
+
+	path := *storePath
+	if path == "" {
+		path = config.DefaultPresenceStorePath(*storeKind)
+	}
+	presenceStore, err := store.Open(*storeKind, path)
+	if err != nil {
+		return fmt.Errorf("открытие хранилища истории: %w", err)
+	}
+	defer presenceStore.Close()
+
+	exporter := export.New(presenceStore)
+	if *output == "" {
+		if err := exporter.Export(os.Stdout, opts); err != nil {
+			return fmt.Errorf("выгрузка: %w", err)
+		}
+		return nil
+	}
+
+	f, err := os.Create(*output)
+	if err != nil {
+		return fmt.Errorf("создание файла выгрузки: %w", err)
+	}
+	err = exporter.Export(f, opts)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		// Недописанный файл не оставляем
+		os.Remove(*output)
+		return fmt.Errorf("выгрузка: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Выгрузка записана в %s\n", *output)
+	return nil
+}
+
+// envOr возвращает значение переменной окружения или def, если она не задана
+func envOr(name, def string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return def
+}
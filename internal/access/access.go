@@ -0,0 +1,219 @@
+package access
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"telegram-api-with-go/internal/fileutil"
+	"telegram-api-with-go/internal/logger"
+)
+
+// Role - роль пользователя бота
+type Role string
+
+const (
+	// RoleViewer - просмотр отслеживаемых пользователей, отчетов и выгрузок
+	RoleViewer Role = "viewer"
+	// RoleAdmin - управление слежением и уведомлениями
+	RoleAdmin Role = "admin"
+	// RoleOwner - владелец аккаунта: диалоги аккаунта и управление ролями
+	RoleOwner Role = "owner"
+)
+
+// ErrOwnerRole возвращается при попытке выдать или отозвать роль владельца командой:
+// владельцы задаются только конфигурацией
+var ErrOwnerRole = errors.New("роль владельца задается в конфигурации")
+
+// ParseRole разбирает роль, которую можно выдать командой
+func ParseRole(s string) (Role, bool) {
+	switch r := Role(s); r {
+	case RoleViewer, RoleAdmin:
+		return r, true
+	}
+	return "", false
+}
+
+// rank - уровень роли; роль с большим уровнем включает права меньших
+func (r Role) rank() int {
+	switch r {
+	case RoleViewer:
+		return 1
+	case RoleAdmin:
+		return 2
+	case RoleOwner:
+		return 3
+	}
+	return 0
+}
+
+// Includes сообщает, включает ли роль права роли required.
+// Пустая роль (нет роли) не включает никаких прав.
+func (r Role) Includes(required Role) bool {
+	return r.rank() > 0 && r.rank() >= required.rank()
+}
+
+// Assignment - роль, выданная пользователю
+type Assignment struct {
+	UserID    int64     `json:"user_id"`
+	Role      Role      `json:"role"`
+	GrantedBy int64     `json:"granted_by,omitempty"`
+	GrantedAt time.Time `json:"granted_at"`
+}
+
+// Registry хранит роли пользователей. Владельцы задаются конфигурацией и не сохраняются,
+// роли admin и viewer выдаются командами и сохраняются в JSON файл.
+type Registry struct {
+	path   string
+	owners map[int64]bool
+	log    *slog.Logger
+	now    func() time.Time
+
+	mu    sync.Mutex
+	roles map[int64]Assignment
+}
+
+// NewRegistry создает реестр ролей с файлом path и владельцами owners
+func NewRegistry(path string, owners []int64) *Registry {
+	r := &Registry{
+		path:   path,
+		owners: make(map[int64]bool, len(owners)),
+		log:    logger.Log,
+		now:    time.Now,
+		roles:  make(map[int64]Assignment),
+	}
+	for _, id := range owners {
+		r.owners[id] = true
+	}
+	return r
+}
+
+// Load читает сохраненные роли. Отсутствие файла не является ошибкой.
+func (r *Registry) Load() error {
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("чтение реестра ролей: %w", err)
+	}
+
+	var assignments []Assignment
+	if err := json.Unmarshal(data, &assignments); err != nil {
+		return fmt.Errorf("разбор реестра ролей %s: %w", r.path, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.roles = make(map[int64]Assignment, len(assignments))
+	for _, a := range assignments {
+		if _, ok := ParseRole(string(a.Role)); !ok {
+			r.log.Warn("Пропущена некорректная роль", "user_id", a.UserID, "role", a.Role)
+			continue
+		}
+		r.roles[a.UserID] = a
+	}
+	r.log.Info("Загружен реестр ролей", "path", r.path, "owners", len(r.owners), "roles", len(r.roles))
+	return nil
+}
+
+// HasOwners сообщает, задан ли хотя бы один владелец
+func (r *Registry) HasOwners() bool {
+	return len(r.owners) > 0
+}
+
+// Role возвращает роль пользователя; пустая строка - роли нет
+func (r *Registry) Role(userID int64) Role {
+	if r.owners[userID] {
+		return RoleOwner
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.roles[userID].Role
+}
+
+// Grant выдает пользователю роль admin или viewer, заменяя прежнюю
+func (r *Registry) Grant(userID int64, role Role, grantedBy int64) error {
+	if r.owners[userID] {
+		return ErrOwnerRole
+	}
+	if _, ok := ParseRole(string(role)); !ok {
+		return fmt.Errorf("некорректная роль %q", role)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	prev, had := r.roles[userID]
+	r.roles[userID] = Assignment{UserID: userID, Role: role, GrantedBy: grantedBy, GrantedAt: r.now().UTC()}
+	if err := r.saveLocked(); err != nil {
+		if had {
+			r.roles[userID] = prev
+		} else {
+			delete(r.roles, userID)
+		}
+		return err
+	}
+	r.log.Info("Выдана роль", "user_id", userID, "role", role, "granted_by", grantedBy)
+	return nil
+}
+
+// Revoke отзывает роль пользователя. removed равно false, если роли не было.
+func (r *Registry) Revoke(userID int64, revokedBy int64) (removed bool, err error) {
+	if r.owners[userID] {
+		return false, ErrOwnerRole
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	prev, ok := r.roles[userID]
+	if !ok {
+		return false, nil
+	}
+	delete(r.roles, userID)
+	if err := r.saveLocked(); err != nil {
+		r.roles[userID] = prev
+		return false, err
+	}
+	r.log.Info("Отозвана роль", "user_id", userID, "role", prev.Role, "revoked_by", revokedBy)
+	return true, nil
+}
+
+// Assignments возвращает владельцев и выданные роли, упорядоченные по роли и ID
+func (r *Registry) Assignments() []Assignment {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	list := make([]Assignment, 0, len(r.owners)+len(r.roles))
+	for id := range r.owners {
+		list = append(list, Assignment{UserID: id, Role: RoleOwner})
+	}
+	for _, a := range r.roles {
+		list = append(list, a)
+	}
+	slices.SortFunc(list, func(a, b Assignment) int {
+		if c := cmp.Compare(b.Role.rank(), a.Role.rank()); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.UserID, b.UserID)
+	})
+	return list
+}
+
+// saveLocked атомарно перезаписывает файл реестра
+func (r *Registry) saveLocked() error {
+	assignments := make([]Assignment, 0, len(r.roles))
+	for _, a := range r.roles {
+		assignments = append(assignments, a)
+	}
+	slices.SortFunc(assignments, func(a, b Assignment) int { return cmp.Compare(a.UserID, b.UserID) })
+	if err := fileutil.WriteJSONAtomic(r.path, assignments); err != nil {
+		return fmt.Errorf("сохранение реестра ролей: %w", err)
+	}
+	return nil
+}
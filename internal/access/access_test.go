@@ -0,0 +1,87 @@
+package access
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestRegistry(t *testing.T, owners ...int64) *Registry {
+	t.Helper()
+	r := NewRegistry(filepath.Join(t.TempDir(), "roles.json"), owners)
+	r.log = slog.New(slog.NewTextHandler(io.Discard, nil))
+	r.now = func() time.Time { return time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC) }
+	return r
+}
+
+func TestRoleIncludes(t *testing.T) {
+	for _, tc := range []struct {
+		role, required Role
+		want           bool
+	}{
+		{RoleOwner, RoleAdmin, true},
+		{RoleOwner, RoleOwner, true},
+		{RoleAdmin, RoleViewer, true},
+		{RoleAdmin, RoleOwner, false},
+		{RoleViewer, RoleAdmin, false},
+		{"", RoleViewer, false},
+		{"", "", false},
+	} {
+		if got := tc.role.Includes(tc.required); got != tc.want {
+			t.Errorf("%q.Includes(%q) = %v, want %v", tc.role, tc.required, got, tc.want)
+		}
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	r := newTestRegistry(t, 1)
+
+	if r.Role(1) != RoleOwner || r.Role(2) != "" {
+		t.Fatalf("roles = %q, %q", r.Role(1), r.Role(2))
+	}
+	if err := r.Grant(2, RoleAdmin, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Grant(3, RoleViewer, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Grant(3, RoleAdmin, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Grant(4, RoleOwner, 1); err == nil {
+		t.Fatal("owner role must not be granted by command")
+	}
+	if err := r.Grant(1, RoleViewer, 1); !errors.Is(err, ErrOwnerRole) {
+		t.Fatalf("Grant(owner) = %v", err)
+	}
+	if _, err := r.Revoke(1, 1); !errors.Is(err, ErrOwnerRole) {
+		t.Fatalf("Revoke(owner) = %v", err)
+	}
+
+	// Роли переживают перезапуск, владельцы берутся только из конфигурации
+	loaded := NewRegistry(r.path, []int64{5})
+	loaded.log = r.log
+	if err := loaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Role(1) != "" || loaded.Role(2) != RoleAdmin || loaded.Role(3) != RoleAdmin || loaded.Role(5) != RoleOwner {
+		t.Fatalf("loaded roles: 1=%q 2=%q 3=%q 5=%q", loaded.Role(1), loaded.Role(2), loaded.Role(3), loaded.Role(5))
+	}
+	list := loaded.Assignments()
+	if len(list) != 3 || list[0].UserID != 5 || list[1].UserID != 2 || list[2].GrantedBy != 2 || !list[2].GrantedAt.Equal(r.now()) {
+		t.Fatalf("assignments = %+v", list)
+	}
+
+	if removed, err := loaded.Revoke(2, 5); err != nil || !removed {
+		t.Fatalf("Revoke = %v, %v", removed, err)
+	}
+	if removed, err := loaded.Revoke(2, 5); err != nil || removed {
+		t.Fatalf("repeated Revoke = %v, %v", removed, err)
+	}
+	if loaded.Role(2) != "" {
+		t.Fatal("role survived revoke")
+	}
+}
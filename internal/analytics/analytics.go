@@ -0,0 +1,123 @@
+package analytics
+
+import (
+	"time"
+
+	"telegram-api-with-go/internal/presence"
+	"telegram-api-with-go/internal/store"
+)
+
+// Confidence - достоверность границ сессии
+type Confidence string
+
+const (
+	// ConfidenceHigh - начало и конец сессии наблюдались по событиям или опросу
+	ConfidenceHigh Confidence = "high"
+	// ConfidenceMedium - конец сессии оценен по сроку статуса online или следующей записи
+	ConfidenceMedium Confidence = "medium"
+	// ConfidenceLow - начало сессии пропущено, известно только время последнего визита
+	ConfidenceLow Confidence = "low"
+)
+
+// Session - период, когда пользователь был в сети
+type Session struct {
+	Start      time.Time
+	End        time.Time
+	Confidence Confidence
+	// Ongoing - сессия продолжается на момент построения
+	Ongoing bool
+}
+
+// Duration возвращает длительность сессии
+func (s Session) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// Sessions восстанавливает сессии по истории переходов, упорядоченной по времени записи.
+// now используется как конец незавершенной сессии.
+func Sessions(records []store.Record, now time.Time) []Session {
+	var (
+		sessions []Session
+		open     *presence.Status
+		lastSeen time.Time
+	)
+
+	for _, rec := range records {
+		status := rec.Status()
+
+		if open != nil {
+			sessions = append(sessions, closeSession(*open, status, rec.RecordedAt))
+			lastSeen = sessions[len(sessions)-1].End
+			open = nil
+		}
+
+		switch status.Kind {
+		case presence.KindOnline:
+			open = &status
+		case presence.KindOffline:
+			// Визит, начало которого не наблюдалось: известен только момент ухода из сети
+			if status.SeenTo.After(lastSeen) {
+				sessions = append(sessions, Session{
+					Start:      status.SeenTo,
+					End:        status.SeenTo,
+					Confidence: ConfidenceLow,
+				})
+				lastSeen = status.SeenTo
+			}
+		}
+	}
+
+	if open != nil {
+		s := Session{Start: open.SeenFrom, End: now, Confidence: ConfidenceHigh, Ongoing: true}
+		if !open.Expires.IsZero() && open.Expires.Before(now) {
+			// Срок статуса истек, а перехода в offline не было
+			s.End = open.Expires
+			s.Confidence = ConfidenceMedium
+			s.Ongoing = false
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions
+}
+
+// closeSession завершает сессию, начатую статусом online, следующим статусом
+func closeSession(online, next presence.Status, nextRecordedAt time.Time) Session {
+	s := Session{Start: online.SeenFrom}
+	switch {
+	case next.Kind == presence.KindOffline && !next.SeenTo.Before(online.SeenFrom):
+		s.End = next.SeenTo
+		s.Confidence = ConfidenceHigh
+	case !online.Expires.IsZero() && online.Expires.Before(nextRecordedAt):
+		s.End = online.Expires
+		s.Confidence = ConfidenceMedium
+	default:
+		s.End = nextRecordedAt
+		s.Confidence = ConfidenceMedium
+	}
+	if s.End.Before(s.Start) {
+		s.End = s.Start
+	}
+	return s
+}
+
+// Clip обрезает сессии по диапазону [from, to). Нулевые границы не ограничивают диапазон.
+func Clip(sessions []Session, from, to time.Time) []Session {
+	var result []Session
+	for _, s := range sessions {
+		if !to.IsZero() && !s.Start.Before(to) {
+			continue
+		}
+		if !from.IsZero() && s.End.Before(from) {
+			continue
+		}
+		if !from.IsZero() && s.Start.Before(from) {
+			s.Start = from
+		}
+		if !to.IsZero() && s.End.After(to) {
+			s.End = to
+			s.Ongoing = false
+		}
+		result = append(result, s)
+	}
+	return result
+}
@@ -0,0 +1,163 @@
+package analytics
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"telegram-api-with-go/internal/presence"
+	"telegram-api-with-go/internal/store"
+
+	"github.com/gotd/td/tg"
+)
+
+var day = time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC)
+
+func at(h, m int) time.Time {
+	return day.Add(time.Duration(h)*time.Hour + time.Duration(m)*time.Minute)
+}
+
+func online(seen, expires time.Time) store.Record {
+	return store.NewRecord(1, presence.Status{Kind: presence.KindOnline, SeenFrom: seen, SeenTo: seen, Expires: expires}, seen)
+}
+
+func offline(wasOnline, recorded time.Time) store.Record {
+	return store.NewRecord(1, presence.Status{Kind: presence.KindOffline, SeenFrom: wasOnline, SeenTo: wasOnline}, recorded)
+}
+
+func recently(recorded time.Time) store.Record {
+	return store.NewRecord(1, presence.FromTG(&tg.UserStatusRecently{}, recorded), recorded)
+}
+
+func TestSessions(t *testing.T) {
+	records := []store.Record{
+		// Полная сессия: online по событию, offline с точным временем
+		online(at(9, 0), at(9, 5)),
+		offline(at(9, 30), at(9, 30)),
+		// Пропущенный визит: опрос показал новое время ухода из сети
+		offline(at(11, 0), at(12, 0)),
+		// Срок online истек, следующей записью стал скрытый статус
+		online(at(13, 0), at(13, 10)),
+		recently(at(15, 0)),
+		// Незавершенная сессия
+		online(at(20, 0), at(23, 0)),
+	}
+	got := Sessions(records, at(21, 0))
+
+	want := []Session{
+		{Start: at(9, 0), End: at(9, 30), Confidence: ConfidenceHigh},
+		{Start: at(11, 0), End: at(11, 0), Confidence: ConfidenceLow},
+		{Start: at(13, 0), End: at(13, 10), Confidence: ConfidenceMedium},
+		{Start: at(20, 0), End: at(21, 0), Confidence: ConfidenceHigh, Ongoing: true},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d sessions, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("session %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestSessionsExpiredWithoutOffline(t *testing.T) {
+	got := Sessions([]store.Record{online(at(10, 0), at(10, 5))}, at(12, 0))
+	if len(got) != 1 || got[0].End != at(10, 5) || got[0].Ongoing || got[0].Confidence != ConfidenceMedium {
+		t.Fatalf("sessions = %+v", got)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	sessions := []Session{
+		{Start: at(9, 0), End: at(9, 30), Confidence: ConfidenceHigh},
+		// Сессия через полночь делится между днями
+		{Start: at(23, 0), End: at(25, 0), Confidence: ConfidenceHigh},
+		{Start: at(26, 0), End: at(26, 15), Confidence: ConfidenceMedium},
+	}
+	sum := Summarize(1, sessions, day, day.AddDate(0, 0, 3), time.UTC)
+
+	if sum.Total != 2*time.Hour+45*time.Minute {
+		t.Fatalf("total = %s", sum.Total)
+	}
+	if sum.FirstSeen != at(9, 0) || sum.LastSeen != at(26, 15) {
+		t.Fatalf("first/last seen = %s / %s", sum.FirstSeen, sum.LastSeen)
+	}
+	if sum.Longest.Start != at(23, 0) || sum.Longest.Duration() != 2*time.Hour {
+		t.Fatalf("longest = %+v", sum.Longest)
+	}
+
+	// 10 мая 2024 - пятница
+	if got := sum.Heatmap[4][9]; got != 30*time.Minute {
+		t.Fatalf("heatmap friday 09:00 = %s", got)
+	}
+	if got := sum.Heatmap[5][0]; got != time.Hour {
+		t.Fatalf("heatmap saturday 00:00 = %s", got)
+	}
+
+	want := []DayTotal{
+		{Date: day, Online: 90 * time.Minute, Sessions: 2},
+		{Date: day.AddDate(0, 0, 1), Online: 75 * time.Minute, Sessions: 1},
+		{Date: day.AddDate(0, 0, 2)},
+	}
+	if len(sum.Daily) != len(want) {
+		t.Fatalf("daily = %+v", sum.Daily)
+	}
+	for i := range want {
+		if sum.Daily[i] != want[i] {
+			t.Errorf("day %d = %+v, want %+v", i, sum.Daily[i], want[i])
+		}
+	}
+}
+
+func TestSummarizeTimezone(t *testing.T) {
+	moscow := time.FixedZone("MSK", 3*60*60)
+	// 22:00-23:00 UTC - это 01:00-02:00 следующего дня по Москве
+	sessions := []Session{{Start: at(22, 0), End: at(23, 0), Confidence: ConfidenceHigh}}
+	sum := Summarize(1, sessions, time.Time{}, time.Time{}, moscow)
+
+	if len(sum.Daily) != 1 {
+		t.Fatalf("daily = %+v", sum.Daily)
+	}
+	if got := sum.Daily[0].Date; got.Day() != 11 || got.Location() != moscow {
+		t.Fatalf("day = %s", got)
+	}
+}
+
+func TestClip(t *testing.T) {
+	sessions := []Session{
+		{Start: at(8, 0), End: at(10, 0)},
+		{Start: at(12, 0), End: at(13, 0), Ongoing: true},
+		{Start: at(18, 0), End: at(19, 0)},
+	}
+	got := Clip(sessions, at(9, 0), at(12, 30))
+	if len(got) != 2 || got[0].Start != at(9, 0) || got[1].End != at(12, 30) || got[1].Ongoing {
+		t.Fatalf("clipped = %+v", got)
+	}
+}
+
+func TestAnalyzer(t *testing.T) {
+	s, err := store.OpenJSONL(filepath.Join(t.TempDir(), "presence"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rec := range []store.Record{
+		online(at(23, 0), at(23, 5)),
+		offline(at(25, 0), at(25, 0)),
+	} {
+		if err := s.Append(rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	a := New(s)
+	a.now = func() time.Time { return at(30, 0) }
+
+	// Период начинается после online, но сессия должна быть учтена с начала периода
+	sum, err := a.Summary(1, day.AddDate(0, 0, 1), day.AddDate(0, 0, 2), time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum.Total != time.Hour || len(sum.Sessions) != 1 || sum.Sessions[0].Start != at(24, 0) {
+		t.Fatalf("summary = %+v", sum)
+	}
+}
@@ -0,0 +1,165 @@
+package analytics
+
+import (
+	"fmt"
+	"time"
+
+	"telegram-api-with-go/internal/store"
+)
+
+// DayTotal - время в сети за календарный день
+type DayTotal struct {
+	// Date - полночь дня в часовом поясе отчета
+	Date     time.Time
+	Online   time.Duration
+	Sessions int
+}
+
+// Summary - сводка присутствия пользователя за период
+type Summary struct {
+	UserID   int64
+	From     time.Time
+	To       time.Time
+	Location *time.Location
+	Sessions []Session
+	// Daily - итоги по каждому дню периода, включая дни без активности
+	Daily []DayTotal
+	Total time.Duration
+	// FirstSeen и LastSeen - первое и последнее известное присутствие в периоде
+	FirstSeen time.Time
+	LastSeen  time.Time
+	// Longest - самая длинная сессия; нулевая, если сессий нет
+	Longest Session
+	// Heatmap - время в сети по дням недели (0 - понедельник) и часам в часовом поясе отчета
+	Heatmap [7][24]time.Duration
+}
+
+// Summarize строит сводку по сессиям в периоде [from, to) в часовом поясе loc
+func Summarize(userID int64, sessions []Session, from, to time.Time, loc *time.Location) Summary {
+	if loc == nil {
+		loc = time.UTC
+	}
+	sum := Summary{
+		UserID:   userID,
+		From:     from,
+		To:       to,
+		Location: loc,
+		Sessions: Clip(sessions, from, to),
+	}
+
+	daily := make(map[time.Time]*DayTotal)
+	for _, s := range sum.Sessions {
+		sum.Total += s.Duration()
+		if s.Duration() > sum.Longest.Duration() || sum.Longest.Start.IsZero() {
+			sum.Longest = s
+		}
+		if sum.FirstSeen.IsZero() || s.Start.Before(sum.FirstSeen) {
+			sum.FirstSeen = s.Start
+		}
+		if s.End.After(sum.LastSeen) {
+			sum.LastSeen = s.End
+		}
+
+		addToHeatmap(&sum.Heatmap, s, loc)
+
+		counted := false
+		for _, part := range splitByDay(s, loc) {
+			day := startOfDay(part.Start, loc)
+			total, ok := daily[day]
+			if !ok {
+				total = &DayTotal{Date: day}
+				daily[day] = total
+			}
+			total.Online += part.Duration()
+			if !counted {
+				total.Sessions++
+				counted = true
+			}
+		}
+	}
+
+	first, last := from, to
+	if first.IsZero() {
+		first = sum.FirstSeen
+	}
+	if last.IsZero() {
+		last = sum.LastSeen
+	}
+	if !first.IsZero() && !last.IsZero() {
+		for day := startOfDay(first, loc); day.Before(last); day = day.AddDate(0, 0, 1) {
+			total, ok := daily[day]
+			if !ok {
+				total = &DayTotal{Date: day}
+			}
+			sum.Daily = append(sum.Daily, *total)
+		}
+	}
+	return sum
+}
+
+// splitByDay делит сессию на части по границам суток в часовом поясе loc
+func splitByDay(s Session, loc *time.Location) []Session {
+	var parts []Session
+	for start := s.Start; ; {
+		next := startOfDay(start, loc).AddDate(0, 0, 1)
+		if !next.Before(s.End) {
+			return append(parts, Session{Start: start, End: s.End, Confidence: s.Confidence, Ongoing: s.Ongoing})
+		}
+		parts = append(parts, Session{Start: start, End: next, Confidence: s.Confidence})
+		start = next
+	}
+}
+
+// addToHeatmap распределяет время сессии по часам недели
+func addToHeatmap(heatmap *[7][24]time.Duration, s Session, loc *time.Location) {
+	for start := s.Start.In(loc); start.Before(s.End); {
+		next := time.Date(start.Year(), start.Month(), start.Day(), start.Hour(), 0, 0, 0, loc).Add(time.Hour)
+		end := s.End
+		if next.Before(end) {
+			end = next
+		}
+		weekday := (int(start.Weekday()) + 6) % 7
+		heatmap[weekday][start.Hour()] += end.Sub(start)
+		start = end
+	}
+}
+
+func startOfDay(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
+
+// Analyzer строит сессии и сводки по истории из хранилища
+type Analyzer struct {
+	store store.PresenceStore
+	now   func() time.Time
+}
+
+// New создает анализатор истории присутствия
+func New(presenceStore store.PresenceStore) *Analyzer {
+	return &Analyzer{store: presenceStore, now: time.Now}
+}
+
+// Sessions возвращает сессии пользователя в периоде [from, to)
+func (a *Analyzer) Sessions(userID int64, from, to time.Time) ([]Session, error) {
+	// Сессия, начавшаяся до from, открывается записью вне периода,
+	// поэтому история читается с начала
+	records, err := a.store.History(userID, time.Time{}, to)
+	if err != nil {
+		return nil, fmt.Errorf("чтение истории пользователя %d: %w", userID, err)
+	}
+	now := a.now()
+	if !to.IsZero() && to.Before(now) {
+		now = to
+	}
+	return Clip(Sessions(records, now), from, to), nil
+}
+
+// Summary возвращает сводку присутствия пользователя за период [from, to) в часовом поясе loc
+func (a *Analyzer) Summary(userID int64, from, to time.Time, loc *time.Location) (Summary, error) {
+	sessions, err := a.Sessions(userID, from, to)
+	if err != nil {
+		return Summary{}, err
+	}
+	return Summarize(userID, sessions, from, to, loc), nil
+}
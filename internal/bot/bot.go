@@ -3,65 +3,350 @@ package bot
 import (
 	"context"
 	"log/slog"
+	"net/url"
+	"sync"
+	"time"
 
+	"telegram-api-with-go/internal/access"
+	"telegram-api-with-go/internal/analytics"
 	"telegram-api-with-go/internal/config"
+	"telegram-api-with-go/internal/consent"
+	"telegram-api-with-go/internal/conversation"
+	"telegram-api-with-go/internal/export"
+	"telegram-api-with-go/internal/i18n"
 	"telegram-api-with-go/internal/logger"
+	"telegram-api-with-go/internal/notify"
+	"telegram-api-with-go/internal/proxy"
+	"telegram-api-with-go/internal/store"
 	"telegram-api-with-go/internal/telegram"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
 )
 
+// API - методы Bot API, которые использует бот
+type API interface {
+	Send(c tgbotapi.Chattable) (tgbotapi.Message, error)
+	GetUpdatesChan(config tgbotapi.UpdateConfig) (tgbotapi.UpdatesChannel, error)
+	MakeRequest(endpoint string, params url.Values) (tgbotapi.APIResponse, error)
+	UploadFile(endpoint string, params map[string]string, fieldname string, file interface{}) (tgbotapi.APIResponse, error)
+}
+
+// Deps - зависимости бота от MTProto клиента
+type Deps struct {
+	Dialogs  telegram.DialogLister
+	Members  telegram.MemberLister
+	Users    telegram.UserResolver
+	Presence telegram.PresenceFetcher
+	// Updates может быть nil, тогда статус отслеживается только опросом
+	Updates telegram.PresenceSubscriber
+	// Status может быть nil, тогда клиент считается всегда доступным
+	Status telegram.StatusProvider
+	// Store - хранилище истории статусов; nil отключает сохранение
+	Store store.PresenceStore
+}
+
 // Bot представляет Telegram бота
 type Bot struct {
-	api    *tgbotapi.BotAPI
-	client *telegram.Client
-	spy    *telegram.SpyService
-	log    *slog.Logger
+	api      API
+	username string
+	router   *Router
+	// webhook - сервер webhook; nil в режиме long polling
+	webhook *WebhookServer
+	// signer подписывает данные кнопок
+	signer   *callbackSigner
+	dialogs  telegram.DialogLister
+	members  telegram.MemberLister
+	users    telegram.UserResolver
+	status   telegram.StatusProvider
+	trackers *telegram.Registry
+	// roles - роли пользователей бота
+	roles *access.Registry
+	// consent - согласия пользователей на отслеживание
+	consent *consent.Registry
+	// langs - языки, выбранные пользователями командой /lang
+	langs *i18n.Preferences
+	// chatLangs - язык последнего отправителя в чате
+	langMu    sync.Mutex
+	chatLangs map[int64]i18n.Lang
+	// conversations - незавершенные диалоги многошаговых команд по чатам,
+	// flows - описания диалогов по именам команд
+	conversations *conversation.Store
+	flows         map[string]*Conversation
+	// notifyPrefs - настройки уведомлений чатов-подписчиков
+	notifyPrefs *notify.Preferences
+	notifier    *notify.Notifier
+	// store, analyzer и exporter - nil, если хранилище истории не задано
+	store    store.PresenceStore
+	analyzer *analytics.Analyzer
+	exporter *export.Exporter
+	// quit закрывается в Shutdown, done - при возврате из Start. Оба канала
+	// создаются вместе с ботом, поэтому Shutdown, вызванный раньше Start,
+	// дожидается его. abort прерывает обработчики обновлений.
+	quit     chan struct{}
+	quitOnce sync.Once
+	done     chan struct{}
+	lifeMu   sync.Mutex
+	abort    context.CancelFunc
+	log      *slog.Logger
 }
 
 // New создает нового бота
-func New(client *telegram.Client) (*Bot, error) {
+func New(client *telegram.Client, supervisor *telegram.Supervisor, presenceStore store.PresenceStore) (*Bot, error) {
+	deps := Deps{
+		Dialogs:  client,
+		Members:  client,
+		Users:    client,
+		Presence: client,
+		Updates:  client,
+		Store:    presenceStore,
+	}
+	if supervisor != nil {
+		deps.Status = supervisor
+	}
+	return NewWithDeps(deps)
+}
+
+// NewWithDeps создает бота с Bot API из конфигурации и произвольными зависимостями от MTProto клиента
+func NewWithDeps(deps Deps) (*Bot, error) {
 	log := logger.Log
 
-	api, err := tgbotapi.NewBotAPI(config.BotToken)
+	httpClient, err := proxy.FromConfig().HTTPClient()
+	if err != nil {
+		log.Error("Ошибка настройки прокси для Bot API", "error", err)
+		return nil, err
+	}
+	httpClient, err = withEndpoint(httpClient, config.BotAPIEndpoint)
+	if err != nil {
+		log.Error("Ошибка настройки адреса Bot API", "error", err)
+		return nil, err
+	}
+
+	api, err := tgbotapi.NewBotAPIWithClient(config.BotToken, httpClient)
 	if err != nil {
 		log.Error("Ошибка создания Telegram API", "error", err)
 		return nil, err
 	}
+	return NewWithAPI(api, api.Self.UserName, deps), nil
+}
 
-	spy := telegram.NewSpyService(client, config.DefaultSpyUserID)
+// NewWithAPI создает бота поверх произвольной реализации Bot API
+func NewWithAPI(api API, username string, deps Deps) *Bot {
+	var (
+		analyzer *analytics.Analyzer
+		exporter *export.Exporter
+	)
+	if deps.Store != nil {
+		analyzer = analytics.New(deps.Store)
+		exporter = export.New(deps.Store)
+	}
+	b := &Bot{
+		api:           api,
+		username:      username,
+		router:        NewRouter(username),
+		signer:        newCallbackSigner(config.CallbackSecret, config.BotToken, config.CallbackTTL),
+		dialogs:       deps.Dialogs,
+		members:       deps.Members,
+		users:         deps.Users,
+		status:        deps.Status,
+		trackers:      telegram.NewRegistry(deps.Presence, deps.Updates, deps.Store, config.TrackerRegistryFile),
+		roles:         access.NewRegistry(config.RolesFile, config.OwnerIDs),
+		consent:       consent.NewRegistry(config.ConsentFile, config.ConsentAuditFile),
+		langs:         i18n.NewPreferences(config.LangFile),
+		chatLangs:     make(map[int64]i18n.Lang),
+		conversations: conversation.NewStore(config.ConversationsFile, config.ConversationTimeout),
+		flows:         make(map[string]*Conversation),
+		notifyPrefs:   notify.NewPreferences(config.NotifySettingsFile),
+		store:         deps.Store,
+		analyzer:      analyzer,
+		exporter:      exporter,
+		quit:          make(chan struct{}),
+		done:          make(chan struct{}),
+		log:           logger.Log,
+	}
+	b.notifier = notify.New(b.sendNotification, b.notifyPrefs, notify.Options{
+		Debounce:   config.NotifyDebounce,
+		DigestHour: config.NotifyDigestHour,
+		Location:   config.Location,
+		Lang:       b.chatLang,
+	})
+	b.trackers.OnTransition(b.notifier.Notify)
+	b.trackers.RequireConsent(b.consent)
+	b.registerCommands()
+	b.registerChatsCallbacks()
+	b.registerConversation(b.spyConversation())
+	return b
+}
 
-	return &Bot{
-		api:    api,
-		client: client,
-		spy:    spy,
-		log:    log,
-	}, nil
+// Shutdown плавно останавливает бота, запущенного Start: прекращает прием обновлений,
+// дожидается обработчиков, уже получивших обновления, затем останавливает слежение
+// с записью истории и рассылку уведомлений. Если ctx истекает раньше, незавершенные
+// обработчики прерываются, а Shutdown возвращает ошибку ctx. Shutdown, вызванный
+// до Start, дожидается, пока Start увидит остановку и вернется.
+func (b *Bot) Shutdown(ctx context.Context) error {
+	b.quitOnce.Do(func() { close(b.quit) })
+	select {
+	case <-b.done:
+		b.log.Info("Бот остановлен")
+		return nil
+	case <-ctx.Done():
+		b.lifeMu.Lock()
+		abort := b.abort
+		b.lifeMu.Unlock()
+		if abort != nil {
+			abort()
+		}
+		b.log.Warn("Бот не остановился в срок, незавершенные обработчики прерваны", "error", ctx.Err())
+		return ctx.Err()
+	}
 }
 
-// Start запускает бота
+// Start запускает бота и работает до отмены ctx или вызова Shutdown.
+// Отмена ctx прерывает обработчики обновлений, Shutdown дожидается их.
+// Start вызывается один раз.
 func (b *Bot) Start(ctx context.Context) error {
-	b.log.Info("Запуск бота", "username", b.api.Self.UserName)
+	defer close(b.done)
+	select {
+	case <-b.quit:
+		b.log.Info("Бот остановлен до запуска")
+		return nil
+	default:
+	}
+	b.log.Info("Запуск бота", "username", b.username)
 
-	u := tgbotapi.NewUpdate(0)
-	u.Timeout = 60
+	if err := b.roles.Load(); err != nil {
+		b.log.Error("Ошибка загрузки реестра ролей", "error", err)
+		return err
+	}
+	if !b.roles.HasOwners() {
+		b.log.Warn("Владельцы бота не заданы (BOT_OWNER_IDS): доступны только общие команды")
+	}
+	if err := b.consent.Load(); err != nil {
+		b.log.Error("Ошибка загрузки реестра согласий", "error", err)
+		return err
+	}
+	// Возобновляем слежение за пользователями, добавленными до перезапуска
+	if err := b.trackers.Load(); err != nil {
+		b.log.Error("Ошибка загрузки реестра слежения", "error", err)
+		return err
+	}
+	if err := b.notifyPrefs.Load(); err != nil {
+		b.log.Error("Ошибка загрузки настроек уведомлений", "error", err)
+		return err
+	}
+	if err := b.langs.Load(); err != nil {
+		b.log.Error("Ошибка загрузки выбранных языков", "error", err)
+		return err
+	}
+	// Диалоги, начатые до перезапуска, продолжаются с того же шага
+	if err := b.conversations.Load(); err != nil {
+		b.log.Error("Ошибка загрузки диалогов", "error", err)
+		return err
+	}
 
-	updates, err := b.api.GetUpdatesChan(u)
+	notifyCtx, stopNotifier := context.WithCancel(ctx)
+	notifierDone := make(chan struct{})
+	go func() {
+		defer close(notifierDone)
+		b.notifier.Run(notifyCtx)
+	}()
+	defer func() {
+		stopNotifier()
+		<-notifierDone
+	}()
+
+	conversationsCtx, stopConversations := context.WithCancel(ctx)
+	conversationsDone := make(chan struct{})
+	go func() {
+		defer close(conversationsDone)
+		b.expireConversations(conversationsCtx)
+	}()
+	defer func() {
+		stopConversations()
+		<-conversationsDone
+	}()
+
+	b.publishCommands()
+
+	b.trackers.Start(ctx)
+	// При завершении дожидаемся остановки трекеров и незавершенных записей истории
+	defer b.trackers.Stop()
+
+	// Обновления разных чатов обрабатываются параллельно. Обработчики не прерываются
+	// вместе с приемом обновлений: при плавной остановке бот дожидается тех, что уже
+	// получили обновления, и прерывает их, только если истек срок Shutdown или отменен ctx.
+	handlerCtx, abortHandlers := context.WithCancel(context.WithoutCancel(ctx))
+	defer abortHandlers()
+	b.lifeMu.Lock()
+	b.abort = abortHandlers
+	b.lifeMu.Unlock()
+	workers := newDispatcher(config.UpdateWorkers, func(chatID int64, update tgbotapi.Update) {
+		b.processUpdate(handlerCtx, chatID, update)
+	})
+	defer workers.Wait()
+
+	updates, stopUpdates, err := b.openUpdates()
 	if err != nil {
 		b.log.Error("Ошибка получения канала обновлений", "error", err)
 		return err
 	}
+	defer stopUpdates()
 
 	for {
 		select {
 		case <-ctx.Done():
 			b.log.Info("Завершение работы бота")
+			abortHandlers()
 			return ctx.Err()
-		case update := <-updates:
-			if update.Message == nil {
+		case <-b.quit:
+			b.log.Info("Остановка бота: прием обновлений прекращен, ожидание обработчиков")
+			return nil
+		case update, ok := <-updates:
+			if !ok {
+				b.log.Info("Канал обновлений закрыт")
+				return nil
+			}
+			if update.Message == nil && update.CallbackQuery == nil {
 				continue
 			}
-			b.handleUpdate(ctx, update)
+			chatID, _ := updateChat(update)
+			workers.Dispatch(chatID, update)
+		}
+	}
+}
+
+// openUpdates открывает источник обновлений согласно BOT_MODE: long polling или webhook.
+// Возвращаемая функция останавливает источник.
+func (b *Bot) openUpdates() (<-chan tgbotapi.Update, func(), error) {
+	if config.BotMode == "webhook" {
+		b.webhook = NewWebhookServer(b.api, WebhookOptionsFromConfig())
+		if err := b.webhook.Start(); err != nil {
+			return nil, nil, err
+		}
+		stop := func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if err := b.webhook.Shutdown(ctx); err != nil {
+				b.log.Error("Ошибка остановки сервера webhook", "error", err)
+			}
+		}
+		return b.webhook.Updates(), stop, nil
+	}
+
+	// Пока зарегистрирован webhook, Telegram не отдает обновления через getUpdates
+	if _, err := b.api.MakeRequest("deleteWebhook", url.Values{}); err != nil {
+		b.log.Warn("Не удалось удалить webhook", "error", err)
+	}
+	u := tgbotapi.NewUpdate(0)
+	u.Timeout = 60
+	updates, err := b.api.GetUpdatesChan(u)
+	if err != nil {
+		return nil, nil, err
+	}
+	stop := func() {
+		// Останавливает фоновый long polling библиотеки после текущего запроса
+		if poller, ok := b.api.(interface{ StopReceivingUpdates() }); ok {
+			poller.StopReceivingUpdates()
 		}
 	}
+	return updates, stop, nil
 } 
\ No newline at end of file
@@ -0,0 +1,904 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"telegram-api-with-go/internal/access"
+	"telegram-api-with-go/internal/config"
+	"telegram-api-with-go/internal/consent"
+	"telegram-api-with-go/internal/logger"
+	"telegram-api-with-go/internal/presence"
+	"telegram-api-with-go/internal/store"
+	"telegram-api-with-go/internal/telegram"
+	"telegram-api-with-go/internal/telegram/telegramtest"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/gotd/td/tg"
+)
+
+const testChatID = 42
+
+// fakeAPI - реализация API, записывающая отправленные сообщения
+type fakeAPI struct {
+	mu       sync.Mutex
+	sent     []tgbotapi.Chattable
+	requests map[string][]url.Values
+	updates  chan tgbotapi.Update
+}
+
+func newFakeAPI() *fakeAPI {
+	return &fakeAPI{updates: make(chan tgbotapi.Update)}
+}
+
+func (a *fakeAPI) Send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.sent = append(a.sent, c)
+	return tgbotapi.Message{MessageID: len(a.sent)}, nil
+}
+
+func (a *fakeAPI) GetUpdatesChan(tgbotapi.UpdateConfig) (tgbotapi.UpdatesChannel, error) {
+	return a.updates, nil
+}
+
+func (a *fakeAPI) MakeRequest(endpoint string, params url.Values) (tgbotapi.APIResponse, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.requests == nil {
+		a.requests = make(map[string][]url.Values)
+	}
+	a.requests[endpoint] = append(a.requests[endpoint], params)
+	return tgbotapi.APIResponse{Ok: true}, nil
+}
+
+func (a *fakeAPI) UploadFile(endpoint string, params map[string]string, fieldname string, file interface{}) (tgbotapi.APIResponse, error) {
+	values := url.Values{fieldname: {fmt.Sprint(file)}}
+	for k, v := range params {
+		values.Set(k, v)
+	}
+	return a.MakeRequest(endpoint, values)
+}
+
+// requestsTo возвращает параметры вызовов метода Bot API
+func (a *fakeAPI) requestsTo(endpoint string) []url.Values {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.requests[endpoint]
+}
+
+// waitSent ждет, пока бот отправит не меньше n сообщений
+func (a *fakeAPI) waitSent(t *testing.T, n int) []tgbotapi.Chattable {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		a.mu.Lock()
+		if len(a.sent) >= n {
+			sent := append([]tgbotapi.Chattable(nil), a.sent...)
+			a.sent = nil
+			a.mu.Unlock()
+			return sent
+		}
+		a.mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("bot did not send %d messages in time", n)
+	return nil
+}
+
+// commandUpdate создает обновление с командой, как его присылает Telegram
+func commandUpdate(text string) tgbotapi.Update {
+	return commandUpdateFrom(text, testChatID, &tgbotapi.User{ID: 7, UserName: "tester"})
+}
+
+func commandUpdateFrom(text string, chatID int64, from *tgbotapi.User) tgbotapi.Update {
+	command := strings.Fields(text)[0]
+	return tgbotapi.Update{
+		Message: &tgbotapi.Message{
+			Text: text,
+			Chat: &tgbotapi.Chat{ID: chatID},
+			From: from,
+			Entities: &[]tgbotapi.MessageEntity{
+				{Type: "bot_command", Offset: 0, Length: len(command)},
+			},
+		},
+	}
+}
+
+type testBot struct {
+	bot    *Bot
+	api    *fakeAPI
+	client *telegramtest.Client
+	status *telegramtest.Status
+	store  store.PresenceStore
+}
+
+func startTestBot(t *testing.T) *testBot {
+	t.Helper()
+	tb := newTestBot(t)
+	tb.start(t)
+	return tb
+}
+
+// newTestBot создает бота с фейковыми зависимостями в режиме long polling, не запуская его
+func newTestBot(t *testing.T) *testBot {
+	t.Helper()
+	presenceStore := setupTestConfig(t)
+	tb := &testBot{
+		api:    newFakeAPI(),
+		client: telegramtest.NewClient(),
+		status: telegramtest.NewStatus(telegram.StateReady),
+		store:  presenceStore,
+	}
+	tb.bot = NewWithAPI(tb.api, "test_bot", tb.deps())
+	return tb
+}
+
+// deps возвращает фейковые зависимости бота от MTProto клиента
+func (tb *testBot) deps() Deps {
+	return Deps{
+		Dialogs:  tb.client,
+		Members:  tb.client,
+		Users:    tb.client,
+		Presence: tb.client,
+		Updates:  tb.client,
+		Status:   tb.status,
+		Store:    tb.store,
+	}
+}
+
+// setupTestConfig настраивает конфигурацию бота на временный каталог теста
+// и возвращает хранилище истории статусов в нем
+func setupTestConfig(t *testing.T) store.PresenceStore {
+	t.Helper()
+	logger.Log = slog.New(slog.NewTextHandler(io.Discard, nil))
+	config.DefaultSpyUserID = 1001
+	dir := t.TempDir()
+	config.TrackerRegistryFile = filepath.Join(dir, "trackers.json")
+	config.NotifySettingsFile = filepath.Join(dir, "notify.json")
+	config.NotifyDebounce = 0
+	config.ConsentFile = filepath.Join(dir, "consent.json")
+	config.ConsentAuditFile = filepath.Join(dir, "consent_audit.jsonl")
+	// Команды тестов отправляет владелец бота
+	config.OwnerIDs = []int64{7}
+	config.RolesFile = filepath.Join(dir, "roles.json")
+	config.LangFile = filepath.Join(dir, "languages.json")
+	config.ConversationsFile = filepath.Join(dir, "conversations.json")
+	config.ConversationTimeout = time.Hour
+	config.UpdateWorkers = 4
+	config.HandlerTimeout = time.Minute
+	config.CallbackTTL = time.Hour
+	config.ReplyMaxMessages = 3
+	config.BotMode = "polling"
+	// Пользователи, за которыми следят тесты, заранее дают согласие
+	grants := consent.NewRegistry(config.ConsentFile, config.ConsentAuditFile)
+	for _, id := range []int64{1001, 2002, 3003} {
+		if _, err := grants.Allow(consent.Grant{UserID: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	presenceStore, err := store.OpenJSONL(filepath.Join(dir, "presence"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return presenceStore
+}
+
+// start запускает бота до завершения теста
+func (tb *testBot) start(t *testing.T) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- tb.bot.Start(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+func (tb *testBot) send(text string) {
+	tb.api.updates <- commandUpdate(text)
+}
+
+func messageText(t *testing.T, c tgbotapi.Chattable) string {
+	t.Helper()
+	msg, ok := c.(tgbotapi.MessageConfig)
+	if !ok {
+		t.Fatalf("expected text message, got %T", c)
+	}
+	if msg.ChatID != testChatID {
+		t.Fatalf("message sent to chat %d, want %d", msg.ChatID, testChatID)
+	}
+	return msg.Text
+}
+
+// callbackUpdate создает нажатие кнопки под сообщением messageID
+func callbackUpdate(data string, messageID int, from *tgbotapi.User) tgbotapi.Update {
+	return tgbotapi.Update{
+		CallbackQuery: &tgbotapi.CallbackQuery{
+			ID:      "query",
+			From:    from,
+			Message: &tgbotapi.Message{MessageID: messageID, Chat: &tgbotapi.Chat{ID: testChatID}},
+			Data:    data,
+		},
+	}
+}
+
+// editText проверяет, что бот изменил сообщение, и возвращает его
+func editText(t *testing.T, c tgbotapi.Chattable) tgbotapi.EditMessageTextConfig {
+	t.Helper()
+	edit, ok := c.(tgbotapi.EditMessageTextConfig)
+	if !ok {
+		t.Fatalf("expected message edit, got %T", c)
+	}
+	if edit.ChatID != testChatID {
+		t.Fatalf("message edited in chat %d, want %d", edit.ChatID, testChatID)
+	}
+	return edit
+}
+
+// waitAnswer ждет n-го ответа на нажатие кнопки и возвращает его текст
+func (a *fakeAPI) waitAnswer(t *testing.T, n int) string {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if answers := a.requestsTo("answerCallbackQuery"); len(answers) >= n {
+			return answers[n-1].Get("text")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("callback query %d was not answered in time", n)
+	return ""
+}
+
+func TestChatsCommand(t *testing.T) {
+	tb := startTestBot(t)
+	for i := 1; i <= 10; i++ {
+		tb.client.Dialogs = append(tb.client.Dialogs, telegram.Dialog{ID: int64(i), Title: fmt.Sprintf("Чат %d", i), Members: i})
+	}
+	tb.client.Dialogs[9].Created = time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	owner := &tgbotapi.User{ID: 7, UserName: "tester"}
+
+	tb.send("/chats")
+	sent := tb.api.waitSent(t, 2)
+	if got := messageText(t, sent[0]); got != "Запрашиваю список чатов..." {
+		t.Fatalf("first reply = %q", got)
+	}
+	page := editText(t, sent[1])
+	if page.MessageID != 1 || !strings.HasPrefix(page.Text, "Список чатов (страница 1 из 2):\n1. Группа: Чат 1\n") {
+		t.Fatalf("first page = %d %q", page.MessageID, page.Text)
+	}
+	rows := page.ReplyMarkup.InlineKeyboard
+	if len(rows) != chatsPageSize+1 || len(rows[chatsPageSize]) != 1 || rows[chatsPageSize][0].Text != "Вперед »" {
+		t.Fatalf("first page keyboard = %+v", rows)
+	}
+
+	// Переход на следующую страницу изменяет то же сообщение
+	tb.api.updates <- callbackUpdate(*rows[chatsPageSize][0].CallbackData, page.MessageID, owner)
+	if got := tb.api.waitAnswer(t, 1); got != "" {
+		t.Fatalf("answer = %q", got)
+	}
+	page = editText(t, tb.api.waitSent(t, 1)[0])
+	if page.MessageID != 1 || page.Text != "Список чатов (страница 2 из 2):\n9. Группа: Чат 9\n10. Группа: Чат 10\n" {
+		t.Fatalf("second page = %d %q", page.MessageID, page.Text)
+	}
+	rows = page.ReplyMarkup.InlineKeyboard
+	if len(rows) != 3 || rows[2][0].Text != "« Назад" {
+		t.Fatalf("second page keyboard = %+v", rows)
+	}
+
+	// Карточка чата
+	tb.api.updates <- callbackUpdate(*rows[1][0].CallbackData, page.MessageID, owner)
+	detail := editText(t, tb.api.waitSent(t, 1)[0])
+	if detail.Text != "Группа: Чат 10\nID: 10\nУчастников: 10\nСоздана: 10.05.2024\n" {
+		t.Fatalf("detail = %q", detail.Text)
+	}
+	back := *detail.ReplyMarkup.InlineKeyboard[0][0].CallbackData
+	tb.api.updates <- callbackUpdate(back, page.MessageID, owner)
+	if got := editText(t, tb.api.waitSent(t, 1)[0]).Text; !strings.HasPrefix(got, "Список чатов (страница 2 из 2)") {
+		t.Fatalf("back to list = %q", got)
+	}
+
+	// Подделанные данные и нажатие пользователем без прав отклоняются
+	tb.api.updates <- callbackUpdate(strings.Replace(back, "chats:1", "chats:0", 1), page.MessageID, owner)
+	if got := tb.api.waitAnswer(t, 4); got != "Некорректная кнопка." {
+		t.Fatalf("tampered answer = %q", got)
+	}
+	tb.api.updates <- callbackUpdate(back, page.MessageID, &tgbotapi.User{ID: 5005})
+	if got := tb.api.waitAnswer(t, 5); got != "Недостаточно прав." {
+		t.Fatalf("denied answer = %q", got)
+	}
+}
+
+func TestChatsCommandError(t *testing.T) {
+	tb := startTestBot(t)
+	tb.client.DialogsErr = errors.New("FLOOD_WAIT")
+
+	tb.send("/chats")
+	sent := tb.api.waitSent(t, 2)
+	if got := editText(t, sent[1]).Text; got != "Ошибка: FLOOD_WAIT" {
+		t.Fatalf("error reply = %q", got)
+	}
+}
+
+func TestMembersCommand(t *testing.T) {
+	tb := startTestBot(t)
+	tb.client.Members["@team"] = &telegram.ChatMembers{
+		ChatID: 10,
+		Title:  "Команда",
+		Members: []telegram.Member{
+			{UserID: 1, Username: "owner", FirstName: "Анна", Role: telegram.RoleCreator},
+			{UserID: 2, Username: "helper", Bot: true, Role: telegram.RoleAdmin, JoinedAt: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)},
+		},
+	}
+
+	tb.send("/members @team admins")
+	sent := tb.api.waitSent(t, 2)
+
+	doc, ok := sent[1].(tgbotapi.DocumentConfig)
+	if !ok {
+		t.Fatalf("expected document, got %T", sent[1])
+	}
+	file, ok := doc.File.(tgbotapi.FileBytes)
+	if !ok {
+		t.Fatalf("expected file bytes, got %T", doc.File)
+	}
+	if file.Name != "members_10_admins.csv" {
+		t.Fatalf("file name = %q", file.Name)
+	}
+	want := "user_id,username,first_name,last_name,bot,role,joined_at\n" +
+		"1,owner,Анна,,false,creator,\n" +
+		"2,helper,,,true,admin,2024-03-01T12:00:00Z\n"
+	if string(file.Bytes) != want {
+		t.Fatalf("csv = %q, want %q", file.Bytes, want)
+	}
+}
+
+func TestMembersCommandRefusesNonAdmin(t *testing.T) {
+	tb := startTestBot(t)
+	tb.client.MembersErr = telegram.ErrNotChatAdmin
+
+	tb.send("/members @foreign")
+	sent := tb.api.waitSent(t, 2)
+	if got := messageText(t, sent[1]); !strings.HasPrefix(got, "Отказано") {
+		t.Fatalf("reply = %q", got)
+	}
+}
+
+func TestMembersCSV(t *testing.T) {
+	data, err := membersCSV([]telegram.Member{
+		{UserID: 1, Username: "anna", FirstName: "Анна, \"Аня\"", LastName: "Иванова\nСмирнова", Role: telegram.RoleCreator},
+		{UserID: 2, FirstName: " ведущий пробел", Bot: true, Role: telegram.RoleBanned, JoinedAt: time.Date(2024, 3, 1, 15, 0, 0, 0, time.FixedZone("MSK", 3*60*60))},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "user_id,username,first_name,last_name,bot,role,joined_at\n" +
+		"1,anna,\"Анна, \"\"Аня\"\"\",\"Иванова\nСмирнова\",false,creator,\n" +
+		"2,,\" ведущий пробел\",,true,banned,2024-03-01T12:00:00Z\n"
+	if string(data) != want {
+		t.Fatalf("csv = %q, want %q", data, want)
+	}
+
+	// Экранированный файл читается обратно без потерь
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 || records[1][2] != "Анна, \"Аня\"" || records[1][3] != "Иванова\nСмирнова" {
+		t.Fatalf("records = %q", records)
+	}
+}
+
+func TestSpyCommand(t *testing.T) {
+	tb := startTestBot(t)
+	tb.client.SetUserStatus(1001, &tg.UserStatusOnline{Expires: int(time.Now().Add(time.Minute).Unix())})
+
+	tb.send("/spy")
+	sent := tb.api.waitSent(t, 1)
+	if got := messageText(t, sent[0]); got != "Теперь вы следите за пользователем 1001." {
+		t.Fatalf("reply = %q", got)
+	}
+}
+
+func TestSpyFollowsPresenceEvents(t *testing.T) {
+	tb := startTestBot(t)
+	tb.client.SetUserStatus(1001, &tg.UserStatusOffline{WasOnline: 100})
+
+	tb.send("/spy")
+	tb.api.waitSent(t, 1)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for tb.client.Subscribers() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("spy service did not subscribe to presence events")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Событие о другом пользователе не должно учитываться
+	tb.client.EmitStatus(2002, &tg.UserStatusOnline{})
+	// Продление Expires не является сменой статуса
+	expires := time.Now().Add(time.Hour)
+	tb.client.EmitStatus(1001, &tg.UserStatusOnline{Expires: int(expires.Unix())})
+	tb.client.EmitStatus(1001, &tg.UserStatusOnline{Expires: int(expires.Add(time.Minute).Unix())})
+
+	want := "Переходы статуса: события 1, опрос 1, истечение online 0 (получено событий 2, опросов 1)."
+	for {
+		tb.send("/status")
+		got := messageText(t, tb.api.waitSent(t, 1)[0])
+		if strings.Contains(got, want) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("status reply = %q, want %q", got, want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestTrackCommands(t *testing.T) {
+	tb := startTestBot(t)
+	tb.client.SetUser(&tg.User{ID: 2002, Username: "alice", Status: &tg.UserStatusRecently{}})
+	tb.client.SetUser(&tg.User{ID: 3003, FirstName: "Борис"})
+
+	for _, tc := range []struct {
+		command string
+		want    string
+	}{
+		{"/spy @alice", "Теперь вы следите за пользователем @alice (2002)."},
+		{"/spy 3003 2m", "Теперь вы следите за пользователем Борис (3003)."},
+		{"/spy @Alice", "Вы уже следите за пользователем @alice (2002)."},
+		{"/spy @nobody", "Пользователь @nobody не найден."},
+		{"/spy 3003 soon", "Ошибка: некорректный аргумент интервал: \"soon\" (ожидается длительность, например 30s или 2m)\nИспользование: /spy [пользователь] [интервал]"},
+		{"/tracked", "Отслеживаемые пользователи:\n1. @alice (2002) - отслеживается\n2. Борис (3003) - отслеживается, опрос каждые 2m0s\n"},
+		{"/pause 3003", "Слежение за пользователем Борис (3003) приостановлено."},
+		{"/pause @carol", "Вы не следите за пользователем @carol."},
+		{"/unspy @alice", "Вы больше не следите за пользователем @alice (2002)."},
+		{"/unspy @alice", "Вы не следите за пользователем @alice."},
+		{"/tracked", "Отслеживаемые пользователи:\n1. Борис (3003) - приостановлен, опрос каждые 2m0s\n"},
+		{"/resume 3003", "Слежение за пользователем Борис (3003) возобновлено."},
+	} {
+		tb.send(tc.command)
+		if got := messageText(t, tb.api.waitSent(t, 1)[0]); got != tc.want {
+			t.Fatalf("%s: reply = %q, want %q", tc.command, got, tc.want)
+		}
+	}
+}
+
+func TestTrackedTargetsSurviveRestart(t *testing.T) {
+	tb := startTestBot(t)
+	tb.client.SetUser(&tg.User{ID: 2002, Username: "alice"})
+
+	tb.send("/spy @alice")
+	tb.api.waitSent(t, 1)
+
+	// Новый бот с тем же файлом реестра должен восстановить подписку
+	restarted := NewWithAPI(newFakeAPI(), "test_bot", Deps{
+		Users:    tb.client,
+		Presence: tb.client,
+	})
+	if err := restarted.trackers.Load(); err != nil {
+		t.Fatal(err)
+	}
+	targets := restarted.trackers.ForChat(testChatID)
+	if len(targets) != 1 || targets[0].UserID != 2002 || targets[0].Username != "alice" {
+		t.Fatalf("restored targets = %+v", targets)
+	}
+}
+
+func TestReportCommand(t *testing.T) {
+	tb := startTestBot(t)
+	tb.client.SetUser(&tg.User{ID: 2002, Username: "alice"})
+	tb.send("/spy @alice")
+	tb.api.waitSent(t, 1)
+
+	// Дожидаемся записи первичного опроса, чтобы она не попала между записями теста
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, ok, _ := tb.store.Last(2002); ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("initial poll was not recorded")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	now := time.Now().Truncate(time.Second)
+	for _, rec := range []store.Record{
+		store.NewRecord(2002, presence.Status{Kind: presence.KindOnline, SeenFrom: now.Add(-3 * time.Hour), SeenTo: now.Add(-3 * time.Hour)}, now.Add(-3*time.Hour)),
+		store.NewRecord(2002, presence.Status{Kind: presence.KindOffline, SeenFrom: now.Add(-2 * time.Hour), SeenTo: now.Add(-2 * time.Hour)}, now.Add(-2*time.Hour)),
+	} {
+		if err := tb.store.Append(rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tb.send("/report @alice 1d")
+	sent := tb.api.waitSent(t, 2)
+	text := messageText(t, sent[0])
+	for _, want := range []string{"Отчет по пользователю @alice (2002)", "Время в сети: 1 ч", "Сессий: 1"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("report = %q, want %q", text, want)
+		}
+	}
+	photo, ok := sent[1].(tgbotapi.PhotoConfig)
+	if !ok {
+		t.Fatalf("expected photo, got %T", sent[1])
+	}
+	file, ok := photo.File.(tgbotapi.FileBytes)
+	if !ok || !bytes.HasPrefix(file.Bytes, []byte("\x89PNG")) {
+		t.Fatalf("photo is not a PNG: %T", photo.File)
+	}
+
+	tb.send("/report @bob")
+	if got := messageText(t, tb.api.waitSent(t, 1)[0]); got != "Вы не следите за пользователем @bob." {
+		t.Fatalf("reply = %q", got)
+	}
+	tb.send("/report @alice forever")
+	if got := messageText(t, tb.api.waitSent(t, 1)[0]); got != "Ошибка: некорректный период. Примеры: 7d, 2w, 12h." {
+		t.Fatalf("reply = %q", got)
+	}
+	tb.send("/report @alice 400d")
+	if got := messageText(t, tb.api.waitSent(t, 1)[0]); got != "Ошибка: период больше максимального (366 дней)." {
+		t.Fatalf("reply = %q", got)
+	}
+}
+
+func TestDegradedMode(t *testing.T) {
+	tb := startTestBot(t)
+	tb.status.Set(telegram.ClientStatus{
+		State:       telegram.StateReconnecting,
+		Err:         errors.New("connection reset"),
+		Restarts:    3,
+		NextRestart: time.Now().Add(10 * time.Second),
+	})
+
+	tb.send("/chats")
+	sent := tb.api.waitSent(t, 1)
+	got := messageText(t, sent[0])
+	if !strings.Contains(got, "переподключение") || !strings.Contains(got, "connection reset") {
+		t.Fatalf("reply = %q", got)
+	}
+	for _, call := range tb.client.CallLog() {
+		if call == "GetDialogs" {
+			t.Fatal("GetDialogs must not be called while client is reconnecting")
+		}
+	}
+
+	tb.status.Set(telegram.ClientStatus{State: telegram.StateFailed, Err: errors.New("SESSION_REVOKED")})
+	tb.send("/status")
+	sent = tb.api.waitSent(t, 1)
+	if got := messageText(t, sent[0]); !strings.Contains(got, "SESSION_REVOKED") {
+		t.Fatalf("status reply = %q", got)
+	}
+}
+
+func TestNotifyPushesTransitions(t *testing.T) {
+	tb := startTestBot(t)
+	tb.client.SetUser(&tg.User{ID: 2002, Username: "alice", Status: &tg.UserStatusOffline{WasOnline: 100}})
+	tb.send("/spy @alice")
+	tb.api.waitSent(t, 1)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, ok, _ := tb.store.Last(2002); ok && tb.client.Subscribers() > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("tracker did not start")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	tb.client.EmitStatus(2002, &tg.UserStatusOnline{Expires: int(time.Now().Add(time.Hour).Unix())})
+	if got := messageText(t, tb.api.waitSent(t, 1)[0]); got != "@alice (2002): в сети" {
+		t.Fatalf("notification = %q", got)
+	}
+
+	for _, tc := range []struct {
+		command string
+		want    string
+	}{
+		{"/notify quiet 23-7", "Часы тишины: 23:00-07:00"},
+		{"/notify quiet 7-7", "Некорректные часы тишины \"7-7\""},
+		{"/notify digest on", "Ежедневная сводка в"},
+		{"/notify quiet off", "Часы тишины: не заданы"},
+		{"/notify", "Использование:"},
+	} {
+		tb.send(tc.command)
+		if got := messageText(t, tb.api.waitSent(t, 1)[0]); !strings.Contains(got, tc.want) {
+			t.Errorf("%s: reply = %q, want %q", tc.command, got, tc.want)
+		}
+	}
+}
+
+func TestExportCommand(t *testing.T) {
+	tb := startTestBot(t)
+	tb.client.SetUser(&tg.User{ID: 2002, Username: "alice"})
+	tb.send("/spy @alice")
+	tb.api.waitSent(t, 1)
+
+	now := time.Now().Truncate(time.Second)
+	for _, rec := range []store.Record{
+		store.NewRecord(2002, presence.Status{Kind: presence.KindOnline, SeenFrom: now.Add(-3 * time.Hour), SeenTo: now.Add(-3 * time.Hour)}, now.Add(-3*time.Hour)),
+		store.NewRecord(2002, presence.Status{Kind: presence.KindOffline, SeenFrom: now.Add(-2 * time.Hour), SeenTo: now.Add(-2 * time.Hour)}, now.Add(-2*time.Hour)),
+	} {
+		if err := tb.store.Append(rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tb.send("/export @alice ics 1d Europe/Moscow")
+	doc, ok := tb.api.waitSent(t, 1)[0].(tgbotapi.DocumentConfig)
+	if !ok {
+		t.Fatal("expected document")
+	}
+	file, ok := doc.File.(tgbotapi.FileBytes)
+	if !ok || file.Name != "sessions_2002.ics" {
+		t.Fatalf("file = %+v", doc.File)
+	}
+	if n := strings.Count(string(file.Bytes), "BEGIN:VEVENT"); n != 1 {
+		t.Fatalf("events = %d:\n%s", n, file.Bytes)
+	}
+	if !strings.Contains(doc.Caption, "Europe/Moscow") {
+		t.Fatalf("caption = %q", doc.Caption)
+	}
+
+	for command, want := range map[string]string{
+		"/export @alice transitions ics":  "Ошибка: формат ics поддерживается только для сессий.\nПараметры в любом порядке:",
+		"/export @alice Mars/Olympus":     "Ошибка: неизвестный аргумент или часовой пояс \"Mars/Olympus\".\n",
+		"/export @alice 2024-05..2024-06": "Ошибка: некорректный диапазон дат. Пример: 2024-05-01..2024-05-31.\n",
+		"/export @bob":                    "Вы не следите за пользователем @bob.",
+	} {
+		tb.send(command)
+		if got := messageText(t, tb.api.waitSent(t, 1)[0]); !strings.HasPrefix(got, want) {
+			t.Errorf("%s: reply = %q, want prefix %q", command, got, want)
+		}
+	}
+
+	// Ошибки в аргументах переводятся на язык пользователя
+	english := &tgbotapi.User{ID: 7, UserName: "tester", LanguageCode: "en"}
+	for command, want := range map[string]string{
+		"/export @alice transitions ics": "Error: the ics format is only supported for sessions.\nOptions in any order:",
+		"/export @alice Mars/Olympus":    "Error: unknown argument or time zone \"Mars/Olympus\".\n",
+		"/export @alice 400d":            "Error: the period is longer than the maximum (366 days).\n",
+	} {
+		tb.api.updates <- commandUpdateFrom(command, testChatID, english)
+		if got := messageText(t, tb.api.waitSent(t, 1)[0]); !strings.HasPrefix(got, want) {
+			t.Errorf("%s: reply = %q, want prefix %q", command, got, want)
+		}
+	}
+}
+
+func TestConsent(t *testing.T) {
+	tb := startTestBot(t)
+	tb.client.SetUser(&tg.User{ID: 4004, Username: "dave", Status: &tg.UserStatusRecently{}})
+	dave := &tgbotapi.User{ID: 4004, UserName: "dave"}
+
+	tb.send("/spy @dave")
+	if got := messageText(t, tb.api.waitSent(t, 1)[0]); !strings.Contains(got, "не давал согласия на отслеживание") {
+		t.Fatalf("reply = %q", got)
+	}
+
+	tb.api.updates <- commandUpdateFrom("/allow", 4004, dave)
+	if got := tb.api.waitSent(t, 1)[0].(tgbotapi.MessageConfig).Text; !strings.HasPrefix(got, "Согласие на отслеживание записано.") {
+		t.Fatalf("reply = %q", got)
+	}
+
+	tb.send("/spy @dave")
+	if got := messageText(t, tb.api.waitSent(t, 1)[0]); got != "Теперь вы следите за пользователем @dave (4004)." {
+		t.Fatalf("reply = %q", got)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, ok, _ := tb.store.Last(4004); ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("initial poll was not recorded")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	tb.api.updates <- commandUpdateFrom("/deny delete", 4004, dave)
+	sent := tb.api.waitSent(t, 2)
+	replies := map[int64]string{}
+	for _, c := range sent {
+		msg := c.(tgbotapi.MessageConfig)
+		replies[msg.ChatID] = msg.Text
+	}
+	if got := replies[testChatID]; got != "Пользователь @dave (4004) отозвал согласие, слежение прекращено." {
+		t.Fatalf("subscriber notification = %q", got)
+	}
+	if got := replies[4004]; got != "Согласие отозвано, слежение прекращено.\nИстория статусов удалена." {
+		t.Fatalf("reply = %q", got)
+	}
+	if _, ok, _ := tb.store.Last(4004); ok {
+		t.Fatal("history was not deleted")
+	}
+
+	tb.send("/tracked")
+	if got := messageText(t, tb.api.waitSent(t, 1)[0]); strings.Contains(got, "dave") {
+		t.Fatalf("target is still tracked: %q", got)
+	}
+
+	events, err := consent.NewRegistry(config.ConsentFile, config.ConsentAuditFile).Audit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actions []string
+	for _, e := range events {
+		if e.UserID == 4004 {
+			actions = append(actions, string(e.Action))
+		}
+	}
+	if got := strings.Join(actions, ","); got != "allow,deny,delete_history" {
+		t.Fatalf("audit = %s", got)
+	}
+}
+
+func TestRoles(t *testing.T) {
+	tb := startTestBot(t)
+	erin := &tgbotapi.User{ID: 5005, UserName: "erin"}
+	reply := func(text string) string {
+		t.Helper()
+		tb.api.updates <- commandUpdateFrom(text, 5005, erin)
+		return tb.api.waitSent(t, 1)[0].(tgbotapi.MessageConfig).Text
+	}
+
+	// Без роли доступны только общие команды
+	if got := reply("/chats"); got != "Недостаточно прав для команды /chats." {
+		t.Fatalf("reply = %q", got)
+	}
+	if got := reply("/help"); strings.Contains(got, "/spy") || !strings.Contains(got, "/allow") {
+		t.Fatalf("help without role = %q", got)
+	}
+
+	tb.send("/grant 5005 viewer")
+	if got := messageText(t, tb.api.waitSent(t, 1)[0]); got != "Пользователю 5005 выдана роль viewer." {
+		t.Fatalf("reply = %q", got)
+	}
+	if got := reply("/tracked"); !strings.HasPrefix(got, "Вы ни за кем не следите.") {
+		t.Fatalf("viewer /tracked = %q", got)
+	}
+	if got := reply("/spy 2002"); got != "Недостаточно прав для команды /spy." {
+		t.Fatalf("viewer /spy = %q", got)
+	}
+
+	tb.send("/grant 5005 admin")
+	tb.api.waitSent(t, 1)
+	if got := reply("/grant 5005 admin"); got != "Недостаточно прав для команды /grant." {
+		t.Fatalf("admin /grant = %q", got)
+	}
+	if got := reply("/help"); !strings.Contains(got, "/spy") || strings.Contains(got, "/chats") {
+		t.Fatalf("help for admin = %q", got)
+	}
+
+	tb.send("/roles")
+	if got := messageText(t, tb.api.waitSent(t, 1)[0]); got != "Роли пользователей:\n7 - owner\n5005 - admin\n" {
+		t.Fatalf("roles = %q", got)
+	}
+	tb.send("/revoke 7")
+	if got := messageText(t, tb.api.waitSent(t, 1)[0]); !strings.Contains(got, "владелец бота") {
+		t.Fatalf("revoke owner = %q", got)
+	}
+
+	// Роли сохраняются между перезапусками
+	roles := access.NewRegistry(config.RolesFile, nil)
+	if err := roles.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if got := roles.Role(5005); got != access.RoleAdmin {
+		t.Fatalf("persisted role = %q", got)
+	}
+
+	tb.send("/revoke 5005")
+	if got := messageText(t, tb.api.waitSent(t, 1)[0]); got != "Роль пользователя 5005 отозвана." {
+		t.Fatalf("reply = %q", got)
+	}
+	if got := reply("/tracked"); got != "Недостаточно прав для команды /tracked." {
+		t.Fatalf("after revoke = %q", got)
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	tb := newTestBot(t)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	tb.bot.router.Register(Command{
+		Name:        "slow",
+		Description: "command.help",
+		Handler: func(ctx context.Context, req *Request) {
+			started <- struct{}{}
+			select {
+			case <-release:
+				tb.bot.reply(req.ChatID, "done")
+			case <-ctx.Done():
+				tb.bot.reply(req.ChatID, ctx.Err().Error())
+			}
+		},
+	})
+	tb.start(t)
+
+	// Плавная остановка дожидается обработчика, уже получившего обновление
+	tb.send("/slow")
+	<-started
+	stopped := make(chan error, 1)
+	go func() { stopped <- tb.bot.Shutdown(context.Background()) }()
+	select {
+	case err := <-stopped:
+		t.Fatalf("shutdown returned %v before the handler finished", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	if err := <-stopped; err != nil {
+		t.Fatal(err)
+	}
+	if got := messageText(t, tb.api.waitSent(t, 1)[0]); got != "done" {
+		t.Fatalf("reply = %q", got)
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	tb := newTestBot(t)
+	started := make(chan struct{})
+	tb.bot.router.Register(Command{
+		Name:        "slow",
+		Description: "command.help",
+		Handler: func(ctx context.Context, req *Request) {
+			close(started)
+			<-ctx.Done()
+			tb.bot.reply(req.ChatID, ctx.Err().Error())
+		},
+	})
+	tb.start(t)
+
+	// Обработчик, не успевший к сроку остановки, прерывается
+	tb.send("/slow")
+	<-started
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := tb.bot.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("shutdown = %v", err)
+	}
+	if got := messageText(t, tb.api.waitSent(t, 1)[0]); got != "context canceled" {
+		t.Fatalf("reply = %q", got)
+	}
+}
+
+func TestShutdownBeforeStart(t *testing.T) {
+	tb := newTestBot(t)
+
+	// Сигнал завершения пришел раньше, чем горутина успела вызвать Start
+	stopped := make(chan error, 1)
+	go func() { stopped <- tb.bot.Shutdown(context.Background()) }()
+	select {
+	case err := <-stopped:
+		t.Fatalf("shutdown returned %v before Start", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := tb.bot.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-stopped; err != nil {
+		t.Fatal(err)
+	}
+	// Остановленный бот не запускается: меню команд не публикуется
+	if got := tb.api.requestsTo("setMyCommands"); len(got) != 0 {
+		t.Fatalf("bot started after shutdown: %v", got)
+	}
+}
@@ -0,0 +1,341 @@
+// Package bottest содержит фейковый сервер Bot API для сквозных тестов бота.
+package bottest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// Call - вызов метода Bot API
+type Call struct {
+	Method string
+	Params url.Values
+	// File - загруженный файл (sendDocument, sendPhoto); nil для вызовов без файла
+	File *File
+}
+
+// File - файл, загруженный в multipart запросе
+type File struct {
+	Field string
+	Name  string
+	Data  []byte
+}
+
+// ChatID возвращает чат вызова
+func (c Call) ChatID() int64 {
+	id, _ := strconv.ParseInt(c.Params.Get("chat_id"), 10, 64)
+	return id
+}
+
+// Text возвращает текст сообщения
+func (c Call) Text() string {
+	return c.Params.Get("text")
+}
+
+// Keyboard возвращает кнопки из reply_markup; nil, если кнопок нет
+func (c Call) Keyboard() [][]tgbotapi.InlineKeyboardButton {
+	var markup tgbotapi.InlineKeyboardMarkup
+	if err := json.Unmarshal([]byte(c.Params.Get("reply_markup")), &markup); err != nil {
+		return nil
+	}
+	return markup.InlineKeyboard
+}
+
+// apiError - ошибка, которую сервер возвращает на вызов метода
+type apiError struct {
+	code        int
+	description string
+}
+
+// Server - фейковый сервер Bot API. Он записывает вызовы методов, отвечает на
+// sendMessage, editMessageText, sendDocument и sendPhoto сообщениями с новыми ID
+// и отдает через getUpdates обновления, добавленные в тесте.
+type Server struct {
+	// URL - адрес сервера для BOT_API_ENDPOINT
+	URL string
+	// Bot - пользователь бота, которого возвращает getMe
+	Bot tgbotapi.User
+
+	srv    *httptest.Server
+	token  string
+	closed chan struct{}
+
+	mu            sync.Mutex
+	calls         []Call
+	updates       []tgbotapi.Update
+	nextUpdateID  int
+	nextMessageID int
+	errors        map[string]apiError
+	// changed закрывается и заменяется при каждом новом обновлении и вызове
+	changed chan struct{}
+}
+
+// NewServer запускает сервер для бота с токеном token
+func NewServer(token string) *Server {
+	s := &Server{
+		Bot:          tgbotapi.User{ID: 100, UserName: "test_bot", FirstName: "Test", IsBot: true},
+		token:        token,
+		closed:       make(chan struct{}),
+		nextUpdateID: 1,
+		errors:       make(map[string]apiError),
+		changed:      make(chan struct{}),
+	}
+	s.srv = httptest.NewServer(http.HandlerFunc(s.serve))
+	s.URL = s.srv.URL
+	return s
+}
+
+// Close прерывает ожидающие getUpdates и останавливает сервер
+func (s *Server) Close() {
+	close(s.closed)
+	s.srv.Close()
+}
+
+// Fail заставляет метод method возвращать ошибку Bot API
+func (s *Server) Fail(method string, code int, description string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errors[method] = apiError{code: code, description: description}
+}
+
+// Inject добавляет обновление в очередь getUpdates и возвращает его ID
+func (s *Server) Inject(update tgbotapi.Update) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	update.UpdateID = s.nextUpdateID
+	s.nextUpdateID++
+	s.updates = append(s.updates, update)
+	s.notifyLocked()
+	return update.UpdateID
+}
+
+// SendText добавляет сообщение пользователя from в чат chatID.
+// Команда в начале текста размечается entity bot_command, как это делает Telegram.
+func (s *Server) SendText(chatID int64, from tgbotapi.User, text string) int {
+	msg := &tgbotapi.Message{
+		MessageID: s.newMessageID(),
+		From:      &from,
+		Chat:      &tgbotapi.Chat{ID: chatID, Type: "private"},
+		Date:      int(time.Now().Unix()),
+		Text:      text,
+	}
+	if strings.HasPrefix(text, "/") {
+		command, _, _ := strings.Cut(text, " ")
+		msg.Entities = &[]tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: len(utf16(command))}}
+	}
+	return s.Inject(tgbotapi.Update{Message: msg})
+}
+
+// Press добавляет нажатие пользователем from кнопки с данными data под сообщением messageID
+func (s *Server) Press(chatID int64, messageID int, from tgbotapi.User, data string) int {
+	return s.Inject(tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{
+		ID:      fmt.Sprintf("query-%d", time.Now().UnixNano()),
+		From:    &from,
+		Message: &tgbotapi.Message{MessageID: messageID, Chat: &tgbotapi.Chat{ID: chatID}},
+		Data:    data,
+	}})
+}
+
+// Calls возвращает вызовы метода method; пустой method - все вызовы, кроме getUpdates
+func (s *Server) Calls(method string) []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.callsLocked(method)
+}
+
+// WaitCalls ждет, пока метод method будет вызван не меньше n раз, и возвращает его вызовы
+func (s *Server) WaitCalls(method string, n int, timeout time.Duration) ([]Call, error) {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	for {
+		s.mu.Lock()
+		calls := s.callsLocked(method)
+		changed := s.changed
+		s.mu.Unlock()
+		if len(calls) >= n {
+			return calls, nil
+		}
+		select {
+		case <-changed:
+		case <-deadline.C:
+			return calls, fmt.Errorf("метод %s вызван %d раз из %d за %s", method, len(calls), n, timeout)
+		}
+	}
+}
+
+func (s *Server) callsLocked(method string) []Call {
+	var calls []Call
+	for _, c := range s.calls {
+		if method == "" || c.Method == method {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+func (s *Server) notifyLocked() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+func (s *Server) newMessageID() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextMessageID++
+	return s.nextMessageID
+}
+
+// serve обрабатывает запрос вида /bot<token>/<method>
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	token, method, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/bot"), "/")
+	if !ok || token != s.token {
+		writeResponse(w, http.StatusUnauthorized, tgbotapi.APIResponse{Ok: false, ErrorCode: 401, Description: "Unauthorized"})
+		return
+	}
+	call, err := readCall(r, method)
+	if err != nil {
+		writeResponse(w, http.StatusBadRequest, tgbotapi.APIResponse{Ok: false, ErrorCode: 400, Description: err.Error()})
+		return
+	}
+
+	if method == "getUpdates" {
+		s.getUpdates(w, r, call.Params)
+		return
+	}
+
+	s.mu.Lock()
+	s.calls = append(s.calls, call)
+	s.notifyLocked()
+	failure, failed := s.errors[method]
+	s.mu.Unlock()
+	if failed {
+		writeResponse(w, failure.code, tgbotapi.APIResponse{Ok: false, ErrorCode: failure.code, Description: failure.description})
+		return
+	}
+
+	var result any = true
+	switch method {
+	case "getMe":
+		result = s.Bot
+	case "sendMessage", "sendDocument", "sendPhoto":
+		result = tgbotapi.Message{
+			MessageID: s.newMessageID(),
+			Chat:      &tgbotapi.Chat{ID: call.ChatID()},
+			Date:      int(time.Now().Unix()),
+			Text:      call.Text(),
+		}
+	case "editMessageText":
+		id, _ := strconv.Atoi(call.Params.Get("message_id"))
+		result = tgbotapi.Message{MessageID: id, Chat: &tgbotapi.Chat{ID: call.ChatID()}, Text: call.Text()}
+	}
+	writeResult(w, result)
+}
+
+// getUpdates отдает обновления с ID не меньше offset. Если их нет, запрос
+// ждет новых обновлений до истечения timeout, как long polling в Telegram.
+func (s *Server) getUpdates(w http.ResponseWriter, r *http.Request, params url.Values) {
+	offset, _ := strconv.Atoi(params.Get("offset"))
+	timeout, _ := strconv.Atoi(params.Get("timeout"))
+	wait := time.NewTimer(time.Duration(timeout) * time.Second)
+	defer wait.Stop()
+
+	for {
+		s.mu.Lock()
+		// Обновления до offset подтверждены ботом
+		var pending []tgbotapi.Update
+		for _, u := range s.updates {
+			if u.UpdateID >= offset {
+				pending = append(pending, u)
+			}
+		}
+		s.updates = pending
+		changed := s.changed
+		s.mu.Unlock()
+
+		if len(pending) > 0 {
+			writeResult(w, pending)
+			return
+		}
+		select {
+		case <-changed:
+		case <-wait.C:
+			writeResult(w, []tgbotapi.Update{})
+			return
+		case <-s.closed:
+			writeResult(w, []tgbotapi.Update{})
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// readCall читает параметры запроса: форму или multipart с файлом
+func readCall(r *http.Request, method string) (Call, error) {
+	call := Call{Method: method}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		if err := r.ParseForm(); err != nil {
+			return call, err
+		}
+		call.Params = r.Form
+		return call, nil
+	}
+
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		return call, err
+	}
+	call.Params = url.Values(r.MultipartForm.Value)
+	for field, headers := range r.MultipartForm.File {
+		f, err := headers[0].Open()
+		if err != nil {
+			return call, err
+		}
+		data, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			return call, err
+		}
+		call.File = &File{Field: field, Name: headers[0].Filename, Data: data}
+	}
+	return call, nil
+}
+
+func writeResult(w http.ResponseWriter, result any) {
+	data, err := json.Marshal(result)
+	if err != nil {
+		writeResponse(w, http.StatusInternalServerError, tgbotapi.APIResponse{Ok: false, ErrorCode: 500, Description: err.Error()})
+		return
+	}
+	writeResponse(w, http.StatusOK, tgbotapi.APIResponse{Ok: true, Result: data})
+}
+
+func writeResponse(w http.ResponseWriter, status int, resp tgbotapi.APIResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// utf16 возвращает строку в кодировке UTF-16, в которой Bot API считает длину entities
+func utf16(s string) []uint16 {
+	var units []uint16
+	for _, r := range s {
+		if r >= 0x10000 {
+			units = append(units, 0, 0)
+		} else {
+			units = append(units, 0)
+		}
+	}
+	return units
+}
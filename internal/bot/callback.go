@@ -0,0 +1,180 @@
+package bot
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"telegram-api-with-go/internal/telegram"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// maxCallbackData - ограничение Telegram на размер данных кнопки в байтах
+const maxCallbackData = 64
+
+var (
+	// errCallbackInvalid возвращается для данных кнопки с неверной подписью или форматом
+	errCallbackInvalid = errors.New("некорректные данные кнопки")
+	// errCallbackExpired возвращается для кнопки с истекшим сроком действия
+	errCallbackExpired = errors.New("срок действия кнопки истек")
+)
+
+// callbackSigner подписывает данные кнопок и проверяет их при нажатии.
+// Данные имеют вид action:arg...:expires:signature, где expires - время истечения
+// в секундах Unix в base36, а подпись - HMAC-SHA256 от данных и ID чата. Привязка
+// к чату не позволяет повторить нажатие кнопки из одного чата в другом.
+type callbackSigner struct {
+	key []byte
+	ttl time.Duration
+	now func() time.Time
+}
+
+// newCallbackSigner создает подписчика с ключом secret. Пустой secret заменяется
+// ключом, выведенным из токена бота, чтобы кнопки оставались действительными после перезапуска.
+func newCallbackSigner(secret, botToken string, ttl time.Duration) *callbackSigner {
+	key := []byte(secret)
+	if secret == "" {
+		sum := sha256.Sum256([]byte("callback:" + botToken))
+		key = sum[:]
+	}
+	return &callbackSigner{key: key, ttl: ttl, now: time.Now}
+}
+
+// Sign формирует данные кнопки для чата chatID. Действие и аргументы не должны содержать ':'.
+func (s *callbackSigner) Sign(chatID int64, action string, args ...string) string {
+	body := strings.Join(append([]string{action}, args...), ":")
+	body += ":" + strconv.FormatInt(s.now().Add(s.ttl).Unix(), 36)
+	data := body + ":" + s.signature(chatID, body)
+	if len(data) > maxCallbackData {
+		panic("bot: данные кнопки длиннее 64 байт: " + data)
+	}
+	return data
+}
+
+// Verify проверяет подпись и срок действия данных кнопки из чата chatID
+func (s *callbackSigner) Verify(chatID int64, data string) (action string, args []string, err error) {
+	i := strings.LastIndexByte(data, ':')
+	if i < 0 {
+		return "", nil, errCallbackInvalid
+	}
+	body, sig := data[:i], data[i+1:]
+	if !hmac.Equal([]byte(sig), []byte(s.signature(chatID, body))) {
+		return "", nil, errCallbackInvalid
+	}
+
+	fields := strings.Split(body, ":")
+	if len(fields) < 2 {
+		return "", nil, errCallbackInvalid
+	}
+	expires, err := strconv.ParseInt(fields[len(fields)-1], 36, 64)
+	if err != nil {
+		return "", nil, errCallbackInvalid
+	}
+	if !s.now().Before(time.Unix(expires, 0)) {
+		return "", nil, errCallbackExpired
+	}
+	return fields[0], fields[1 : len(fields)-1], nil
+}
+
+func (s *callbackSigner) signature(chatID int64, body string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(strconv.FormatInt(chatID, 10) + ":" + body))
+	// 12 байт подписи достаточно и оставляют место для аргументов
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:12])
+}
+
+// handleCallback обрабатывает нажатие кнопки
+func (b *Bot) handleCallback(ctx context.Context, query *tgbotapi.CallbackQuery) {
+	lang := b.userLang(query.From)
+	if query.Message == nil {
+		b.answerCallback(query.ID, lang.T("callback.expired"))
+		return
+	}
+	chatID := query.Message.Chat.ID
+	b.rememberLang(chatID, lang)
+
+	action, args, err := b.signer.Verify(chatID, query.Data)
+	var cb *Callback
+	if err == nil {
+		var ok bool
+		if cb, ok = b.router.Callback(action); !ok {
+			err = errCallbackInvalid
+		}
+	}
+	switch {
+	case errors.Is(err, errCallbackExpired):
+		b.answerCallback(query.ID, lang.T("callback.expired"))
+		return
+	case err != nil:
+		b.log.Warn("Отклонено нажатие кнопки с некорректными данными",
+			"user_id", query.From.ID,
+			"chat_id", chatID,
+			"error", err,
+		)
+		b.answerCallback(query.ID, lang.T("callback.invalid"))
+		return
+	}
+
+	role := b.roleOf(query.From)
+	if !permits(role, cb.Permission) {
+		b.log.Warn("Нажатие кнопки отклонено: недостаточно прав",
+			"action", action,
+			"user_id", query.From.ID,
+			"username", query.From.UserName,
+			"role", role,
+			"chat_id", chatID,
+		)
+		b.answerCallback(query.ID, lang.T("callback.forbidden"))
+		return
+	}
+	if cb.RequiresClient && b.status != nil {
+		if status := b.status.Status(); status.State != telegram.StateReady {
+			b.log.Warn("Нажатие кнопки отклонено: Telegram клиент недоступен",
+				"state", status.State.String(),
+				"chat_id", chatID,
+			)
+			b.answerCallback(query.ID, clientStatusText(lang, status))
+			return
+		}
+	}
+
+	b.answerCallback(query.ID, "")
+	cb.Handler(ctx, &CallbackRequest{
+		Query:     query,
+		ChatID:    chatID,
+		MessageID: query.Message.MessageID,
+		Args:      args,
+		Lang:      lang,
+	})
+}
+
+// answerCallback подтверждает нажатие кнопки; непустой text показывается пользователю
+func (b *Bot) answerCallback(queryID, text string) {
+	params := url.Values{"callback_query_id": {queryID}}
+	if text != "" {
+		params.Set("text", text)
+	}
+	if _, err := b.api.MakeRequest("answerCallbackQuery", params); err != nil {
+		b.log.Error("Ошибка ответа на нажатие кнопки", "error", err)
+	}
+}
+
+// editMessage заменяет текст и кнопки сообщения; nil markup убирает кнопки
+func (b *Bot) editMessage(chatID int64, messageID int, text string, markup *tgbotapi.InlineKeyboardMarkup) {
+	edit := tgbotapi.NewEditMessageText(chatID, messageID, text)
+	edit.ReplyMarkup = markup
+	if _, err := b.api.Send(edit); err != nil {
+		b.log.Error("Ошибка изменения сообщения",
+			"chat_id", chatID,
+			"message_id", messageID,
+			"error", err,
+		)
+	}
+}
@@ -0,0 +1,44 @@
+package bot
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCallbackSigner(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	s := newCallbackSigner("", "123:token", time.Hour)
+	s.now = func() time.Time { return now }
+
+	data := s.Sign(-1001234567890, "chat", "-1001234567890", "12")
+	if len(data) > maxCallbackData {
+		t.Fatalf("data is %d bytes: %s", len(data), data)
+	}
+	action, args, err := s.Verify(-1001234567890, data)
+	if err != nil || action != "chat" || !slices.Equal(args, []string{"-1001234567890", "12"}) {
+		t.Fatalf("Verify = %q, %q, %v", action, args, err)
+	}
+
+	// Кнопка действительна только в чате, для которого подписана
+	if _, _, err := s.Verify(42, data); !errors.Is(err, errCallbackInvalid) {
+		t.Fatalf("other chat: err = %v", err)
+	}
+	// Ключ по умолчанию зависит от токена бота
+	other := newCallbackSigner("", "456:token", time.Hour)
+	other.now = s.now
+	if _, _, err := other.Verify(-1001234567890, data); !errors.Is(err, errCallbackInvalid) {
+		t.Fatalf("other token: err = %v", err)
+	}
+	for _, bad := range []string{"", "chat", "chat:1:2:x:sig", data[:len(data)-1] + "A"} {
+		if _, _, err := s.Verify(-1001234567890, bad); !errors.Is(err, errCallbackInvalid) {
+			t.Errorf("Verify(%q) = %v", bad, err)
+		}
+	}
+
+	now = now.Add(time.Hour)
+	if _, _, err := s.Verify(-1001234567890, data); !errors.Is(err, errCallbackExpired) {
+		t.Fatalf("expired: err = %v", err)
+	}
+}
@@ -0,0 +1,202 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"telegram-api-with-go/internal/config"
+	"telegram-api-with-go/internal/i18n"
+	"telegram-api-with-go/internal/telegram"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// chatsPageSize - число чатов на странице /chats
+const chatsPageSize = 8
+
+// Действия кнопок /chats
+const (
+	callbackChatsPage = "chats"
+	callbackChat      = "chat"
+)
+
+// registerChatsCallbacks регистрирует обработчики кнопок /chats
+func (b *Bot) registerChatsCallbacks() {
+	b.router.RegisterCallback(Callback{
+		Action:         callbackChatsPage,
+		Permission:     PermissionOwner,
+		RequiresClient: true,
+		Handler:        b.handleChatsPageCallback,
+	})
+	b.router.RegisterCallback(Callback{
+		Action:         callbackChat,
+		Permission:     PermissionOwner,
+		RequiresClient: true,
+		Handler:        b.handleChatCallback,
+	})
+}
+
+// handleChatsCommand обрабатывает команду /chats: отправляет первую страницу списка чатов.
+// Переход между страницами и просмотр чата изменяют это же сообщение.
+func (b *Bot) handleChatsCommand(ctx context.Context, req *Request) {
+	chatID := req.ChatID
+	placeholder, err := b.api.Send(tgbotapi.NewMessage(chatID, req.Lang.T("chats.requesting")))
+	if err != nil {
+		b.log.Error("Ошибка отправки сообщения", "chat_id", chatID, "error", err)
+		return
+	}
+
+	b.log.Info("Запрос списка чатов",
+		"user", req.Message.From.UserName,
+		"chat_id", chatID,
+	)
+	b.showChatsPage(ctx, req.Lang, chatID, placeholder.MessageID, 0)
+}
+
+// handleChatsPageCallback обрабатывает кнопки перехода между страницами списка чатов
+func (b *Bot) handleChatsPageCallback(ctx context.Context, req *CallbackRequest) {
+	page, err := strconv.Atoi(callbackArg(req, 0))
+	if err != nil {
+		b.log.Warn("Некорректный номер страницы в данных кнопки", "args", req.Args)
+		return
+	}
+	b.showChatsPage(ctx, req.Lang, req.ChatID, req.MessageID, page)
+}
+
+// handleChatCallback обрабатывает выбор чата в списке
+func (b *Bot) handleChatCallback(ctx context.Context, req *CallbackRequest) {
+	id, err := strconv.ParseInt(callbackArg(req, 0), 10, 64)
+	if err != nil {
+		b.log.Warn("Некорректный ID чата в данных кнопки", "args", req.Args)
+		return
+	}
+	page, _ := strconv.Atoi(callbackArg(req, 1))
+
+	dialogs, ok := b.fetchDialogs(ctx, req.Lang, req.ChatID, req.MessageID)
+	if !ok {
+		return
+	}
+	back := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(req.Lang.T("chats.back"), b.signer.Sign(req.ChatID, callbackChatsPage, strconv.Itoa(page))),
+	))
+	for _, d := range dialogs {
+		if d.ID == id {
+			b.editMessage(req.ChatID, req.MessageID, dialogText(req.Lang, d), &back)
+			return
+		}
+	}
+	b.editMessage(req.ChatID, req.MessageID, req.Lang.T("chats.gone"), &back)
+}
+
+// showChatsPage заменяет сообщение messageID страницей page списка чатов
+func (b *Bot) showChatsPage(ctx context.Context, lang i18n.Lang, chatID int64, messageID int, page int) {
+	dialogs, ok := b.fetchDialogs(ctx, lang, chatID, messageID)
+	if !ok {
+		return
+	}
+	if len(dialogs) == 0 {
+		b.log.Info("Чаты не найдены", "chat_id", chatID)
+		b.editMessage(chatID, messageID, lang.T("chats.empty"), nil)
+		return
+	}
+
+	text, markup := b.chatsPage(lang, chatID, dialogs, page)
+	b.log.Info("Отправка списка чатов",
+		"count", len(dialogs),
+		"page", page,
+		"chat_id", chatID,
+	)
+	b.editMessage(chatID, messageID, text, &markup)
+}
+
+// fetchDialogs получает список чатов; при ошибке сообщение messageID заменяется текстом ошибки
+func (b *Bot) fetchDialogs(ctx context.Context, lang i18n.Lang, chatID int64, messageID int) ([]telegram.Dialog, bool) {
+	dialogs, err := b.dialogs.GetDialogs(ctx)
+	if err != nil {
+		b.log.Error("Ошибка получения списка чатов",
+			"error", err,
+			"chat_id", chatID,
+		)
+		b.editMessage(chatID, messageID, errorText(lang, err), nil)
+		return nil, false
+	}
+	return dialogs, true
+}
+
+// chatsPage формирует текст и кнопки страницы списка чатов. Номер страницы
+// приводится к допустимому, если список чатов изменился с момента отправки кнопок.
+func (b *Bot) chatsPage(lang i18n.Lang, chatID int64, dialogs []telegram.Dialog, page int) (string, tgbotapi.InlineKeyboardMarkup) {
+	pages := (len(dialogs) + chatsPageSize - 1) / chatsPageSize
+	page = max(0, min(page, pages-1))
+	first := page * chatsPageSize
+	last := min(first+chatsPageSize, len(dialogs))
+
+	var sb strings.Builder
+	sb.WriteString(lang.T("chats.title"))
+	if pages > 1 {
+		sb.WriteString(lang.T("chats.page", page+1, pages))
+	}
+	sb.WriteString(":\n")
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for i := first; i < last; i++ {
+		d := dialogs[i]
+		fmt.Fprintf(&sb, "%d. %s\n", i+1, dialogTitle(lang, d))
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(
+			fmt.Sprintf("%d. %s", i+1, d.Title),
+			b.signer.Sign(chatID, callbackChat, strconv.FormatInt(d.ID, 10), strconv.Itoa(page)),
+		)))
+	}
+
+	var nav []tgbotapi.InlineKeyboardButton
+	if page > 0 {
+		nav = append(nav, tgbotapi.NewInlineKeyboardButtonData(lang.T("chats.prev"), b.signer.Sign(chatID, callbackChatsPage, strconv.Itoa(page-1))))
+	}
+	if page < pages-1 {
+		nav = append(nav, tgbotapi.NewInlineKeyboardButtonData(lang.T("chats.next"), b.signer.Sign(chatID, callbackChatsPage, strconv.Itoa(page+1))))
+	}
+	if len(nav) > 0 {
+		rows = append(rows, nav)
+	}
+	return sb.String(), tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// dialogTitle возвращает название чата с его видом на языке lang
+func dialogTitle(lang i18n.Lang, d telegram.Dialog) string {
+	if d.Forbidden {
+		return lang.T("chats.group.banned", d.Title)
+	}
+	return lang.T("chats.group", d.Title)
+}
+
+// dialogText описывает чат для карточки чата на языке lang
+func dialogText(lang i18n.Lang, d telegram.Dialog) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s\nID: %d\n", dialogTitle(lang, d), d.ID)
+	if d.Forbidden {
+		sb.WriteString(lang.T("chats.banned") + "\n")
+		return sb.String()
+	}
+	if d.Members > 0 {
+		sb.WriteString(lang.T("chats.members", d.Members) + "\n")
+	}
+	if !d.Created.IsZero() {
+		loc := config.Location
+		if loc == nil {
+			loc = time.UTC
+		}
+		sb.WriteString(lang.T("chats.created", d.Created.In(loc).Format("02.01.2006")) + "\n")
+	}
+	return sb.String()
+}
+
+// callbackArg возвращает i-й аргумент кнопки или пустую строку
+func callbackArg(req *CallbackRequest, i int) string {
+	if i < len(req.Args) {
+		return req.Args[i]
+	}
+	return ""
+}
@@ -0,0 +1,178 @@
+package bot
+
+import (
+	"context"
+
+	"telegram-api-with-go/internal/access"
+	"telegram-api-with-go/internal/i18n"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// userArg - аргумент с @username или ID отслеживаемого пользователя
+var userArg = Arg{Name: "user", Type: ArgUser}
+
+// registerCommands регистрирует команды бота. Порядок регистрации
+// определяет порядок в /help и в меню команд Telegram.
+func (b *Bot) registerCommands() {
+	b.router.Register(Command{
+		Name:        "help",
+		Aliases:     []string{"start"},
+		Description: "command.help",
+		Handler:     b.handleHelpCommand,
+	})
+	b.router.Register(Command{
+		Name: "spy",
+		Args: []Arg{
+			{Name: "user", Type: ArgUser, Optional: true},
+			{Name: "interval", Type: ArgDuration, Optional: true},
+		},
+		Description:    "command.spy",
+		Permission:     PermissionTrack,
+		RequiresClient: true,
+		Handler:        b.handleSpyCommand,
+	})
+	b.router.Register(Command{
+		Name:        "unspy",
+		Aliases:     []string{"untrack"},
+		Args:        []Arg{userArg},
+		Description: "command.unspy",
+		Permission:  PermissionTrack,
+		Handler:     b.handleUnspyCommand,
+	})
+	b.router.Register(Command{
+		Name:        "tracked",
+		Aliases:     []string{"list"},
+		Description: "command.tracked",
+		Permission:  PermissionView,
+		Handler:     b.handleTrackedCommand,
+	})
+	b.router.Register(Command{
+		Name:        "pause",
+		Args:        []Arg{userArg},
+		Description: "command.pause",
+		Permission:  PermissionTrack,
+		Handler:     func(_ context.Context, req *Request) { b.handlePauseCommand(req, true) },
+	})
+	b.router.Register(Command{
+		Name:        "resume",
+		Args:        []Arg{userArg},
+		Description: "command.resume",
+		Permission:  PermissionTrack,
+		Handler:     func(_ context.Context, req *Request) { b.handlePauseCommand(req, false) },
+	})
+	b.router.Register(Command{
+		Name: "notify",
+		Args: []Arg{
+			{Name: "setting", Optional: true, Choices: []string{"quiet", "digest"}},
+			{Name: "value", Optional: true},
+		},
+		Description: "command.notify",
+		Permission:  PermissionTrack,
+		Handler:     b.handleNotifyCommand,
+	})
+	b.router.Register(Command{
+		Name:        "report",
+		Args:        []Arg{userArg, {Name: "period", Optional: true}},
+		Description: "command.report",
+		Permission:  PermissionView,
+		Handler:     b.handleReportCommand,
+	})
+	b.router.Register(Command{
+		Name:        "export",
+		Args:        []Arg{userArg, {Name: "options", Optional: true, Rest: true}},
+		Description: "command.export",
+		Permission:  PermissionView,
+		Handler:     b.handleExportCommand,
+	})
+	b.router.Register(Command{
+		Name:        "allow",
+		Description: "command.allow",
+		Handler:     b.handleAllowCommand,
+	})
+	b.router.Register(Command{
+		Name:        "deny",
+		Args:        []Arg{{Name: "delete", Optional: true, Choices: []string{"delete"}}},
+		Description: "command.deny",
+		Handler:     b.handleDenyCommand,
+	})
+	b.router.Register(Command{
+		Name:           "chats",
+		Description:    "command.chats",
+		Permission:     PermissionOwner,
+		RequiresClient: true,
+		Handler:        b.handleChatsCommand,
+	})
+	b.router.Register(Command{
+		Name: "members",
+		Args: []Arg{
+			{Name: "chat"},
+			{Name: "filter", Optional: true, Choices: []string{"recent", "admins", "banned", "restricted", "bots"}},
+		},
+		Description:    "command.members",
+		Permission:     PermissionOwner,
+		RequiresClient: true,
+		Handler:        b.handleMembersCommand,
+	})
+	b.router.Register(Command{
+		Name:        "status",
+		Description: "command.status",
+		Permission:  PermissionView,
+		Handler:     b.handleStatusCommand,
+	})
+	b.router.Register(Command{
+		Name:        "grant",
+		Args:        []Arg{userArg, {Name: "role", Choices: []string{string(access.RoleAdmin), string(access.RoleViewer)}}},
+		Description: "command.grant",
+		Permission:  PermissionOwner,
+		Handler:     b.handleGrantCommand,
+	})
+	b.router.Register(Command{
+		Name:        "revoke",
+		Args:        []Arg{userArg},
+		Description: "command.revoke",
+		Permission:  PermissionOwner,
+		Handler:     b.handleRevokeCommand,
+	})
+	b.router.Register(Command{
+		Name:        "roles",
+		Description: "command.roles",
+		Permission:  PermissionOwner,
+		Handler:     b.handleRolesCommand,
+	})
+	b.router.Register(Command{
+		Name:        "lang",
+		Args:        []Arg{{Name: "language", Optional: true, Choices: langChoices()}},
+		Description: "command.lang",
+		Handler:     b.handleLangCommand,
+	})
+	b.router.Register(Command{
+		Name:        "cancel",
+		Description: "command.cancel",
+		Handler:     b.handleCancelCommand,
+	})
+}
+
+// handleHelpCommand обрабатывает команду /help
+func (b *Bot) handleHelpCommand(_ context.Context, req *Request) {
+	role := b.roleOf(req.Message.From)
+	help := b.router.HelpText(req.Lang, func(cmd *Command) bool { return permits(role, cmd.Permission) })
+	header := req.Lang.T("help.header")
+	b.reply(req.ChatID, help, tgbotapi.MessageEntity{Type: "bold", Offset: 0, Length: utf16Len(header)})
+}
+
+// publishCommands публикует список команд в меню Telegram методом setMyCommands
+// на каждом поддерживаемом языке. Ошибка не мешает работе бота и только записывается в лог.
+func (b *Bot) publishCommands() {
+	for _, lang := range i18n.Langs() {
+		params, err := b.router.setMyCommandsParams(lang)
+		if err == nil {
+			_, err = b.api.MakeRequest("setMyCommands", params)
+		}
+		if err != nil {
+			b.log.Warn("Не удалось опубликовать список команд", "lang", lang, "error", err)
+			continue
+		}
+		b.log.Info("Список команд опубликован", "lang", lang, "commands", len(b.router.Commands()))
+	}
+}
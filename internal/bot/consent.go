@@ -0,0 +1,99 @@
+package bot
+
+import (
+	"context"
+	"errors"
+
+	"telegram-api-with-go/internal/consent"
+	"telegram-api-with-go/internal/telegram"
+)
+
+// errNoStore возвращается, если для команды нужна история статусов, а хранилище не настроено
+var errNoStore = errors.New("хранилище истории не настроено")
+
+// handleAllowCommand обрабатывает команду /allow: отправитель соглашается на отслеживание
+func (b *Bot) handleAllowCommand(_ context.Context, req *Request) {
+	chatID := req.ChatID
+	from := req.Message.From
+
+	added, err := b.consent.Allow(consent.Grant{
+		UserID:    int64(from.ID),
+		Username:  from.UserName,
+		FirstName: from.FirstName,
+		LastName:  from.LastName,
+	})
+	if err != nil {
+		b.log.Error("Ошибка записи согласия", "user_id", from.ID, "error", err)
+		b.reply(chatID, errorText(req.Lang, err))
+		return
+	}
+	// Пользователь мог быть добавлен в реестр до того, как согласие стало обязательным
+	b.trackers.Activate(int64(from.ID))
+
+	if !added {
+		b.reply(chatID, req.Lang.T("allow.already"))
+		return
+	}
+	b.reply(chatID, req.Lang.T("allow.done"))
+}
+
+// handleDenyCommand обрабатывает команду /deny [delete]: отправитель отзывает согласие,
+// слежение за ним прекращается во всех чатах, а с delete удаляется и история статусов
+func (b *Bot) handleDenyCommand(_ context.Context, req *Request) {
+	chatID := req.ChatID
+	from := req.Message.From
+	userID := int64(from.ID)
+	deleteHistory := req.Args.Has("delete")
+
+	removed, err := b.consent.Deny(userID, from.UserName)
+	if err != nil {
+		b.log.Error("Ошибка отзыва согласия", "user_id", userID, "error", err)
+		b.reply(chatID, errorText(req.Lang, err))
+		return
+	}
+
+	target, err := b.trackers.Remove(userID)
+	switch {
+	case errors.Is(err, telegram.ErrNotTracked):
+	case err != nil:
+		b.log.Error("Ошибка остановки слежения после отзыва согласия", "user_id", userID, "error", err)
+		b.reply(chatID, errorText(req.Lang, err))
+		return
+	default:
+		for _, subscriber := range target.Subscribers {
+			if subscriber == chatID {
+				continue
+			}
+			b.reply(subscriber, b.chatLang(subscriber).T("deny.subscriber", target.Label()))
+		}
+	}
+
+	reply := req.Lang.T("deny.done")
+	if !removed {
+		reply = req.Lang.T("deny.not_given")
+	}
+	if deleteHistory {
+		if err := b.deleteHistory(userID, from.UserName); err != nil {
+			b.log.Error("Ошибка удаления истории", "user_id", userID, "error", err)
+			text := req.Lang.T("deny.history_error", err)
+			if errors.Is(err, errNoStore) {
+				text = req.Lang.T("error.no_store")
+			}
+			b.reply(chatID, reply+"\n"+text)
+			return
+		}
+		reply += "\n" + req.Lang.T("deny.history_deleted")
+	}
+	b.reply(chatID, reply)
+}
+
+// deleteHistory удаляет историю статусов пользователя и записывает это в журнал согласий
+func (b *Bot) deleteHistory(userID int64, username string) error {
+	if b.store == nil {
+		return errNoStore
+	}
+	if err := b.store.Delete(userID); err != nil {
+		return err
+	}
+	return b.consent.Record(userID, username, consent.ActionDeleteHistory)
+}
@@ -0,0 +1,236 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"telegram-api-with-go/internal/config"
+	"telegram-api-with-go/internal/conversation"
+	"telegram-api-with-go/internal/i18n"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// Step - вопрос многошаговой команды
+type Step struct {
+	// Name - имя ответа в значениях, передаваемых Done
+	Name string
+	// Prompt - ключ каталога с вопросом
+	Prompt string
+	// Validate проверяет ответ и возвращает сохраняемое значение.
+	// *AnswerError повторяет вопрос, другие ошибки прерывают диалог.
+	Validate func(ctx context.Context, req *Request, answer string) (string, error)
+}
+
+// Conversation описывает многошаговую команду: бот задает вопросы Steps по
+// очереди и после последнего ответа вызывает Done с ответами по именам шагов
+type Conversation struct {
+	Command string
+	Steps   []Step
+	Done    func(ctx context.Context, req *Request, values map[string]string)
+}
+
+// AnswerError - некорректный ответ на вопрос; Key и Args - сообщение из каталога
+type AnswerError struct {
+	Key  string
+	Args []any
+}
+
+func (e *AnswerError) Error() string {
+	return i18n.Default.T(e.Key, e.Args...)
+}
+
+// registerConversation добавляет многошаговую команду. Команда должна быть
+// зарегистрирована в маршрутизаторе; повтор - ошибка программы, поэтому функция паникует.
+func (b *Bot) registerConversation(c Conversation) {
+	if _, ok := b.router.Command(c.Command); !ok {
+		panic(fmt.Sprintf("bot: диалог для незарегистрированной команды /%s", c.Command))
+	}
+	if _, ok := b.flows[c.Command]; ok {
+		panic(fmt.Sprintf("bot: диалог команды /%s уже зарегистрирован", c.Command))
+	}
+	if len(c.Steps) == 0 || c.Done == nil {
+		panic(fmt.Sprintf("bot: диалог команды /%s без шагов или завершения", c.Command))
+	}
+	b.flows[c.Command] = &c
+}
+
+// startConversation начинает диалог команды req.Command с отправителем и задает первый вопрос.
+// Новый диалог заменяет незавершенный диалог отправителя; диалоги других участников чата
+// продолжаются.
+func (b *Bot) startConversation(req *Request) {
+	flow := b.flows[req.Command.Name]
+	st := conversation.State{Command: flow.Command, UserID: int64(req.Message.From.ID)}
+	if _, err := b.conversations.Save(req.ChatID, st); err != nil {
+		b.log.Error("Ошибка сохранения диалога", "chat_id", req.ChatID, "error", err)
+		b.reply(req.ChatID, errorText(req.Lang, err))
+		return
+	}
+	b.log.Info("Начат диалог",
+		"command", flow.Command,
+		"user_id", st.UserID,
+		"chat_id", req.ChatID,
+	)
+	b.askStep(req.ChatID, req.Lang, flow.Steps[0])
+}
+
+// askStep задает вопрос шага
+func (b *Bot) askStep(chatID int64, lang i18n.Lang, step Step) {
+	b.reply(chatID, stepPrompt(lang, step))
+}
+
+// stepPrompt возвращает вопрос шага с подсказкой об отмене
+func stepPrompt(lang i18n.Lang, step Step) string {
+	return lang.T(step.Prompt) + "\n" + lang.T("conversation.cancel_hint")
+}
+
+// handleAnswer обрабатывает сообщение без команды как ответ на вопрос диалога.
+// Возвращает false, если в чате нет диалога с отправителем.
+func (b *Bot) handleAnswer(ctx context.Context, update tgbotapi.Update, lang i18n.Lang) bool {
+	msg := update.Message
+	chatID := msg.Chat.ID
+	if msg.From == nil {
+		return false
+	}
+	st, ok := b.conversations.Get(chatID, int64(msg.From.ID))
+	if !ok {
+		return false
+	}
+	flow, ok := b.flows[st.Command]
+	cmd, _ := b.router.Command(st.Command)
+	if !ok || st.Step >= len(flow.Steps) {
+		// Диалог сохранен версией бота с другим набором шагов
+		b.log.Warn("Неизвестный диалог удален", "command", st.Command, "step", st.Step, "chat_id", chatID)
+		b.endConversation(chatID, st.UserID)
+		return false
+	}
+
+	req := &Request{Update: update, Message: msg, ChatID: chatID, Command: cmd, Lang: lang}
+	// Права и клиент проверяются на каждом шаге: за время диалога они могли измениться
+	if !b.authorize(req) {
+		b.endConversation(chatID, st.UserID)
+		return true
+	}
+	if cmd.RequiresClient && !b.requireClient(chatID, lang) {
+		return true
+	}
+
+	step := flow.Steps[st.Step]
+	answer := strings.TrimSpace(msg.Text)
+	value, err := step.Validate(ctx, req, answer)
+	var answerErr *AnswerError
+	switch {
+	case errors.As(err, &answerErr):
+		b.log.Info("Некорректный ответ в диалоге",
+			"command", st.Command,
+			"step", step.Name,
+			"answer", answer,
+			"chat_id", chatID,
+		)
+		// Повторный вопрос продлевает ожидание ответа
+		if _, ok, err := b.conversations.CompareAndSave(chatID, st, st); err != nil {
+			b.log.Error("Ошибка сохранения диалога", "chat_id", chatID, "error", err)
+		} else if !ok {
+			b.conversationGone(chatID, st)
+			return true
+		}
+		b.reply(chatID, lang.T(answerErr.Key, answerErr.Args...)+"\n"+stepPrompt(lang, step))
+		return true
+	case err != nil:
+		b.log.Error("Ошибка проверки ответа в диалоге", "command", st.Command, "step", step.Name, "error", err)
+		b.endConversation(chatID, st.UserID)
+		b.reply(chatID, errorText(lang, err))
+		return true
+	}
+
+	// Пока проверялся ответ, диалог мог истечь или быть отменен: следующий шаг
+	// сохраняется, только если диалог не изменился
+	next := st
+	if next.Values == nil {
+		next.Values = make(map[string]string)
+	}
+	next.Values[step.Name] = value
+	next.Step++
+	if next.Step < len(flow.Steps) {
+		_, ok, err := b.conversations.CompareAndSave(chatID, st, next)
+		switch {
+		case err != nil:
+			b.log.Error("Ошибка сохранения диалога", "chat_id", chatID, "error", err)
+			b.reply(chatID, errorText(lang, err))
+		case !ok:
+			b.conversationGone(chatID, st)
+		default:
+			b.askStep(chatID, lang, flow.Steps[next.Step])
+		}
+		return true
+	}
+
+	if ok, err := b.conversations.CompareAndDelete(chatID, st); err != nil {
+		b.log.Error("Ошибка удаления диалога", "chat_id", chatID, "error", err)
+	} else if !ok {
+		b.conversationGone(chatID, st)
+		return true
+	}
+	b.log.Info("Диалог завершен", "command", st.Command, "user_id", st.UserID, "chat_id", chatID)
+	flow.Done(ctx, req, next.Values)
+	return true
+}
+
+// endConversation удаляет диалог пользователя в чате
+func (b *Bot) endConversation(chatID, userID int64) {
+	if _, _, err := b.conversations.Delete(chatID, userID); err != nil {
+		b.log.Error("Ошибка удаления диалога", "chat_id", chatID, "error", err)
+	}
+}
+
+// conversationGone записывает ответ, пришедший в диалог, который истек или был
+// отменен во время обработки ответа. Об истечении сообщает expireConversations.
+func (b *Bot) conversationGone(chatID int64, st conversation.State) {
+	b.log.Info("Ответ не учтен: диалог истек или отменен во время обработки",
+		"command", st.Command,
+		"user_id", st.UserID,
+		"chat_id", chatID,
+	)
+}
+
+// handleCancelCommand обрабатывает команду /cancel: прерывает диалог отправителя
+func (b *Bot) handleCancelCommand(_ context.Context, req *Request) {
+	st, ok := b.conversations.Get(req.ChatID, int64(req.Message.From.ID))
+	if !ok {
+		b.reply(req.ChatID, req.Lang.T("conversation.none"))
+		return
+	}
+	b.endConversation(req.ChatID, st.UserID)
+	b.log.Info("Диалог отменен", "command", st.Command, "user_id", st.UserID, "chat_id", req.ChatID)
+	b.reply(req.ChatID, req.Lang.T("conversation.cancelled", st.Command))
+}
+
+// expireConversations каждые четверть времени ожидания прерывает диалоги без ответа
+func (b *Bot) expireConversations(ctx context.Context) {
+	interval := max(min(config.ConversationTimeout/4, time.Minute), 10*time.Millisecond)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		expired, err := b.conversations.Expire()
+		if err != nil {
+			b.log.Error("Ошибка сохранения диалогов", "error", err)
+		}
+		for _, e := range expired {
+			b.log.Info("Диалог прерван: истекло время ожидания ответа",
+				"command", e.State.Command,
+				"user_id", e.State.UserID,
+				"chat_id", e.ChatID,
+			)
+			b.reply(e.ChatID, b.chatLang(e.ChatID).T("conversation.timeout", e.State.Command))
+		}
+	}
+}
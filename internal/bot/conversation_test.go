@@ -0,0 +1,172 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"telegram-api-with-go/internal/access"
+	"telegram-api-with-go/internal/config"
+	"telegram-api-with-go/internal/conversation"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/gotd/td/tg"
+)
+
+const (
+	askUser   = "За кем следить? Отправьте @username или ID пользователя.\nОтменить: /cancel"
+	askNotify = "Как уведомлять о смене статуса в этом чате?\nall - о каждом переходе\ndigest - ежедневной сводкой\n" +
+		"<с>-<до> - часы тишины, например 23-7\nkeep - оставить текущие настройки\nОтменить: /cancel"
+)
+
+// answer отправляет боту ответ без команды и возвращает тексты ответов бота
+func (tb *testBot) answer(t *testing.T, text string, replies int) []string {
+	t.Helper()
+	tb.api.updates <- textUpdate(text)
+	var texts []string
+	for _, c := range tb.api.waitSent(t, replies) {
+		texts = append(texts, messageText(t, c))
+	}
+	return texts
+}
+
+func TestSpyConversation(t *testing.T) {
+	tb := newTestBot(t)
+	config.DefaultSpyUserID = 0
+	tb.client.SetUser(&tg.User{ID: 2002, Username: "alice"})
+	tb.client.SetUser(&tg.User{ID: 4004, Username: "carol"})
+	tb.start(t)
+
+	tb.send("/spy")
+	if got := messageText(t, tb.api.waitSent(t, 1)[0]); got != askUser {
+		t.Fatalf("first question = %q", got)
+	}
+	for _, tc := range []struct {
+		answer string
+		want   string
+	}{
+		{"кто-нибудь", "\"кто-нибудь\" не похоже на @username или ID пользователя.\n" + askUser},
+		{"@nobody", "Пользователь @nobody не найден.\n" + askUser},
+		{"@carol", "Пользователь @carol не давал согласия на отслеживание. Слежение станет возможным, " +
+			"когда пользователь сам отправит боту команду /allow.\n" + askUser},
+		{"@alice", askNotify},
+	} {
+		if got := tb.answer(t, tc.answer, 1); got[0] != tc.want {
+			t.Fatalf("%s: reply = %q, want %q", tc.answer, got[0], tc.want)
+		}
+	}
+
+	// Диалог продолжается после перезапуска бота
+	restarted := &testBot{api: newFakeAPI(), client: tb.client, status: tb.status, store: tb.store}
+	restarted.bot = NewWithAPI(restarted.api, "test_bot", restarted.deps())
+	restarted.start(t)
+
+	if got := restarted.answer(t, "sometimes", 1); got[0] != "Непонятный ответ \"sometimes\".\n"+askNotify {
+		t.Fatalf("invalid notify answer = %q", got[0])
+	}
+	got := restarted.answer(t, "Digest", 2)
+	if got[0] != "Теперь вы следите за пользователем @alice (2002)." ||
+		!strings.Contains(got[1], "Ежедневная сводка в") {
+		t.Fatalf("finish = %q", got)
+	}
+	if settings := restarted.bot.notifyPrefs.Get(testChatID); !settings.Digest {
+		t.Fatalf("notify settings = %+v", settings)
+	}
+
+	// Завершенный диалог больше не ждет ответа
+	if got := restarted.answer(t, "@alice", 1); got[0] != "Неизвестная команда. Список команд: /help" {
+		t.Fatalf("answer after finish = %q", got[0])
+	}
+}
+
+func TestConversationCancel(t *testing.T) {
+	tb := newTestBot(t)
+	config.DefaultSpyUserID = 0
+	tb.client.SetUser(&tg.User{ID: 2002, Username: "alice"})
+	tb.start(t)
+
+	tb.send("/spy")
+	tb.api.waitSent(t, 1)
+
+	// Другие команды не прерывают диалог, а ответы других участников чата не учитываются
+	tb.send("/tracked")
+	if got := messageText(t, tb.api.waitSent(t, 1)[0]); !strings.HasPrefix(got, "Вы ни за кем не следите.") {
+		t.Fatalf("tracked = %q", got)
+	}
+	other := textUpdate("@alice")
+	other.Message.From = &tgbotapi.User{ID: 8, UserName: "other"}
+	tb.api.updates <- other
+	if got := messageText(t, tb.api.waitSent(t, 1)[0]); got != "Неизвестная команда. Список команд: /help" {
+		t.Fatalf("answer from another user = %q", got)
+	}
+
+	for _, want := range []string{"Команда /spy отменена.", "Нечего отменять: бот ничего у вас не спрашивает."} {
+		tb.send("/cancel")
+		if got := messageText(t, tb.api.waitSent(t, 1)[0]); got != want {
+			t.Fatalf("cancel = %q, want %q", got, want)
+		}
+	}
+	if targets := tb.bot.trackers.ForChat(testChatID); len(targets) != 0 {
+		t.Fatalf("cancelled conversation tracked %+v", targets)
+	}
+}
+
+func TestConversationPerUser(t *testing.T) {
+	tb := newTestBot(t)
+	config.DefaultSpyUserID = 0
+	tb.client.SetUser(&tg.User{ID: 2002, Username: "alice"})
+	tb.client.SetUser(&tg.User{ID: 3003, Username: "bob"})
+	if err := tb.bot.roles.Grant(8, access.RoleAdmin, 7); err != nil {
+		t.Fatal(err)
+	}
+	tb.start(t)
+
+	other := &tgbotapi.User{ID: 8, UserName: "other"}
+	otherSays := func(text string) string {
+		t.Helper()
+		update := textUpdate(text)
+		if strings.HasPrefix(text, "/") {
+			update = commandUpdateFrom(text, testChatID, other)
+		}
+		update.Message.From = other
+		tb.api.updates <- update
+		return messageText(t, tb.api.waitSent(t, 1)[0])
+	}
+
+	tb.send("/spy")
+	tb.api.waitSent(t, 1)
+	if got := tb.answer(t, "@alice", 1); got[0] != askNotify {
+		t.Fatalf("second question = %q", got[0])
+	}
+
+	// /spy другого участника группы начинает его собственный диалог и не прерывает начатый
+	if got := otherSays("/spy"); got != askUser {
+		t.Fatalf("other /spy = %q", got)
+	}
+	if got := otherSays("@bob"); got != askNotify {
+		t.Fatalf("other answer = %q", got)
+	}
+	if got := tb.answer(t, "keep", 1); got[0] != "Теперь вы следите за пользователем @alice (2002)." {
+		t.Fatalf("first user finish = %q", got[0])
+	}
+	if got := otherSays("keep"); got != "Теперь вы следите за пользователем @bob (3003)." {
+		t.Fatalf("other user finish = %q", got)
+	}
+}
+
+func TestConversationTimeout(t *testing.T) {
+	tb := newTestBot(t)
+	config.DefaultSpyUserID = 0
+	config.ConversationTimeout = 50 * time.Millisecond
+	tb.bot.conversations = conversation.NewStore(config.ConversationsFile, config.ConversationTimeout)
+	tb.start(t)
+
+	tb.send("/spy")
+	tb.api.waitSent(t, 1)
+	if got := messageText(t, tb.api.waitSent(t, 1)[0]); got != "Ответ не получен вовремя, команда /spy отменена." {
+		t.Fatalf("timeout = %q", got)
+	}
+	if got := tb.answer(t, "@alice", 1); got[0] != "Неизвестная команда. Список команд: /help" {
+		t.Fatalf("answer after timeout = %q", got[0])
+	}
+}
@@ -0,0 +1,123 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"telegram-api-with-go/internal/config"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// dispatcher обрабатывает обновления разных чатов параллельно, сохраняя
+// порядок внутри чата: у каждого чата с необработанными обновлениями есть
+// своя очередь, а одновременно выполняется не больше workers обработчиков
+type dispatcher struct {
+	handle func(chatID int64, update tgbotapi.Update)
+	slots  chan struct{}
+
+	mu     sync.Mutex
+	queues map[int64][]tgbotapi.Update
+	wg     sync.WaitGroup
+}
+
+// newDispatcher создает диспетчер, вызывающий handle не больше чем в workers горутинах одновременно
+func newDispatcher(workers int, handle func(chatID int64, update tgbotapi.Update)) *dispatcher {
+	return &dispatcher{
+		handle: handle,
+		slots:  make(chan struct{}, max(workers, 1)),
+		queues: make(map[int64][]tgbotapi.Update),
+	}
+}
+
+// Dispatch ставит обновление в очередь чата chatID и не ждет его обработки
+func (d *dispatcher) Dispatch(chatID int64, update tgbotapi.Update) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	queue, running := d.queues[chatID]
+	d.queues[chatID] = append(queue, update)
+	if running {
+		return
+	}
+	d.wg.Add(1)
+	go d.drain(chatID)
+}
+
+// drain обрабатывает очередь чата, пока она не опустеет
+func (d *dispatcher) drain(chatID int64) {
+	defer d.wg.Done()
+	for {
+		d.mu.Lock()
+		queue := d.queues[chatID]
+		if len(queue) == 0 {
+			delete(d.queues, chatID)
+			d.mu.Unlock()
+			return
+		}
+		update := queue[0]
+		d.queues[chatID] = queue[1:]
+		d.mu.Unlock()
+
+		d.slots <- struct{}{}
+		d.handle(chatID, update)
+		<-d.slots
+	}
+}
+
+// Wait ждет обработки всех поставленных в очередь обновлений
+func (d *dispatcher) Wait() {
+	d.wg.Wait()
+}
+
+// updateChat возвращает чат, в который бот отвечает на обновление, и отправителя
+func updateChat(update tgbotapi.Update) (int64, *tgbotapi.User) {
+	if cb := update.CallbackQuery; cb != nil {
+		if cb.Message != nil && cb.Message.Chat != nil {
+			return cb.Message.Chat.ID, cb.From
+		}
+		// Кнопка inline-сообщения: отвечаем в личный чат нажавшего
+		if cb.From != nil {
+			return int64(cb.From.ID), cb.From
+		}
+		return 0, nil
+	}
+	if update.Message != nil && update.Message.Chat != nil {
+		return update.Message.Chat.ID, update.Message.From
+	}
+	return 0, nil
+}
+
+// processUpdate обрабатывает обновление с ограничением времени HANDLER_TIMEOUT.
+// Паника обработчика не завершает бота: она записывается в лог со стеком,
+// а отправитель получает сообщение об ошибке.
+func (b *Bot) processUpdate(ctx context.Context, chatID int64, update tgbotapi.Update) {
+	ctx, cancel := context.WithTimeout(ctx, config.HandlerTimeout)
+	defer cancel()
+
+	start := time.Now()
+	defer func() {
+		if r := recover(); r != nil {
+			_, from := updateChat(update)
+			b.log.Error("Паника при обработке обновления",
+				"panic", r,
+				"update_id", update.UpdateID,
+				"chat_id", chatID,
+				"stack", string(debug.Stack()),
+			)
+			b.reply(chatID, b.userLang(from).T("error.internal"))
+			return
+		}
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			b.log.Warn("Обработка обновления превысила HANDLER_TIMEOUT",
+				"update_id", update.UpdateID,
+				"chat_id", chatID,
+				"elapsed", time.Since(start).Round(time.Millisecond),
+			)
+		}
+	}()
+
+	b.handleUpdate(ctx, update)
+}
@@ -0,0 +1,86 @@
+package bot
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"telegram-api-with-go/internal/config"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+func TestDispatcher(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		handled = make(map[int64][]int)
+	)
+	release := make(chan struct{})
+	other := make(chan struct{})
+	d := newDispatcher(2, func(chatID int64, update tgbotapi.Update) {
+		if chatID == 1 && update.UpdateID == 1 {
+			<-release
+		}
+		mu.Lock()
+		handled[chatID] = append(handled[chatID], update.UpdateID)
+		mu.Unlock()
+		if chatID == 2 {
+			other <- struct{}{}
+		}
+	})
+
+	for id := 1; id <= 5; id++ {
+		d.Dispatch(1, tgbotapi.Update{UpdateID: id})
+	}
+	// Медленный обработчик чата 1 не задерживает другие чаты
+	d.Dispatch(2, tgbotapi.Update{UpdateID: 10})
+	select {
+	case <-other:
+	case <-time.After(5 * time.Second):
+		t.Fatal("update of another chat is blocked by a slow handler")
+	}
+
+	close(release)
+	d.Wait()
+	if got := handled[1]; len(got) != 5 || got[0] != 1 || got[1] != 2 || got[2] != 3 || got[3] != 4 || got[4] != 5 {
+		t.Fatalf("chat 1 order = %v", got)
+	}
+	if len(d.queues) != 0 {
+		t.Fatalf("queues left after Wait: %v", d.queues)
+	}
+}
+
+func TestHandlerPanicAndTimeout(t *testing.T) {
+	tb := newTestBot(t)
+	config.HandlerTimeout = 50 * time.Millisecond
+	tb.bot.router.Register(Command{
+		Name:        "boom",
+		Description: "command.help",
+		Handler:     func(context.Context, *Request) { panic("boom") },
+	})
+	tb.bot.router.Register(Command{
+		Name:        "slow",
+		Description: "command.help",
+		Handler: func(ctx context.Context, req *Request) {
+			<-ctx.Done()
+			tb.bot.reply(req.ChatID, ctx.Err().Error())
+		},
+	})
+	tb.start(t)
+
+	for _, tc := range []struct {
+		command string
+		want    string
+	}{
+		{"/boom", "Внутренняя ошибка при обработке запроса. Попробуйте еще раз позже."},
+		{"/slow", "context deadline exceeded"},
+		{"/help", "<b>Доступные команды:</b>"},
+	} {
+		tb.send(tc.command)
+		if got := messageText(t, tb.api.waitSent(t, 1)[0]); !strings.HasPrefix(got, tc.want) {
+			t.Fatalf("%s: reply = %q, want %q", tc.command, got, tc.want)
+		}
+	}
+}
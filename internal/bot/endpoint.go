@@ -0,0 +1,52 @@
+package bot
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// defaultAPIHost - хост Bot API, к которому обращается tgbotapi
+const defaultAPIHost = "api.telegram.org"
+
+// endpointTransport перенаправляет запросы к api.telegram.org на другой адрес Bot API:
+// локальный сервер Bot API или фейковый сервер в тестах. В tgbotapi v4 адрес задан
+// константой, поэтому подменяется транспорт HTTP клиента.
+type endpointTransport struct {
+	endpoint *url.URL
+	next     http.RoundTripper
+}
+
+// withEndpoint возвращает клиент, отправляющий запросы Bot API на endpoint.
+// Пустой endpoint оставляет клиент без изменений.
+func withEndpoint(client *http.Client, endpoint string) (*http.Client, error) {
+	if endpoint == "" {
+		return client, nil
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("некорректный адрес Bot API %q", endpoint)
+	}
+
+	next := client.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	redirected := *client
+	redirected.Transport = &endpointTransport{endpoint: u, next: next}
+	return &redirected, nil
+}
+
+func (t *endpointTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host != defaultAPIHost {
+		return t.next.RoundTrip(req)
+	}
+	// RoundTrip не должен изменять исходный запрос
+	req = req.Clone(req.Context())
+	req.URL.Scheme = t.endpoint.Scheme
+	req.URL.Host = t.endpoint.Host
+	req.URL.Path = strings.TrimSuffix(t.endpoint.Path, "/") + req.URL.Path
+	req.Host = t.endpoint.Host
+	return t.next.RoundTrip(req)
+}
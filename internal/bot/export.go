@@ -0,0 +1,127 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"telegram-api-with-go/internal/config"
+	"telegram-api-with-go/internal/export"
+	"telegram-api-with-go/internal/telegram"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// handleExportCommand обрабатывает команду /export и отправляет выгрузку документом
+func (b *Bot) handleExportCommand(_ context.Context, req *Request) {
+	chatID := req.ChatID
+	ref := req.Args.String("user")
+	if b.exporter == nil {
+		b.reply(chatID, req.Lang.T("error.no_store"))
+		return
+	}
+
+	target, err := b.trackers.Find(chatID, ref)
+	if err != nil {
+		if errors.Is(err, telegram.ErrNotTracked) {
+			b.reply(chatID, req.Lang.T("user.not_tracked", ref))
+			return
+		}
+		b.reply(chatID, errorText(req.Lang, err))
+		return
+	}
+
+	opts, err := parseExportArgs(req.Args.Rest("options"), time.Now())
+	if err != nil {
+		b.reply(chatID, errorText(req.Lang, err)+"\n"+req.Lang.T("export.hint"))
+		return
+	}
+	opts.UserID = target.UserID
+	opts.Label = target.Label()
+	opts.Lang = req.Lang
+
+	var buf bytes.Buffer
+	if err := b.exporter.Export(&buf, opts); err != nil {
+		b.log.Error("Ошибка выгрузки истории", "user_id", target.UserID, "error", err)
+		b.reply(chatID, errorText(req.Lang, err))
+		return
+	}
+
+	b.log.Info("Выгрузка истории",
+		"user_id", target.UserID,
+		"data", opts.Data,
+		"format", opts.Format,
+		"from", opts.From,
+		"to", opts.To,
+		"chat_id", chatID,
+	)
+	doc := tgbotapi.NewDocumentUpload(chatID, tgbotapi.FileBytes{
+		Name:  export.FileName(opts),
+		Bytes: buf.Bytes(),
+	})
+	doc.Caption = fmt.Sprintf("%s: %s, %s - %s (%s)",
+		target.Label(),
+		opts.Data,
+		opts.From.In(opts.Location).Format("02.01.2006 15:04"),
+		opts.To.In(opts.Location).Format("02.01.2006 15:04"),
+		opts.Location,
+	)
+	if _, err := b.api.Send(doc); err != nil {
+		b.log.Error("Ошибка отправки выгрузки",
+			"chat_id", chatID,
+			"error", err,
+		)
+	}
+}
+
+// unknownArgError - аргумент команды, который не удалось разобрать
+type unknownArgError struct {
+	arg string
+}
+
+func (e *unknownArgError) Error() string {
+	return fmt.Sprintf("неизвестный аргумент или часовой пояс %q", e.arg)
+}
+
+// parseExportArgs разбирает необязательные аргументы /export в любом порядке.
+// Формат ics подразумевает выгрузку сессий.
+func parseExportArgs(args []string, now time.Time) (export.Options, error) {
+	opts := export.Options{Data: export.DataSessions, Format: export.FormatCSV, Location: config.Location}
+	if opts.Location == nil {
+		opts.Location = time.UTC
+	}
+
+	period := ""
+	for _, arg := range args {
+		if data, ok := export.ParseData(arg); ok {
+			opts.Data = data
+			continue
+		}
+		if format, ok := export.ParseFormat(arg); ok {
+			opts.Format = format
+			continue
+		}
+		if strings.Contains(arg, "..") || (arg != "" && arg[0] >= '0' && arg[0] <= '9') {
+			period = arg
+			continue
+		}
+		loc, err := time.LoadLocation(arg)
+		if err != nil {
+			return export.Options{}, &unknownArgError{arg: arg}
+		}
+		opts.Location = loc
+	}
+	if opts.Format == export.FormatICS && opts.Data == export.DataTransitions {
+		return export.Options{}, export.ErrICSTransitions
+	}
+
+	var err error
+	opts.From, opts.To, err = export.ParseRange(period, now, opts.Location)
+	if err != nil {
+		return export.Options{}, err
+	}
+	return opts, nil
+}
@@ -1,116 +1,208 @@
 package bot
 
 import (
+	"bytes"
 	"context"
+	"encoding/csv"
+	"errors"
 	"fmt"
+	"strconv"
+	"time"
+
+	"telegram-api-with-go/internal/i18n"
+	"telegram-api-with-go/internal/telegram"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
 )
 
 // handleUpdate обрабатывает обновления от Telegram
 func (b *Bot) handleUpdate(ctx context.Context, update tgbotapi.Update) {
+	if update.CallbackQuery != nil {
+		b.handleCallback(ctx, update.CallbackQuery)
+		return
+	}
+
 	b.log.Info("Получено сообщение",
 		"user", update.Message.From.UserName,
 		"text", update.Message.Text,
 		"chat_id", update.Message.Chat.ID,
 	)
 
-	switch update.Message.Text {
-	case "/spy":
-		b.handleSpyCommand(ctx, update)
-	case "/chats":
-		b.handleChatsCommand(ctx, update)
-	default:
-		b.handleUnknownCommand(update)
+	lang := b.userLang(update.Message.From)
+	b.rememberLang(update.Message.Chat.ID, lang)
+
+	req, err := b.router.Route(update)
+	var argErr *ArgError
+	switch {
+	case errors.As(err, &argErr):
+		b.reply(update.Message.Chat.ID, lang.T("usage.error", argErr.Text(lang), argErr.Command.Usage(lang)))
+		return
+	case errors.Is(err, ErrNotCommand) && b.handleAnswer(ctx, update, lang):
+		return
+	case err != nil:
+		b.handleUnknownCommand(update, lang)
+		return
+	case req == nil:
+		// Команда адресована другому боту в группе
+		return
+	}
+
+	req.Lang = lang
+	if !b.authorize(req) {
+		return
 	}
+	if req.Command.RequiresClient && !b.requireClient(req.ChatID, lang) {
+		return
+	}
+	req.Command.Handler(ctx, req)
 }
 
-// handleSpyCommand обрабатывает команду /spy
-func (b *Bot) handleSpyCommand(ctx context.Context, update tgbotapi.Update) {
-	msg := tgbotapi.NewMessage(update.Message.Chat.ID, "")
+// requireClient проверяет, что Telegram клиент подключен.
+// Если клиент недоступен, бот сообщает пользователю о причине и возвращает false.
+func (b *Bot) requireClient(chatID int64, lang i18n.Lang) bool {
+	if b.status == nil {
+		return true
+	}
+	status := b.status.Status()
+	if status.State == telegram.StateReady {
+		return true
+	}
 
-	if b.spy != nil {
-		userID := b.spy.GetUserID()
-		msg.Text = fmt.Sprintf("Теперь вы следите за пользователем %d.", userID)
-		b.log.Info("Запуск слежения за пользователем",
-			"user_id", userID,
-			"initiator", update.Message.From.UserName,
-		)
-		go b.spy.StartSpying(ctx)
-	} else {
-		msg.Text = "Ошибка: сервис слежения не инициализирован"
-		b.log.Error("Сервис слежения не инициализирован",
-			"chat_id", update.Message.Chat.ID,
-		)
+	b.log.Warn("Команда отклонена: Telegram клиент недоступен",
+		"state", status.State.String(),
+		"chat_id", chatID,
+	)
+	b.reply(chatID, lang.T("client.unavailable", clientStatusText(lang, status)))
+	return false
+}
+
+// handleStatusCommand обрабатывает команду /status
+func (b *Bot) handleStatusCommand(_ context.Context, req *Request) {
+	status := telegram.ClientStatus{State: telegram.StateReady}
+	if b.status != nil {
+		status = b.status.Status()
 	}
+	m := b.trackers.Metrics()
+	b.reply(req.ChatID, req.Lang.T("status.transitions",
+		clientStatusText(req.Lang, status), m.EventTransitions, m.PollTransitions, m.ExpiryTransitions, m.Events, m.Polls))
+}
 
-	if _, err := b.api.Send(msg); err != nil {
-		b.log.Error("Ошибка отправки сообщения",
-			"chat_id", update.Message.Chat.ID,
-			"error", err,
-		)
+// clientStatusText описывает состояние Telegram клиента для пользователя на языке lang
+func clientStatusText(lang i18n.Lang, status telegram.ClientStatus) string {
+	switch status.State {
+	case telegram.StateReady:
+		return lang.T("client.ready")
+	case telegram.StateStarting:
+		return lang.T("client.starting")
+	case telegram.StateReconnecting:
+		wait := time.Until(status.NextRestart).Round(time.Second)
+		if wait < 0 {
+			wait = 0
+		}
+		return lang.T("client.reconnecting", wait, status.Restarts, status.Err)
+	case telegram.StateFailed:
+		return lang.T("client.failed", status.Err)
+	default:
+		return lang.T("client.stopped")
 	}
 }
 
-// handleChatsCommand обрабатывает команду /chats
-func (b *Bot) handleChatsCommand(ctx context.Context, update tgbotapi.Update) {
-	msg := tgbotapi.NewMessage(update.Message.Chat.ID, "Запрашиваю список чатов...")
-	b.api.Send(msg)
+// handleMembersCommand обрабатывает команду /members <чат> [фильтр]
+// и отправляет список участников администрируемого чата в формате CSV
+func (b *Bot) handleMembersCommand(ctx context.Context, req *Request) {
+	chatID := req.ChatID
+	chat := req.Args.String("chat")
 
-	b.log.Info("Запрос списка чатов",
-		"user", update.Message.From.UserName,
-		"chat_id", update.Message.Chat.ID,
+	filter, err := telegram.ParseParticipantsFilter(req.Args.String("filter"))
+	if err != nil {
+		b.reply(chatID, errorText(req.Lang, err))
+		return
+	}
+
+	b.log.Info("Запрос участников чата",
+		"user", req.Message.From.UserName,
+		"chat", chat,
+		"filter", filter,
+		"chat_id", chatID,
 	)
+	b.reply(chatID, req.Lang.T("members.requesting"))
 
-	chats, err := b.client.GetChats(ctx)
+	members, err := b.members.GetMembers(ctx, chat, filter)
 	if err != nil {
-		msg.Text = "Ошибка: " + err.Error()
-		b.log.Error("Ошибка получения списка чатов",
+		b.log.Error("Ошибка получения участников чата",
 			"error", err,
-			"chat_id", update.Message.Chat.ID,
+			"chat", chat,
+			"chat_id", chatID,
 		)
-		b.api.Send(msg)
+		switch {
+		case errors.Is(err, telegram.ErrNotChatAdmin):
+			b.reply(chatID, req.Lang.T("members.not_admin"))
+		case errors.Is(err, telegram.ErrChatNotFound):
+			b.reply(chatID, req.Lang.T("members.not_found"))
+		default:
+			b.reply(chatID, errorText(req.Lang, err))
+		}
 		return
 	}
 
-	if len(chats) == 0 {
-		msg.Text = "Чаты не найдены."
-		b.log.Info("Чаты не найдены",
-			"chat_id", update.Message.Chat.ID,
-		)
-	} else {
-		text := "Список чатов:\n"
-		for i, chat := range chats {
-			text += fmt.Sprintf("%d. %s\n", i+1, chat)
-		}
-		msg.Text = text
-		b.log.Info("Отправка списка чатов",
-			"count", len(chats),
-			"chat_id", update.Message.Chat.ID,
-		)
+	data, err := membersCSV(members.Members)
+	if err != nil {
+		b.log.Error("Ошибка формирования CSV", "error", err, "chat_id", chatID)
+		b.reply(chatID, errorText(req.Lang, err))
+		return
 	}
 
-	if _, err := b.api.Send(msg); err != nil {
-		b.log.Error("Ошибка отправки списка чатов",
+	doc := tgbotapi.NewDocumentUpload(chatID, tgbotapi.FileBytes{
+		Name:  fmt.Sprintf("members_%d_%s.csv", members.ChatID, members.Filter),
+		Bytes: data,
+	})
+	doc.Caption = req.Lang.N("members.caption", len(members.Members), members.Title, len(members.Members), members.Filter)
+	if _, err := b.api.Send(doc); err != nil {
+		b.log.Error("Ошибка отправки списка участников",
 			"error", err,
-			"chat_id", update.Message.Chat.ID,
+			"chat_id", chatID,
 		)
 	}
 }
 
+// membersCSV формирует CSV со списком участников
+func membersCSV(members []telegram.Member) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+
+	if err := w.Write([]string{"user_id", "username", "first_name", "last_name", "bot", "role", "joined_at"}); err != nil {
+		return nil, err
+	}
+	for _, m := range members {
+		var joined string
+		if !m.JoinedAt.IsZero() {
+			joined = m.JoinedAt.UTC().Format(time.RFC3339)
+		}
+		record := []string{
+			strconv.FormatInt(m.UserID, 10),
+			m.Username,
+			m.FirstName,
+			m.LastName,
+			strconv.FormatBool(m.Bot),
+			string(m.Role),
+			joined,
+		}
+		if err := w.Write(record); err != nil {
+			return nil, err
+		}
+	}
+
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
 // handleUnknownCommand обрабатывает неизвестные команды
-func (b *Bot) handleUnknownCommand(update tgbotapi.Update) {
+func (b *Bot) handleUnknownCommand(update tgbotapi.Update, lang i18n.Lang) {
 	b.log.Warn("Получена неизвестная команда",
 		"command", update.Message.Text,
 		"user", update.Message.From.UserName,
 		"chat_id", update.Message.Chat.ID,
 	)
 
-	msg := tgbotapi.NewMessage(update.Message.Chat.ID, "Неизвестная команда. Доступные команды: /spy, /chats")
-	if _, err := b.api.Send(msg); err != nil {
-		b.log.Error("Ошибка отправки сообщения о неизвестной команде",
-			"error", err,
-			"chat_id", update.Message.Chat.ID,
-		)
-	}
+	b.reply(update.Message.Chat.ID, lang.T("command.unknown"))
 } 
\ No newline at end of file
@@ -0,0 +1,124 @@
+package bot
+
+import (
+	"context"
+	"errors"
+
+	"telegram-api-with-go/internal/export"
+	"telegram-api-with-go/internal/i18n"
+	"telegram-api-with-go/internal/report"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// langAuto - значение /lang, возвращающее язык из настроек Telegram
+const langAuto = "auto"
+
+// langChoices возвращает допустимые значения аргумента /lang
+func langChoices() []string {
+	var choices []string
+	for _, lang := range i18n.Langs() {
+		choices = append(choices, string(lang))
+	}
+	return append(choices, langAuto)
+}
+
+// userLang возвращает язык ответов пользователю: выбранный командой /lang,
+// а без него - язык из настроек Telegram
+func (b *Bot) userLang(from *tgbotapi.User) i18n.Lang {
+	if from == nil {
+		return i18n.Default
+	}
+	if lang, ok := b.langs.Get(int64(from.ID)); ok {
+		return lang
+	}
+	return i18n.FromCode(from.LanguageCode)
+}
+
+// rememberLang запоминает язык последнего отправителя в чате для сообщений,
+// которые бот отправляет в чат сам
+func (b *Bot) rememberLang(chatID int64, lang i18n.Lang) {
+	b.langMu.Lock()
+	defer b.langMu.Unlock()
+	b.chatLangs[chatID] = lang
+}
+
+// chatLang возвращает язык сообщений, которые бот отправляет в чат не в ответ
+// на команду: уведомлений и сообщений другим подписчикам. В личном чате ID чата
+// совпадает с ID пользователя, поэтому учитывается выбранный им язык.
+func (b *Bot) chatLang(chatID int64) i18n.Lang {
+	if lang, ok := b.langs.Get(chatID); ok {
+		return lang
+	}
+	b.langMu.Lock()
+	defer b.langMu.Unlock()
+	if lang, ok := b.chatLangs[chatID]; ok {
+		return lang
+	}
+	return i18n.Default
+}
+
+// errorText возвращает текст ошибки для ответа на языке lang. Ошибки в аргументах
+// команд переводятся по каталогу, остальные выводятся как есть.
+func errorText(lang i18n.Lang, err error) string {
+	var argErr *unknownArgError
+	switch {
+	case errors.As(err, &argErr):
+		return lang.T("error.unknown_arg", argErr.arg)
+	case errors.Is(err, report.ErrInvalidPeriod):
+		return lang.T("error.bad_period")
+	case errors.Is(err, report.ErrPeriodTooLong):
+		return lang.T("error.period_too_long", int(report.MaxPeriod.Hours()/24))
+	case errors.Is(err, export.ErrInvalidRange):
+		return lang.T("error.bad_range")
+	case errors.Is(err, export.ErrICSTransitions):
+		return lang.T("error.ics_transitions")
+	case errors.Is(err, errNoStore):
+		return lang.T("error.no_store")
+	}
+	return lang.T("error", err)
+}
+
+// handleLangCommand обрабатывает команду /lang [ru|en|auto]
+func (b *Bot) handleLangCommand(_ context.Context, req *Request) {
+	from := req.Message.From
+	userID := int64(from.ID)
+
+	choice := req.Args.String("language")
+	if choice == "" {
+		text := req.Lang.T("lang.auto", req.Lang.Name())
+		if _, ok := b.langs.Get(userID); ok {
+			text = req.Lang.T("lang.chosen", req.Lang.Name())
+		}
+		b.reply(req.ChatID, text+"\n"+req.Lang.T("lang.usage"))
+		return
+	}
+
+	var (
+		lang i18n.Lang
+		err  error
+		key  string
+	)
+	if choice == langAuto {
+		lang, key = i18n.FromCode(from.LanguageCode), "lang.reset"
+		err = b.langs.Reset(userID)
+	} else {
+		lang, _ = i18n.Parse(choice)
+		key = "lang.set"
+		err = b.langs.Set(userID, lang)
+	}
+	if err != nil {
+		b.log.Error("Ошибка сохранения языка", "user_id", userID, "error", err)
+		b.reply(req.ChatID, errorText(req.Lang, err))
+		return
+	}
+
+	b.log.Info("Изменен язык ответов",
+		"user_id", userID,
+		"lang", lang,
+		"auto", choice == langAuto,
+		"chat_id", req.ChatID,
+	)
+	b.rememberLang(req.ChatID, lang)
+	b.reply(req.ChatID, lang.T(key, lang.Name()))
+}
@@ -0,0 +1,180 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"telegram-api-with-go/internal/config"
+	"telegram-api-with-go/internal/export"
+	"telegram-api-with-go/internal/i18n"
+	"telegram-api-with-go/internal/report"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// TestMessageKeys падает, если код модуля использует ключ, которого нет в каталоге
+// какого-либо языка: в вызовах T и N, в описаниях команд и названиях аргументов.
+// Сообщения с числом нужно выводить через N: T для них возвращает общую форму.
+func TestMessageKeys(t *testing.T) {
+	keys := make(map[string]string)
+	singular := make(map[string]string)
+
+	// Ключи ищутся во всех пакетах модуля: тексты формируют не только обработчики бота,
+	// но и отчеты, уведомления и выгрузки
+	var files []string
+	err := filepath.WalkDir(filepath.Join("..", ".."), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && (d.Name() == "testdata" || strings.HasPrefix(d.Name(), ".")) && path != filepath.Join("..", "..") {
+			return filepath.SkipDir
+		}
+		if !d.IsDir() && strings.HasSuffix(path, ".go") && !strings.HasSuffix(path, "_test.go") {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fset := token.NewFileSet()
+	for _, name := range files {
+		file, err := parser.ParseFile(fset, name, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ast.Inspect(file, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || len(call.Args) == 0 {
+				return true
+			}
+			sel, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok || (sel.Sel.Name != "T" && sel.Sel.Name != "N") {
+				return true
+			}
+			lit, ok := call.Args[0].(*ast.BasicLit)
+			if !ok || lit.Kind != token.STRING {
+				return true
+			}
+			key, err := strconv.Unquote(lit.Value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pos := fset.Position(lit.Pos()).String()
+			keys[key] = pos
+			if sel.Sel.Name == "T" {
+				singular[key] = pos
+			}
+			return true
+		})
+	}
+	if len(keys) == 0 {
+		t.Fatal("no message keys found")
+	}
+
+	tb := newTestBot(t)
+	for _, cmd := range tb.bot.router.Commands() {
+		keys[cmd.Description] = "команда /" + cmd.Name
+		for _, arg := range cmd.Args {
+			if len(arg.Choices) == 0 {
+				keys["arg."+arg.Name] = "аргумент " + arg.Name + " команды /" + cmd.Name
+			}
+		}
+	}
+	for _, key := range []string{"arg.error.missing", "arg.error.quote", "arg.error.too_many",
+		"arg.error.user", "arg.error.int", "arg.error.duration", "arg.error.choice"} {
+		keys[key] = "router.go"
+	}
+
+	for key, pos := range keys {
+		for _, lang := range i18n.Langs() {
+			if !lang.Has(key) {
+				t.Errorf("%s: key %q is missing in %s", pos, key, lang)
+			}
+		}
+	}
+	for key, pos := range singular {
+		for _, lang := range i18n.Langs() {
+			if lang.Plural(key) {
+				t.Errorf("%s: plural key %q is used with T instead of N", pos, key)
+			}
+		}
+	}
+}
+
+func TestLanguage(t *testing.T) {
+	tb := startTestBot(t)
+	english := &tgbotapi.User{ID: 7, UserName: "tester", LanguageCode: "en-US"}
+	reply := func(text string) string {
+		tb.api.updates <- commandUpdateFrom(text, testChatID, english)
+		return messageText(t, tb.api.waitSent(t, 1)[0])
+	}
+
+	// Язык берется из настроек Telegram
+	if got := reply("/help"); !strings.HasPrefix(got, "<b>Available commands:</b>\n/help - list of commands (also /start)\n") {
+		t.Fatalf("help = %q", got)
+	}
+	if got := reply("/unspy"); got != "Error: missing argument user\nUsage: /unspy <user>" {
+		t.Fatalf("argument error = %q", got)
+	}
+	if got := reply("/lang"); got != "Reply language: English (from Telegram settings).\n"+
+		"Choose a language: /lang ru|en\nUse the language from Telegram settings: /lang auto" {
+		t.Fatalf("lang = %q", got)
+	}
+
+	// Выбранный язык важнее настроек Telegram и сохраняется
+	if got := reply("/lang ru"); got != "Язык ответов: Русский." {
+		t.Fatalf("lang ru = %q", got)
+	}
+	if got := reply("/status"); !strings.HasPrefix(got, "Telegram клиент подключен.") {
+		t.Fatalf("status = %q", got)
+	}
+	saved := i18n.NewPreferences(config.LangFile)
+	if err := saved.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if lang, ok := saved.Get(7); !ok || lang != i18n.Russian {
+		t.Fatalf("saved language = %q, %v", lang, ok)
+	}
+
+	if got := reply("/lang auto"); got != "The reply language follows Telegram settings again: English." {
+		t.Fatalf("lang auto = %q", got)
+	}
+	if got := reply("/tracked"); got != "You are not tracking anyone. Add a user with /spy <@username|ID>." {
+		t.Fatalf("tracked = %q", got)
+	}
+
+	// Меню команд публикуется на каждом языке
+	requests := tb.api.requestsTo("setMyCommands")
+	if len(requests) != len(i18n.Langs()) || requests[1].Get("language_code") != "en" ||
+		!strings.Contains(requests[1].Get("commands"), `"description":"list of commands"`) {
+		t.Fatalf("setMyCommands = %v", requests)
+	}
+}
+
+func TestErrorText(t *testing.T) {
+	_, periodErr := report.ParsePeriod("week")
+	for _, tc := range []struct {
+		err  error
+		want string
+	}{
+		{periodErr, "Error: invalid period. Examples: 7d, 2w, 12h."},
+		{fmt.Errorf("удаление истории: %w", errNoStore), "Error: status history storage is not configured."},
+		{export.ErrICSTransitions, "Error: the ics format is only supported for sessions."},
+		{&unknownArgError{arg: "Mars"}, `Error: unknown argument or time zone "Mars".`},
+		// Прочие ошибки выводятся как есть
+		{errors.New("connection reset"), "Error: connection reset"},
+	} {
+		if got := errorText(i18n.English, tc.err); got != tc.want {
+			t.Errorf("errorText(%v) = %q, want %q", tc.err, got, tc.want)
+		}
+	}
+}
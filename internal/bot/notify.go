@@ -0,0 +1,104 @@
+package bot
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"telegram-api-with-go/internal/config"
+	"telegram-api-with-go/internal/i18n"
+	"telegram-api-with-go/internal/notify"
+)
+
+// sendNotification отправляет уведомление о смене статуса.
+// Текст уведомления формирует пакет notify на языке чата (Options.Lang).
+func (b *Bot) sendNotification(chatID int64, text string) error {
+	return b.reply(chatID, text)
+}
+
+// handleNotifyCommand обрабатывает команду /notify
+func (b *Bot) handleNotifyCommand(_ context.Context, req *Request) {
+	chatID := req.ChatID
+	settings := b.notifyPrefs.Get(chatID)
+	notifyUsage := req.Lang.T("notify.usage")
+
+	setting, value := req.Args.String("setting"), req.Args.String("value")
+	if setting == "" {
+		b.reply(chatID, notifySettingsText(req.Lang, settings)+"\n\n"+notifyUsage)
+		return
+	}
+	if value == "" {
+		b.reply(chatID, notifyUsage)
+		return
+	}
+
+	switch setting {
+	case "quiet":
+		if value == "off" {
+			settings.QuietFrom, settings.QuietTo = 0, 0
+			break
+		}
+		from, to, ok := parseQuietHours(value)
+		if !ok {
+			b.reply(chatID, req.Lang.T("notify.bad_quiet", value))
+			return
+		}
+		settings.QuietFrom, settings.QuietTo = from, to
+	case "digest":
+		switch value {
+		case "on":
+			settings.Digest = true
+		case "off":
+			settings.Digest = false
+		default:
+			b.reply(chatID, notifyUsage)
+			return
+		}
+	default:
+		b.reply(chatID, notifyUsage)
+		return
+	}
+
+	if err := b.notifyPrefs.Set(chatID, settings); err != nil {
+		b.log.Error("Ошибка сохранения настроек уведомлений", "chat_id", chatID, "error", err)
+		b.reply(chatID, errorText(req.Lang, err))
+		return
+	}
+	b.log.Info("Изменены настройки уведомлений",
+		"chat_id", chatID,
+		"quiet_from", settings.QuietFrom,
+		"quiet_to", settings.QuietTo,
+		"digest", settings.Digest,
+	)
+	b.reply(chatID, notifySettingsText(req.Lang, settings))
+}
+
+// parseQuietHours разбирает часы тишины в формате <с>-<до>
+func parseQuietHours(s string) (from, to int, ok bool) {
+	fromStr, toStr, found := strings.Cut(s, "-")
+	if !found {
+		return 0, 0, false
+	}
+	from, errFrom := strconv.Atoi(fromStr)
+	to, errTo := strconv.Atoi(toStr)
+	if errFrom != nil || errTo != nil || from < 0 || from > 23 || to < 0 || to > 23 || from == to {
+		return 0, 0, false
+	}
+	return from, to, true
+}
+
+func notifySettingsText(lang i18n.Lang, s notify.Settings) string {
+	var sb strings.Builder
+	sb.WriteString(lang.T("notify.header") + "\n")
+	if s.HasQuietHours() {
+		sb.WriteString(lang.T("notify.quiet", s.QuietFrom, s.QuietTo, config.Location) + "\n")
+	} else {
+		sb.WriteString(lang.T("notify.quiet.off") + "\n")
+	}
+	if s.Digest {
+		sb.WriteString(lang.T("notify.digest", config.NotifyDigestHour))
+	} else {
+		sb.WriteString(lang.T("notify.digest.off"))
+	}
+	return sb.String()
+}
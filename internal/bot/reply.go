@@ -0,0 +1,239 @@
+package bot
+
+import (
+	"fmt"
+	"html"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+
+	"telegram-api-with-go/internal/config"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// maxMessageLength - ограничение Telegram на длину сообщения в символах UTF-16
+const maxMessageLength = 4096
+
+// replyFileName - имя файла, которым отправляется слишком длинный ответ
+const replyFileName = "reply.txt"
+
+// reply отправляет ответ в чат. Длинный текст делится на сообщения по границам строк,
+// entities (смещения в символах UTF-16, как в Bot API) переносятся в соответствующие части.
+// Если частей больше config.ReplyMaxMessages, ответ отправляется файлом .txt.
+// Ошибка отправки записывается в лог и возвращается.
+func (b *Bot) reply(chatID int64, text string, entities ...tgbotapi.MessageEntity) error {
+	parts := splitMessage(text, entities, maxMessageLength)
+	if config.ReplyMaxMessages > 0 && len(parts) > config.ReplyMaxMessages {
+		return b.replyDocument(chatID, text, len(parts))
+	}
+
+	for _, part := range parts {
+		msg := tgbotapi.NewMessage(chatID, part.Text)
+		if len(part.Entities) > 0 {
+			msg.Text = renderHTML(part.Text, part.Entities)
+			msg.ParseMode = tgbotapi.ModeHTML
+		}
+		if _, err := b.api.Send(msg); err != nil {
+			b.log.Error("Ошибка отправки сообщения",
+				"chat_id", chatID,
+				"error", err,
+			)
+			return err
+		}
+	}
+	return nil
+}
+
+// replyDocument отправляет ответ файлом вместо parts сообщений
+func (b *Bot) replyDocument(chatID int64, text string, parts int) error {
+	doc := tgbotapi.NewDocumentUpload(chatID, tgbotapi.FileBytes{Name: replyFileName, Bytes: []byte(text)})
+	doc.Caption = b.chatLang(chatID).T("reply.document")
+	b.log.Info("Ответ отправляется файлом", "chat_id", chatID, "messages", parts)
+	if _, err := b.api.Send(doc); err != nil {
+		b.log.Error("Ошибка отправки ответа файлом",
+			"chat_id", chatID,
+			"error", err,
+		)
+		return err
+	}
+	return nil
+}
+
+// messagePart - часть длинного ответа
+type messagePart struct {
+	Text     string
+	Entities []tgbotapi.MessageEntity
+}
+
+// splitMessage делит текст на части не длиннее limit символов UTF-16.
+// Части заканчиваются на границе строки, по возможности вне entities; разделяющий
+// перевод строки в части не входит. Строка длиннее limit делится посимвольно.
+// Entity на границе частей обрезается и продолжается в следующей части.
+func splitMessage(text string, entities []tgbotapi.MessageEntity, limit int) []messagePart {
+	units := utf16.Encode([]rune(text))
+	if len(units) <= limit {
+		return []messagePart{{Text: text, Entities: entities}}
+	}
+
+	var parts []messagePart
+	for start := 0; start < len(units); {
+		end, next := len(units), len(units)
+		if end-start > limit {
+			end, next = splitPoint(units, entities, start, limit)
+		}
+		if part := cutPart(units, entities, start, end); part.Text != "" {
+			parts = append(parts, part)
+		}
+		start = next
+	}
+	return parts
+}
+
+// splitPoint выбирает конец части, начинающейся с start, и начало следующей
+func splitPoint(units []uint16, entities []tgbotapi.MessageEntity, start, limit int) (end, next int) {
+	fallback := -1
+	for i := start + limit; i > start; i-- {
+		if units[i] != '\n' {
+			continue
+		}
+		if !insideEntity(entities, i) {
+			return i, i + 1
+		}
+		if fallback < 0 {
+			fallback = i
+		}
+	}
+	if fallback >= 0 {
+		return fallback, fallback + 1
+	}
+
+	// Строка длиннее limit: режем по символам, не разделяя суррогатную пару
+	end = start + limit
+	if utf16.IsSurrogate(rune(units[end-1])) && units[end-1] < 0xDC00 {
+		end--
+	}
+	return end, end
+}
+
+// insideEntity сообщает, разрывает ли граница pos какую-либо entity
+func insideEntity(entities []tgbotapi.MessageEntity, pos int) bool {
+	for _, e := range entities {
+		if e.Offset < pos && pos < e.Offset+e.Length {
+			return true
+		}
+	}
+	return false
+}
+
+// cutPart возвращает часть текста [start, end) с пересекающими ее entities.
+// Пробелы по краям отбрасываются: Telegram удаляет их сам, и смещения entities бы сдвинулись.
+func cutPart(units []uint16, entities []tgbotapi.MessageEntity, start, end int) messagePart {
+	for start < end && unicode.IsSpace(rune(units[start])) {
+		start++
+	}
+	for end > start && unicode.IsSpace(rune(units[end-1])) {
+		end--
+	}
+	part := messagePart{Text: string(utf16.Decode(units[start:end]))}
+	for _, e := range entities {
+		from, to := max(e.Offset, start), min(e.Offset+e.Length, end)
+		if from >= to {
+			continue
+		}
+		e.Offset, e.Length = from-start, to-from
+		part.Entities = append(part.Entities, e)
+	}
+	return part
+}
+
+// utf16Len возвращает длину строки в символах UTF-16, в которых Bot API считает смещения entities
+func utf16Len(s string) int {
+	return len(utf16.Encode([]rune(s)))
+}
+
+// renderHTML записывает текст с entities в разметке HTML Bot API
+func renderHTML(text string, entities []tgbotapi.MessageEntity) string {
+	units := utf16.Encode([]rune(text))
+	sorted := slices.Clone(entities)
+	// Внешние entities открываются раньше вложенных
+	slices.SortStableFunc(sorted, func(a, b tgbotapi.MessageEntity) int {
+		if a.Offset != b.Offset {
+			return a.Offset - b.Offset
+		}
+		return b.Length - a.Length
+	})
+
+	var (
+		sb   strings.Builder
+		open []tgbotapi.MessageEntity
+		pos  int
+		next int
+	)
+	write := func(to int) {
+		sb.WriteString(html.EscapeString(string(utf16.Decode(units[pos:to]))))
+		pos = to
+	}
+	for pos < len(units) || len(open) > 0 {
+		// Закрываем entities, которые заканчиваются в текущей позиции
+		for len(open) > 0 && open[len(open)-1].Offset+open[len(open)-1].Length <= pos {
+			sb.WriteString(closeTag(open[len(open)-1]))
+			open = open[:len(open)-1]
+		}
+		for next < len(sorted) && sorted[next].Offset <= pos {
+			sb.WriteString(openTag(sorted[next]))
+			open = append(open, sorted[next])
+			next++
+		}
+		if pos >= len(units) {
+			if len(open) == 0 {
+				break
+			}
+			// Entity выходит за конец текста
+			pos = open[len(open)-1].Offset + open[len(open)-1].Length
+			continue
+		}
+
+		to := len(units)
+		if len(open) > 0 {
+			to = min(to, open[len(open)-1].Offset+open[len(open)-1].Length)
+		}
+		if next < len(sorted) {
+			to = min(to, sorted[next].Offset)
+		}
+		write(to)
+	}
+	return sb.String()
+}
+
+// entityTags - теги HTML для типов entities
+var entityTags = map[string]string{
+	"bold":          "b",
+	"italic":        "i",
+	"underline":     "u",
+	"strikethrough": "s",
+	"code":          "code",
+	"pre":           "pre",
+	"spoiler":       "tg-spoiler",
+}
+
+func openTag(e tgbotapi.MessageEntity) string {
+	if e.Type == "text_link" {
+		return fmt.Sprintf(`<a href="%s">`, html.EscapeString(e.URL))
+	}
+	if tag, ok := entityTags[e.Type]; ok {
+		return "<" + tag + ">"
+	}
+	return ""
+}
+
+func closeTag(e tgbotapi.MessageEntity) string {
+	if e.Type == "text_link" {
+		return "</a>"
+	}
+	if tag, ok := entityTags[e.Type]; ok {
+		return "</" + tag + ">"
+	}
+	return ""
+}
@@ -0,0 +1,103 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+
+	"telegram-api-with-go/internal/config"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+func TestSplitMessage(t *testing.T) {
+	bold := func(offset, length int) tgbotapi.MessageEntity {
+		return tgbotapi.MessageEntity{Type: "bold", Offset: offset, Length: length}
+	}
+
+	// Короткий текст не делится
+	if parts := splitMessage("hello\nworld", nil, 20); len(parts) != 1 || parts[0].Text != "hello\nworld" {
+		t.Fatalf("short: %+v", parts)
+	}
+
+	// Деление по последней подходящей границе строки
+	parts := splitMessage("aaa\nbbb\nccc\nddd", nil, 8)
+	if len(parts) != 2 || parts[0].Text != "aaa\nbbb" || parts[1].Text != "ccc\nddd" {
+		t.Fatalf("lines: %+v", parts)
+	}
+
+	// Граница внутри entity переносится на предыдущую строку, entity сдвигается
+	parts = splitMessage("aaa\nbbb\nccc", []tgbotapi.MessageEntity{bold(4, 7)}, 9)
+	if len(parts) != 2 || parts[0].Text != "aaa" || parts[1].Text != "bbb\nccc" {
+		t.Fatalf("entity boundary: %+v", parts)
+	}
+	if len(parts[0].Entities) != 0 || len(parts[1].Entities) != 1 || parts[1].Entities[0] != bold(0, 7) {
+		t.Fatalf("entity boundary entities: %+v", parts)
+	}
+
+	// Entity длиннее части обрезается и продолжается в следующей
+	parts = splitMessage("aaa\nbbb\nccc", []tgbotapi.MessageEntity{bold(0, 11)}, 8)
+	if len(parts) != 2 || parts[0].Entities[0] != bold(0, 7) || parts[1].Entities[0] != bold(0, 3) {
+		t.Fatalf("long entity: %+v", parts)
+	}
+
+	// Строка без переводов делится по символам, суррогатная пара не разрывается
+	parts = splitMessage("ab😀cd", nil, 3)
+	if len(parts) != 3 || parts[0].Text != "ab" || parts[1].Text != "😀c" || parts[2].Text != "d" {
+		t.Fatalf("long line: %+v", parts)
+	}
+
+	// Смещения считаются в UTF-16, пустые части пропускаются
+	parts = splitMessage("привет\n\n\nмир", []tgbotapi.MessageEntity{bold(9, 3)}, 7)
+	if len(parts) != 2 || parts[1].Text != "мир" || parts[1].Entities[0] != bold(0, 3) {
+		t.Fatalf("utf16: %+v", parts)
+	}
+}
+
+func TestRenderHTML(t *testing.T) {
+	for _, tc := range []struct {
+		text     string
+		entities []tgbotapi.MessageEntity
+		want     string
+	}{
+		{"a <b> & c", nil, "a &lt;b&gt; &amp; c"},
+		{"Заголовок\nтекст", []tgbotapi.MessageEntity{{Type: "bold", Offset: 0, Length: 9}}, "<b>Заголовок</b>\nтекст"},
+		{
+			"bold italic",
+			[]tgbotapi.MessageEntity{{Type: "italic", Offset: 5, Length: 6}, {Type: "bold", Offset: 0, Length: 11}},
+			"<b>bold <i>italic</i></b>",
+		},
+		{"see docs", []tgbotapi.MessageEntity{{Type: "text_link", Offset: 4, Length: 4, URL: "https://e.x/?a=1&b=2"}}, `see <a href="https://e.x/?a=1&amp;b=2">docs</a>`},
+		{"😀 x", []tgbotapi.MessageEntity{{Type: "code", Offset: 3, Length: 1}}, "😀 <code>x</code>"},
+	} {
+		if got := renderHTML(tc.text, tc.entities); got != tc.want {
+			t.Errorf("renderHTML(%q) = %q, want %q", tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestReplyFallsBackToDocument(t *testing.T) {
+	tb := startTestBot(t)
+	config.ReplyMaxMessages = 2
+
+	line := strings.Repeat("x", 99) + "\n"
+	b := tb.bot
+	if err := b.reply(testChatID, strings.Repeat(line, 60)); err != nil {
+		t.Fatal(err)
+	}
+	sent := tb.api.waitSent(t, 2)
+	if len(sent) != 2 || len([]rune(messageText(t, sent[0]))) > maxMessageLength || !strings.HasSuffix(messageText(t, sent[1]), "x") {
+		t.Fatalf("split reply = %d messages", len(sent))
+	}
+
+	if err := b.reply(testChatID, strings.Repeat(line, 120)); err != nil {
+		t.Fatal(err)
+	}
+	doc, ok := tb.api.waitSent(t, 1)[0].(tgbotapi.DocumentConfig)
+	if !ok {
+		t.Fatal("long reply was not sent as a document")
+	}
+	file := doc.File.(tgbotapi.FileBytes)
+	if file.Name != replyFileName || len(file.Bytes) != 120*len(line) {
+		t.Fatalf("document %s, %d bytes", file.Name, len(file.Bytes))
+	}
+}
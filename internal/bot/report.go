@@ -0,0 +1,77 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"telegram-api-with-go/internal/config"
+	"telegram-api-with-go/internal/report"
+	"telegram-api-with-go/internal/telegram"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// handleReportCommand обрабатывает команду /report <пользователь> [период]
+func (b *Bot) handleReportCommand(_ context.Context, req *Request) {
+	chatID := req.ChatID
+	ref := req.Args.String("user")
+	if b.analyzer == nil {
+		b.reply(chatID, req.Lang.T("error.no_store"))
+		return
+	}
+
+	target, err := b.trackers.Find(chatID, ref)
+	if err != nil {
+		if errors.Is(err, telegram.ErrNotTracked) {
+			b.reply(chatID, req.Lang.T("user.not_tracked", ref))
+			return
+		}
+		b.reply(chatID, errorText(req.Lang, err))
+		return
+	}
+
+	period, err := report.ParsePeriod(req.Args.String("period"))
+	if err != nil {
+		b.reply(chatID, errorText(req.Lang, err))
+		return
+	}
+
+	loc := config.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	to := time.Now().In(loc)
+	sum, err := b.analyzer.Summary(target.UserID, to.Add(-period), to, loc)
+	if err != nil {
+		b.log.Error("Ошибка построения отчета", "user_id", target.UserID, "error", err)
+		b.reply(chatID, errorText(req.Lang, err))
+		return
+	}
+
+	b.log.Info("Построен отчет",
+		"user_id", target.UserID,
+		"period", period,
+		"sessions", len(sum.Sessions),
+		"chat_id", chatID,
+	)
+	b.reply(chatID, report.Text(req.Lang, target.Label(), sum))
+
+	chart, err := report.RenderPNG(sum)
+	if err != nil {
+		b.log.Error("Ошибка построения графика", "user_id", target.UserID, "error", err)
+		b.reply(chatID, req.Lang.T("report.chart_error", err))
+		return
+	}
+	photo := tgbotapi.NewPhotoUpload(chatID, tgbotapi.FileBytes{
+		Name:  fmt.Sprintf("report_%d.png", target.UserID),
+		Bytes: chart,
+	})
+	if _, err := b.api.Send(photo); err != nil {
+		b.log.Error("Ошибка отправки графика",
+			"chat_id", chatID,
+			"error", err,
+		)
+	}
+}
@@ -0,0 +1,131 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"telegram-api-with-go/internal/access"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// requiredRoles - минимальная роль для каждого права команды
+var requiredRoles = map[Permission]access.Role{
+	PermissionView:  access.RoleViewer,
+	PermissionTrack: access.RoleAdmin,
+	PermissionOwner: access.RoleOwner,
+}
+
+// permits сообщает, достаточно ли роли для права permission
+func permits(role access.Role, permission Permission) bool {
+	if permission == PermissionAny {
+		return true
+	}
+	return role.Includes(requiredRoles[permission])
+}
+
+// roleOf возвращает роль отправителя сообщения
+func (b *Bot) roleOf(from *tgbotapi.User) access.Role {
+	if from == nil {
+		return ""
+	}
+	return b.roles.Role(int64(from.ID))
+}
+
+// authorize проверяет право отправителя на команду. Отказ записывается в лог,
+// а пользователь получает сообщение о недостаточных правах.
+func (b *Bot) authorize(req *Request) bool {
+	role := b.roleOf(req.Message.From)
+	if permits(role, req.Command.Permission) {
+		return true
+	}
+
+	var userID int64
+	var username string
+	if from := req.Message.From; from != nil {
+		userID, username = int64(from.ID), from.UserName
+	}
+	b.log.Warn("Команда отклонена: недостаточно прав",
+		"command", req.Command.Name,
+		"user_id", userID,
+		"username", username,
+		"role", role,
+		"required", requiredRoles[req.Command.Permission],
+		"chat_id", req.ChatID,
+	)
+	b.reply(req.ChatID, req.Lang.T("command.forbidden", req.Command.Name))
+	return false
+}
+
+// resolveUserID возвращает ID пользователя по числовому ID или @username
+func (b *Bot) resolveUserID(ctx context.Context, ref string) (int64, error) {
+	if id, err := strconv.ParseInt(ref, 10, 64); err == nil {
+		return id, nil
+	}
+	user, err := b.users.ResolveUser(ctx, ref)
+	if err != nil {
+		return 0, err
+	}
+	return user.ID, nil
+}
+
+// handleGrantCommand обрабатывает команду /grant <пользователь> <роль>
+func (b *Bot) handleGrantCommand(ctx context.Context, req *Request) {
+	ref := req.Args.String("user")
+	userID, err := b.resolveUserID(ctx, ref)
+	if err != nil {
+		b.log.Error("Ошибка поиска пользователя", "ref", ref, "error", err)
+		b.reply(req.ChatID, errorText(req.Lang, err))
+		return
+	}
+
+	role := access.Role(req.Args.String("role"))
+	err = b.roles.Grant(userID, role, int64(req.Message.From.ID))
+	if errors.Is(err, access.ErrOwnerRole) {
+		b.reply(req.ChatID, req.Lang.T("roles.owner", ref))
+		return
+	}
+	if err != nil {
+		b.log.Error("Ошибка выдачи роли", "user_id", userID, "error", err)
+		b.reply(req.ChatID, errorText(req.Lang, err))
+		return
+	}
+	b.reply(req.ChatID, req.Lang.T("grant.done", ref, role))
+}
+
+// handleRevokeCommand обрабатывает команду /revoke <пользователь>
+func (b *Bot) handleRevokeCommand(ctx context.Context, req *Request) {
+	ref := req.Args.String("user")
+	userID, err := b.resolveUserID(ctx, ref)
+	if err != nil {
+		b.log.Error("Ошибка поиска пользователя", "ref", ref, "error", err)
+		b.reply(req.ChatID, errorText(req.Lang, err))
+		return
+	}
+
+	removed, err := b.roles.Revoke(userID, int64(req.Message.From.ID))
+	switch {
+	case errors.Is(err, access.ErrOwnerRole):
+		b.reply(req.ChatID, req.Lang.T("roles.owner", ref))
+	case err != nil:
+		b.log.Error("Ошибка отзыва роли", "user_id", userID, "error", err)
+		b.reply(req.ChatID, errorText(req.Lang, err))
+	case !removed:
+		b.reply(req.ChatID, req.Lang.T("revoke.none", ref))
+	default:
+		b.reply(req.ChatID, req.Lang.T("revoke.done", ref))
+	}
+}
+
+// handleRolesCommand обрабатывает команду /roles
+func (b *Bot) handleRolesCommand(_ context.Context, req *Request) {
+	var sb strings.Builder
+	sb.WriteString(req.Lang.T("roles.header") + "\n")
+	for _, a := range b.roles.Assignments() {
+		fmt.Fprintf(&sb, "%d - %s\n", a.UserID, a.Role)
+	}
+	b.reply(req.ChatID, sb.String())
+}
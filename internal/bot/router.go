@@ -0,0 +1,506 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"telegram-api-with-go/internal/i18n"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// Permission - право, необходимое для выполнения команды
+type Permission int
+
+const (
+	// PermissionAny - команда доступна любому пользователю
+	PermissionAny Permission = iota
+	// PermissionView - просмотр отслеживаемых пользователей, отчетов и выгрузок
+	PermissionView
+	// PermissionTrack - управление слежением и уведомлениями
+	PermissionTrack
+	// PermissionOwner - доступ к диалогам аккаунта и управление ролями
+	PermissionOwner
+)
+
+// ArgType - тип аргумента команды
+type ArgType int
+
+const (
+	// ArgString - произвольная строка
+	ArgString ArgType = iota
+	// ArgUser - пользователь: @username или числовой ID
+	ArgUser
+	// ArgInt - целое число
+	ArgInt
+	// ArgDuration - длительность в формате Go, например 30s или 2m
+	ArgDuration
+)
+
+// Arg описывает аргумент команды
+type Arg struct {
+	// Name - имя аргумента в Args; в справке выводится перевод ключа arg.<Name>
+	Name string
+	Type ArgType
+	// Optional - аргумент можно не указывать; необязательные аргументы идут последними
+	Optional bool
+	// Rest - аргумент забирает все оставшиеся слова; может быть только последним
+	Rest bool
+	// Choices ограничивает допустимые значения строкового аргумента
+	Choices []string
+}
+
+// Command описывает команду бота
+type Command struct {
+	Name    string
+	Aliases []string
+	Args    []Arg
+	// Description - ключ описания в каталоге i18n; строка без перевода выводится как есть
+	Description string
+	Permission  Permission
+	// RequiresClient - команде нужен подключенный MTProto клиент
+	RequiresClient bool
+	Handler        func(ctx context.Context, req *Request)
+}
+
+// Usage возвращает строку использования команды на языке lang, например /spy <пользователь> [интервал]
+func (c *Command) Usage(lang i18n.Lang) string {
+	var sb strings.Builder
+	sb.WriteString("/" + c.Name)
+	for _, arg := range c.Args {
+		name := argLabel(lang, arg)
+		if len(arg.Choices) > 0 {
+			name = strings.Join(arg.Choices, "|")
+		}
+		if arg.Rest {
+			name += "..."
+		}
+		if arg.Optional {
+			fmt.Fprintf(&sb, " [%s]", name)
+		} else {
+			fmt.Fprintf(&sb, " <%s>", name)
+		}
+	}
+	return sb.String()
+}
+
+// argLabel возвращает название аргумента на языке lang
+func argLabel(lang i18n.Lang, arg Arg) string {
+	if key := "arg." + arg.Name; lang.Has(key) {
+		return lang.T(key)
+	}
+	return arg.Name
+}
+
+// Request - разобранный вызов команды
+type Request struct {
+	Update  tgbotapi.Update
+	Message *tgbotapi.Message
+	ChatID  int64
+	Command *Command
+	Args    Args
+	// Lang - язык ответов отправителю
+	Lang i18n.Lang
+}
+
+// Callback описывает обработчик нажатий кнопок с действием Action
+type Callback struct {
+	Action     string
+	Permission Permission
+	// RequiresClient - обработчику нужен подключенный MTProto клиент
+	RequiresClient bool
+	Handler        func(ctx context.Context, req *CallbackRequest)
+}
+
+// CallbackRequest - проверенное нажатие кнопки
+type CallbackRequest struct {
+	Query     *tgbotapi.CallbackQuery
+	ChatID    int64
+	MessageID int
+	// Args - аргументы из подписанных данных кнопки
+	Args []string
+	// Lang - язык ответов нажавшему кнопку
+	Lang i18n.Lang
+}
+
+// Args - значения аргументов команды по именам
+type Args map[string]any
+
+// Has сообщает, указан ли аргумент
+func (a Args) Has(name string) bool {
+	_, ok := a[name]
+	return ok
+}
+
+// String возвращает значение строкового аргумента или аргумента-пользователя
+func (a Args) String(name string) string {
+	s, _ := a[name].(string)
+	return s
+}
+
+// Int возвращает значение целочисленного аргумента
+func (a Args) Int(name string) int64 {
+	n, _ := a[name].(int64)
+	return n
+}
+
+// Duration возвращает значение аргумента-длительности
+func (a Args) Duration(name string) time.Duration {
+	d, _ := a[name].(time.Duration)
+	return d
+}
+
+// Rest возвращает слова аргумента с Rest
+func (a Args) Rest(name string) []string {
+	s, _ := a[name].([]string)
+	return s
+}
+
+// ErrNotCommand возвращается для сообщений, которые не являются командой
+var ErrNotCommand = errors.New("сообщение не является командой")
+
+// errOtherBot возвращается для команд вида /cmd@other_bot, адресованных другому боту
+var errOtherBot = errors.New("команда адресована другому боту")
+
+// ArgError - ошибка разбора аргументов команды
+type ArgError struct {
+	Command *Command
+	// Key - ключ текста ошибки в каталоге i18n
+	Key string
+	// Arg - некорректный или пропущенный аргумент; nil для ошибок всей строки аргументов
+	Arg *Arg
+	// Value - некорректное значение аргумента
+	Value string
+}
+
+func (e *ArgError) Error() string {
+	return e.Text(i18n.Default)
+}
+
+// Text возвращает текст ошибки на языке lang
+func (e *ArgError) Text(lang i18n.Lang) string {
+	switch {
+	case e.Arg == nil:
+		return lang.T(e.Key)
+	case e.Key == "arg.error.missing":
+		return lang.T(e.Key, argLabel(lang, *e.Arg))
+	case len(e.Arg.Choices) > 0:
+		return lang.T(e.Key, argLabel(lang, *e.Arg), e.Value, strings.Join(e.Arg.Choices, ", "))
+	default:
+		return lang.T(e.Key, argLabel(lang, *e.Arg), e.Value)
+	}
+}
+
+// UnknownCommandError возвращается для незарегистрированной команды
+type UnknownCommandError struct {
+	Name string
+}
+
+func (e *UnknownCommandError) Error() string {
+	return fmt.Sprintf("неизвестная команда /%s", e.Name)
+}
+
+// Router сопоставляет сообщения с зарегистрированными командами
+type Router struct {
+	username  string
+	commands  []*Command
+	byName    map[string]*Command
+	callbacks map[string]*Callback
+}
+
+// NewRouter создает маршрутизатор команд бота username
+func NewRouter(username string) *Router {
+	return &Router{
+		username:  username,
+		byName:    make(map[string]*Command),
+		callbacks: make(map[string]*Callback),
+	}
+}
+
+var commandNamePattern = regexp.MustCompile(`^[a-z0-9_]{1,32}$`)
+
+// Register добавляет команду. Повторяющиеся имена и некорректные описания
+// аргументов - ошибка программы, поэтому Register паникует.
+func (r *Router) Register(cmd Command) {
+	for _, name := range append([]string{cmd.Name}, cmd.Aliases...) {
+		if !commandNamePattern.MatchString(name) {
+			panic(fmt.Sprintf("bot: некорректное имя команды %q", name))
+		}
+		if _, ok := r.byName[name]; ok {
+			panic(fmt.Sprintf("bot: команда /%s уже зарегистрирована", name))
+		}
+	}
+	for i, arg := range cmd.Args {
+		last := i == len(cmd.Args)-1
+		if arg.Rest && !last {
+			panic(fmt.Sprintf("bot: аргумент %s команды /%s с Rest должен быть последним", arg.Name, cmd.Name))
+		}
+		if !arg.Optional && i > 0 && cmd.Args[i-1].Optional {
+			panic(fmt.Sprintf("bot: обязательный аргумент %s команды /%s после необязательного", arg.Name, cmd.Name))
+		}
+	}
+
+	c := &cmd
+	r.commands = append(r.commands, c)
+	for _, name := range append([]string{cmd.Name}, cmd.Aliases...) {
+		r.byName[name] = c
+	}
+}
+
+// RegisterCallback добавляет обработчик нажатий кнопок. Повторяющееся действие
+// или действие с ':' - ошибка программы, поэтому RegisterCallback паникует.
+func (r *Router) RegisterCallback(cb Callback) {
+	if cb.Action == "" || strings.Contains(cb.Action, ":") {
+		panic(fmt.Sprintf("bot: некорректное действие кнопки %q", cb.Action))
+	}
+	if _, ok := r.callbacks[cb.Action]; ok {
+		panic(fmt.Sprintf("bot: действие кнопки %s уже зарегистрировано", cb.Action))
+	}
+	r.callbacks[cb.Action] = &cb
+}
+
+// Callback возвращает обработчик нажатий кнопок с действием action
+func (r *Router) Callback(action string) (*Callback, bool) {
+	cb, ok := r.callbacks[action]
+	return cb, ok
+}
+
+// Command возвращает команду по имени или псевдониму
+func (r *Router) Command(name string) (*Command, bool) {
+	cmd, ok := r.byName[name]
+	return cmd, ok
+}
+
+// Commands возвращает команды в порядке регистрации
+func (r *Router) Commands() []*Command {
+	return r.commands
+}
+
+// Route разбирает сообщение и возвращает вызов команды.
+// Для сообщений без команды возвращается ErrNotCommand, для незарегистрированных команд -
+// *UnknownCommandError, для некорректных аргументов - *ArgError.
+// Команды, адресованные другому боту (/cmd@other_bot), возвращают nil без ошибки.
+func (r *Router) Route(update tgbotapi.Update) (*Request, error) {
+	msg := update.Message
+	name, rest, err := r.splitCommand(msg.Text)
+	if errors.Is(err, errOtherBot) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cmd, ok := r.byName[name]
+	if !ok {
+		return nil, &UnknownCommandError{Name: name}
+	}
+
+	tokens, err := tokenize(rest)
+	if err != nil {
+		return nil, &ArgError{Command: cmd, Key: "arg.error.quote"}
+	}
+	args, argErr := bindArgs(cmd, tokens)
+	if argErr != nil {
+		argErr.Command = cmd
+		return nil, argErr
+	}
+	return &Request{Update: update, Message: msg, ChatID: msg.Chat.ID, Command: cmd, Args: args}, nil
+}
+
+// splitCommand выделяет имя команды без суффикса @botname и строку аргументов
+func (r *Router) splitCommand(text string) (name, rest string, err error) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "/") {
+		return "", "", ErrNotCommand
+	}
+	head := text
+	if i := strings.IndexFunc(text, unicode.IsSpace); i >= 0 {
+		head, rest = text[:i], text[i:]
+	}
+
+	name = strings.TrimPrefix(head, "/")
+	if base, bot, ok := strings.Cut(name, "@"); ok {
+		if !strings.EqualFold(bot, r.username) {
+			return "", "", errOtherBot
+		}
+		name = base
+	}
+	if name == "" {
+		return "", "", ErrNotCommand
+	}
+	return strings.ToLower(name), rest, nil
+}
+
+// quotePairs - открывающие кавычки и соответствующие им закрывающие
+var quotePairs = map[rune]rune{'"': '"', '\'': '\'', '“': '”', '«': '»'}
+
+// tokenize делит строку на слова по пробелам. Слова в кавычках ("...", '...', “...”, «...»)
+// сохраняют пробелы; кавычка внутри слова считается обычным символом; обратная косая черта экранирует следующий символ вне одинарных кавычек.
+func tokenize(s string) ([]string, error) {
+	var (
+		tokens  []string
+		current strings.Builder
+		inToken bool
+		closing rune
+		escaped bool
+	)
+	for _, c := range s {
+		switch {
+		case escaped:
+			current.WriteRune(c)
+			escaped = false
+		case c == '\\' && closing != '\'':
+			escaped = true
+			inToken = true
+		case closing != 0:
+			if c == closing {
+				closing = 0
+			} else {
+				current.WriteRune(c)
+			}
+		case unicode.IsSpace(c):
+			if inToken {
+				tokens = append(tokens, current.String())
+				current.Reset()
+				inToken = false
+			}
+		default:
+			if end, ok := quotePairs[c]; ok && !inToken {
+				closing = end
+				inToken = true
+				continue
+			}
+			current.WriteRune(c)
+			inToken = true
+		}
+	}
+	if closing != 0 {
+		return nil, errUnclosedQuote
+	}
+	if escaped {
+		current.WriteRune('\\')
+	}
+	if inToken {
+		tokens = append(tokens, current.String())
+	}
+	return tokens, nil
+}
+
+// errUnclosedQuote возвращается tokenize для строки с незакрытой кавычкой
+var errUnclosedQuote = errors.New("не закрыта кавычка")
+
+var usernamePattern = regexp.MustCompile(`^@[A-Za-z0-9_]{3,32}$`)
+
+// bindArgs сопоставляет слова аргументам команды и проверяет их типы
+func bindArgs(cmd *Command, tokens []string) (Args, *ArgError) {
+	args := make(Args)
+	for i, arg := range cmd.Args {
+		if i >= len(tokens) {
+			if !arg.Optional {
+				return nil, &ArgError{Key: "arg.error.missing", Arg: &cmd.Args[i]}
+			}
+			break
+		}
+		if arg.Rest {
+			args[arg.Name] = tokens[i:]
+			return args, nil
+		}
+		value, err := parseArg(&cmd.Args[i], tokens[i])
+		if err != nil {
+			return nil, err
+		}
+		args[arg.Name] = value
+	}
+	if len(tokens) > len(cmd.Args) {
+		return nil, &ArgError{Key: "arg.error.too_many"}
+	}
+	return args, nil
+}
+
+func parseArg(arg *Arg, s string) (any, *ArgError) {
+	switch arg.Type {
+	case ArgUser:
+		if _, err := strconv.ParseInt(s, 10, 64); err == nil || usernamePattern.MatchString(s) {
+			return s, nil
+		}
+		return nil, &ArgError{Key: "arg.error.user", Arg: arg, Value: s}
+	case ArgInt:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, &ArgError{Key: "arg.error.int", Arg: arg, Value: s}
+		}
+		return n, nil
+	case ArgDuration:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, &ArgError{Key: "arg.error.duration", Arg: arg, Value: s}
+		}
+		return d, nil
+	default:
+		if len(arg.Choices) > 0 {
+			for _, choice := range arg.Choices {
+				if strings.EqualFold(s, choice) {
+					return choice, nil
+				}
+			}
+			return nil, &ArgError{Key: "arg.error.choice", Arg: arg, Value: s}
+		}
+		return s, nil
+	}
+}
+
+// HelpText формирует справку на языке lang по командам, для которых allowed возвращает true.
+// nil allowed включает в справку все команды.
+func (r *Router) HelpText(lang i18n.Lang, allowed func(*Command) bool) string {
+	var sb strings.Builder
+	sb.WriteString(lang.T("help.header") + "\n")
+	for _, cmd := range r.commands {
+		if allowed != nil && !allowed(cmd) {
+			continue
+		}
+		fmt.Fprintf(&sb, "%s - %s", cmd.Usage(lang), lang.T(cmd.Description))
+		if len(cmd.Aliases) > 0 {
+			sb.WriteString(lang.T("help.aliases", strings.Join(cmd.Aliases, ", /")))
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// botCommand - команда в формате метода setMyCommands
+type botCommand struct {
+	Command     string `json:"command"`
+	Description string `json:"description"`
+}
+
+// setMyCommandsParams формирует параметры setMyCommands для языка lang: команды без
+// псевдонимов с описаниями, обрезанными до ограничения Telegram в 256 символов.
+// Меню на языке по умолчанию публикуется без language_code и видно всем пользователям,
+// для которых нет меню на их языке.
+func (r *Router) setMyCommandsParams(lang i18n.Lang) (url.Values, error) {
+	commands := make([]botCommand, 0, len(r.commands))
+	for _, cmd := range r.commands {
+		description := []rune(lang.T(cmd.Description))
+		if len(description) > 256 {
+			description = description[:256]
+		}
+		commands = append(commands, botCommand{Command: cmd.Name, Description: string(description)})
+	}
+	data, err := json.Marshal(commands)
+	if err != nil {
+		return nil, err
+	}
+	params := url.Values{"commands": {string(data)}}
+	if lang != i18n.Default {
+		params.Set("language_code", string(lang))
+	}
+	return params, nil
+}
@@ -0,0 +1,215 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"telegram-api-with-go/internal/i18n"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/gotd/td/tg"
+)
+
+func textUpdate(text string) tgbotapi.Update {
+	return tgbotapi.Update{Message: &tgbotapi.Message{
+		Text: text,
+		Chat: &tgbotapi.Chat{ID: testChatID},
+		From: &tgbotapi.User{ID: 7},
+	}}
+}
+
+func testRouter() *Router {
+	r := NewRouter("test_bot")
+	noop := func(context.Context, *Request) {}
+	r.Register(Command{
+		Name:    "spy",
+		Aliases: []string{"track"},
+		Args: []Arg{
+			{Name: "пользователь", Type: ArgUser},
+			{Name: "интервал", Type: ArgDuration, Optional: true},
+		},
+		Description: "следить",
+		Handler:     noop,
+	})
+	r.Register(Command{
+		Name:        "note",
+		Args:        []Arg{{Name: "id", Type: ArgInt}, {Name: "текст", Optional: true, Rest: true}},
+		Description: "заметка",
+		Handler:     noop,
+	})
+	r.Register(Command{
+		Name:        "mode",
+		Args:        []Arg{{Name: "режим", Choices: []string{"on", "off"}}},
+		Description: "режим",
+		Handler:     noop,
+	})
+	return r
+}
+
+func TestTokenize(t *testing.T) {
+	for in, want := range map[string][]string{
+		"":                 nil,
+		"  a   b ":         {"a", "b"},
+		`"Рабочий чат" 7d`: {"Рабочий чат", "7d"},
+		`'a "b"' c`:        {`a "b"`, "c"},
+		`«Семья» “Друзья и коллеги”`: {"Семья", "Друзья и коллеги"},
+		`a\ b c\"d`: {"a b", `c"d`},
+		`""`:        {""},
+		"O'Brien":   {"O'Brien"},
+	} {
+		got, err := tokenize(in)
+		if err != nil || !slices.Equal(got, want) {
+			t.Errorf("tokenize(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := tokenize(`"unterminated`); err == nil {
+		t.Error("unterminated quote must fail")
+	}
+}
+
+func TestRoute(t *testing.T) {
+	r := testRouter()
+
+	req, err := r.Route(textUpdate("  /Spy@Test_Bot   @alice   2m "))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Command.Name != "spy" || req.Args.String("пользователь") != "@alice" || req.Args.Duration("интервал") != 2*time.Minute {
+		t.Fatalf("request = %+v", req)
+	}
+
+	req, err = r.Route(textUpdate("/track 1001"))
+	if err != nil || req.Command.Name != "spy" || req.Args.Has("интервал") {
+		t.Fatalf("alias: request = %+v, %v", req, err)
+	}
+
+	req, err = r.Route(textUpdate(`/note 5 купить "молоко и хлеб"`))
+	if err != nil || req.Args.Int("id") != 5 || !slices.Equal(req.Args.Rest("текст"), []string{"купить", "молоко и хлеб"}) {
+		t.Fatalf("rest: request = %+v, %v", req, err)
+	}
+
+	req, err = r.Route(textUpdate("/MODE OFF"))
+	if err != nil || req.Args.String("режим") != "off" {
+		t.Fatalf("choice: request = %+v, %v", req, err)
+	}
+
+	// Команда другому боту в группе игнорируется
+	if req, err := r.Route(textUpdate("/spy@other_bot @alice")); req != nil || err != nil {
+		t.Fatalf("other bot: request = %+v, %v", req, err)
+	}
+
+	if _, err := r.Route(textUpdate("hello")); !errors.Is(err, ErrNotCommand) {
+		t.Fatalf("plain text: err = %v", err)
+	}
+	var unknown *UnknownCommandError
+	if _, err := r.Route(textUpdate("/unknown")); !errors.As(err, &unknown) || unknown.Name != "unknown" {
+		t.Fatalf("unknown: err = %v", err)
+	}
+
+	for _, text := range []string{
+		"/spy",
+		"/spy alice",
+		"/spy @alice soon",
+		"/spy @alice 2m extra",
+		"/note five",
+		"/mode maybe",
+		`/spy "@alice`,
+	} {
+		var argErr *ArgError
+		if _, err := r.Route(textUpdate(text)); !errors.As(err, &argErr) || argErr.Command == nil {
+			t.Errorf("%s: err = %v, want ArgError", text, err)
+		}
+	}
+}
+
+func TestRouterRejectsInvalidCommands(t *testing.T) {
+	for name, cmd := range map[string]Command{
+		"duplicate alias":         {Name: "other", Aliases: []string{"track"}},
+		"invalid name":            {Name: "Spy-Now"},
+		"rest not last":           {Name: "x", Args: []Arg{{Name: "a", Rest: true}, {Name: "b"}}},
+		"required after optional": {Name: "y", Args: []Arg{{Name: "a", Optional: true}, {Name: "b"}}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Fatal("Register must panic")
+				}
+			}()
+			testRouter().Register(cmd)
+		})
+	}
+}
+
+func TestHelpAndPublishedCommands(t *testing.T) {
+	r := testRouter()
+	help := r.HelpText(i18n.Default, nil)
+	for _, want := range []string{
+		"/spy <пользователь> [интервал] - следить (также /track)\n",
+		"/note <id> [текст...] - заметка\n",
+		"/mode <on|off> - режим\n",
+	} {
+		if !strings.Contains(help, want) {
+			t.Errorf("help does not contain %q:\n%s", want, help)
+		}
+	}
+
+	params, err := r.setMyCommandsParams(i18n.Default)
+	if err != nil || params.Has("language_code") {
+		t.Fatalf("default menu = %v, %v", params, err)
+	}
+	var commands []botCommand
+	if err := json.Unmarshal([]byte(params.Get("commands")), &commands); err != nil {
+		t.Fatal(err)
+	}
+	if len(commands) != 3 || commands[0] != (botCommand{Command: "spy", Description: "следить"}) {
+		t.Fatalf("commands = %+v", commands)
+	}
+	if params, _ := r.setMyCommandsParams(i18n.English); params.Get("language_code") != "en" {
+		t.Fatalf("english menu = %v", params)
+	}
+}
+
+func TestBotRoutesCommands(t *testing.T) {
+	tb := startTestBot(t)
+	tb.client.SetUser(&tg.User{ID: 2002, Username: "alice"})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(tb.api.requestsTo("setMyCommands")) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("commands were not published")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := tb.api.requestsTo("setMyCommands")[0].Get("commands"); !strings.Contains(got, `"command":"spy"`) {
+		t.Fatalf("published commands = %s", got)
+	}
+
+	for _, tc := range []struct {
+		command string
+		want    string
+	}{
+		{"/spy@test_bot   @alice", "Теперь вы следите за пользователем @alice (2002)."},
+		{"/list", "Отслеживаемые пользователи:\n1. @alice (2002) - отслеживается\n"},
+		{"/unspy", "Ошибка: не указан аргумент пользователь\nИспользование: /unspy <пользователь>"},
+		{"/status@test_bot", "Telegram клиент подключен."},
+		{"/nonsense", "Неизвестная команда. Список команд: /help"},
+	} {
+		tb.send(tc.command)
+		if got := messageText(t, tb.api.waitSent(t, 1)[0]); !strings.HasPrefix(got, tc.want) {
+			t.Errorf("%s: reply = %q, want %q", tc.command, got, tc.want)
+		}
+	}
+
+	tb.send("/help")
+	help := messageText(t, tb.api.waitSent(t, 1)[0])
+	for _, cmd := range []string{"/spy", "/unspy", "/tracked", "/report", "/export", "/notify", "/allow", "/deny", "/chats", "/members", "/status"} {
+		if !strings.Contains(help, "\n"+cmd+" ") {
+			t.Errorf("help does not mention %s:\n%s", cmd, help)
+		}
+	}
+}
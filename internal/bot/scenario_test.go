@@ -0,0 +1,233 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"telegram-api-with-go/internal/bot/bottest"
+	"telegram-api-with-go/internal/config"
+	"telegram-api-with-go/internal/telegram"
+	"telegram-api-with-go/internal/telegram/telegramtest"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/gotd/td/tg"
+)
+
+const scenarioTimeout = 5 * time.Second
+
+// scenario - бот с настоящим клиентом Bot API, подключенный к фейковому серверу
+type scenario struct {
+	*testBot
+	server *bottest.Server
+	owner  tgbotapi.User
+}
+
+// startScenario запускает бота через NewWithDeps с BOT_API_ENDPOINT на фейковом сервере
+func startScenario(t *testing.T) *scenario {
+	t.Helper()
+	presenceStore := setupTestConfig(t)
+	config.BotToken = "123:test"
+	server := bottest.NewServer(config.BotToken)
+	t.Cleanup(server.Close)
+	config.BotAPIEndpoint = server.URL
+	t.Cleanup(func() { config.BotAPIEndpoint = "" })
+
+	s := &scenario{
+		testBot: &testBot{
+			client: telegramtest.NewClient(),
+			status: telegramtest.NewStatus(telegram.StateReady),
+			store:  presenceStore,
+		},
+		server: server,
+		owner:  tgbotapi.User{ID: 7, UserName: "tester"},
+	}
+	bot, err := NewWithDeps(s.deps())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.bot = bot
+	return s
+}
+
+// run запускает бота до завершения теста
+func (s *scenario) run(t *testing.T) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.bot.Start(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	if _, err := s.server.WaitCalls("setMyCommands", 1, scenarioTimeout); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// wait ждет n-й вызов метода и возвращает его
+func (s *scenario) wait(t *testing.T, method string, n int) bottest.Call {
+	t.Helper()
+	calls, err := s.server.WaitCalls(method, n, scenarioTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return calls[n-1]
+}
+
+func TestScenarioStartup(t *testing.T) {
+	s := startScenario(t)
+	s.run(t)
+
+	if s.bot.username != "test_bot" {
+		t.Fatalf("username = %q", s.bot.username)
+	}
+	// В режиме long polling бот удаляет webhook перед getUpdates
+	s.wait(t, "deleteWebhook", 1)
+	if got := s.wait(t, "setMyCommands", 1).Params.Get("commands"); !strings.Contains(got, `"command":"help"`) {
+		t.Fatalf("commands = %s", got)
+	}
+
+	s.server.SendText(testChatID, s.owner, "/help")
+	help := s.wait(t, "sendMessage", 1)
+	if help.ChatID() != testChatID || help.Params.Get("parse_mode") != "HTML" ||
+		!strings.HasPrefix(help.Text(), "<b>Доступные команды:</b>") {
+		t.Fatalf("help = %v", help.Params)
+	}
+}
+
+func TestScenarioSpy(t *testing.T) {
+	s := startScenario(t)
+	s.client.SetUserStatus(1001, &tg.UserStatusOffline{WasOnline: 100})
+	s.run(t)
+
+	s.server.SendText(testChatID, s.owner, "/spy")
+	if got := s.wait(t, "sendMessage", 1).Text(); got != "Теперь вы следите за пользователем 1001." {
+		t.Fatalf("spy reply = %q", got)
+	}
+
+	s.server.SendText(testChatID, s.owner, "/tracked")
+	if got := s.wait(t, "sendMessage", 2).Text(); !strings.HasPrefix(got, "Отслеживаемые пользователи:\n1. 1001 - ") {
+		t.Fatalf("tracked reply = %q", got)
+	}
+
+	// Пользователь без роли не может управлять слежением
+	s.server.SendText(99, tgbotapi.User{ID: 99, UserName: "stranger"}, "/unspy 1001")
+	if got := s.wait(t, "sendMessage", 3).Text(); got != "Недостаточно прав для команды /unspy." {
+		t.Fatalf("stranger reply = %q", got)
+	}
+}
+
+func TestScenarioChatsPaging(t *testing.T) {
+	s := startScenario(t)
+	for i := 1; i <= 10; i++ {
+		s.client.Dialogs = append(s.client.Dialogs, telegram.Dialog{ID: int64(i), Title: fmt.Sprintf("Чат %d", i)})
+	}
+	s.run(t)
+
+	s.server.SendText(testChatID, s.owner, "/chats")
+	placeholder := s.wait(t, "sendMessage", 1)
+	page := s.wait(t, "editMessageText", 1)
+	if !strings.HasPrefix(page.Text(), "Список чатов (страница 1 из 2):") {
+		t.Fatalf("first page = %q", page.Text())
+	}
+	rows := page.Keyboard()
+	next := rows[len(rows)-1][0]
+	if next.Text != "Вперед »" || next.CallbackData == nil {
+		t.Fatalf("first page keyboard = %+v", rows)
+	}
+
+	// Сообщение-заглушка получило ID от сервера, его и редактирует бот
+	messageID := page.Params.Get("message_id")
+	s.server.Press(testChatID, atoi(t, messageID), s.owner, *next.CallbackData)
+	if got := s.wait(t, "answerCallbackQuery", 1).Params.Get("text"); got != "" {
+		t.Fatalf("answer = %q", got)
+	}
+	page = s.wait(t, "editMessageText", 2)
+	if page.Params.Get("message_id") != messageID || !strings.HasPrefix(page.Text(), "Список чатов (страница 2 из 2):") {
+		t.Fatalf("second page = %v", page.Params)
+	}
+	if placeholder.Text() != "Запрашиваю список чатов..." {
+		t.Fatalf("placeholder = %q", placeholder.Text())
+	}
+}
+
+func TestScenarioMembersDocument(t *testing.T) {
+	s := startScenario(t)
+	s.client.Members["@team"] = &telegram.ChatMembers{
+		ChatID:  10,
+		Title:   "Команда",
+		Members: []telegram.Member{{UserID: 1, Username: "owner", Role: telegram.RoleCreator}},
+	}
+	s.run(t)
+
+	s.server.SendText(testChatID, s.owner, "/members @team")
+	doc := s.wait(t, "sendDocument", 1)
+	if doc.File == nil || doc.File.Field != "document" || doc.File.Name != "members_10_recent.csv" {
+		t.Fatalf("document = %+v", doc.File)
+	}
+	if !strings.Contains(string(doc.File.Data), "1,owner,,,false,creator,") {
+		t.Fatalf("csv = %q", doc.File.Data)
+	}
+	if got := doc.Params.Get("caption"); got != "Команда: 1 участник (recent)" {
+		t.Fatalf("caption = %q", got)
+	}
+}
+
+func TestScenarioSendError(t *testing.T) {
+	s := startScenario(t)
+	s.run(t)
+	s.server.Fail("sendMessage", 403, "Forbidden: bot was blocked by the user")
+
+	// Ошибка отправки не останавливает обработку следующих обновлений
+	s.server.SendText(testChatID, s.owner, "/help")
+	s.wait(t, "sendMessage", 1)
+	s.server.SendText(testChatID, s.owner, "/help")
+	s.wait(t, "sendMessage", 2)
+}
+
+func TestWithEndpoint(t *testing.T) {
+	for _, endpoint := range []string{"localhost:8081", "ftp://host", "http://"} {
+		if _, err := withEndpoint(http.DefaultClient, endpoint); err == nil {
+			t.Errorf("withEndpoint(%q) accepted invalid endpoint", endpoint)
+		}
+	}
+	if client, err := withEndpoint(http.DefaultClient, ""); err != nil || client != http.DefaultClient {
+		t.Fatalf("empty endpoint changed the client: %v", err)
+	}
+
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Host+r.URL.Path)
+	}))
+	defer srv.Close()
+	client, err := withEndpoint(srv.Client(), srv.URL+"/telegram/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, target := range []string{"https://api.telegram.org/bot1:x/getMe", srv.URL + "/other"} {
+		resp, err := client.Get(target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	host := strings.TrimPrefix(srv.URL, "http://")
+	want := []string{host + "/telegram/bot1:x/getMe", host + "/other"}
+	if strings.Join(paths, " ") != strings.Join(want, " ") {
+		t.Fatalf("paths = %v, want %v", paths, want)
+	}
+}
+
+func atoi(t *testing.T, s string) int {
+	t.Helper()
+	var n int
+	if _, err := fmt.Sscan(s, &n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
@@ -0,0 +1,270 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"telegram-api-with-go/internal/config"
+	"telegram-api-with-go/internal/i18n"
+	"telegram-api-with-go/internal/telegram"
+)
+
+// handleSpyCommand обрабатывает команду /spy <пользователь> [интервал опроса].
+// Без пользователя отслеживается DEFAULT_SPY_USER_ID, а если он не задан,
+// бот спрашивает пользователя и настройки уведомлений в диалоге.
+func (b *Bot) handleSpyCommand(ctx context.Context, req *Request) {
+	chatID := req.ChatID
+
+	ref := req.Args.String("user")
+	if ref == "" && config.DefaultSpyUserID != 0 {
+		ref = strconv.FormatInt(config.DefaultSpyUserID, 10)
+	}
+	if ref == "" {
+		b.startConversation(req)
+		return
+	}
+
+	var settings telegram.TargetSettings
+	if req.Args.Has("interval") {
+		interval := req.Args.Duration("interval")
+		if interval < time.Second {
+			b.reply(chatID, req.Lang.T("spy.bad_interval", interval))
+			return
+		}
+		settings.PollInterval = interval
+	}
+	b.startTracking(ctx, req, ref, settings)
+}
+
+// spyConversation - диалог /spy без аргументов: кого отслеживать и как уведомлять
+func (b *Bot) spyConversation() Conversation {
+	return Conversation{
+		Command: "spy",
+		Steps: []Step{
+			{Name: "user", Prompt: "spy.ask.user", Validate: b.validateSpyUser},
+			{Name: "notify", Prompt: "spy.ask.notify", Validate: validateSpyNotify},
+		},
+		Done: b.finishSpyConversation,
+	}
+}
+
+// validateSpyUser проверяет, что пользователь существует и дал согласие на отслеживание
+func (b *Bot) validateSpyUser(ctx context.Context, _ *Request, answer string) (string, error) {
+	if _, err := strconv.ParseInt(answer, 10, 64); err != nil && !usernamePattern.MatchString(answer) {
+		return "", &AnswerError{Key: "spy.bad_user", Args: []any{answer}}
+	}
+	user, err := b.users.ResolveUser(ctx, answer)
+	if errors.Is(err, telegram.ErrUserNotFound) {
+		return "", &AnswerError{Key: "user.not_found", Args: []any{answer}}
+	}
+	if err != nil {
+		return "", err
+	}
+	if !b.consent.Allowed(user.ID) {
+		return "", &AnswerError{Key: "spy.no_consent", Args: []any{answer}}
+	}
+	return answer, nil
+}
+
+// Ответы на вопрос о настройках уведомлений; кроме них принимаются часы тишины <с>-<до>
+const (
+	spyNotifyKeep   = "keep"
+	spyNotifyAll    = "all"
+	spyNotifyDigest = "digest"
+)
+
+// validateSpyNotify проверяет ответ о настройках уведомлений
+func validateSpyNotify(_ context.Context, _ *Request, answer string) (string, error) {
+	answer = strings.ToLower(answer)
+	switch answer {
+	case spyNotifyKeep, spyNotifyAll, spyNotifyDigest:
+		return answer, nil
+	}
+	if _, _, ok := parseQuietHours(answer); ok {
+		return answer, nil
+	}
+	return "", &AnswerError{Key: "spy.bad_notify", Args: []any{answer}}
+}
+
+// finishSpyConversation начинает слежение и применяет выбранные в диалоге настройки уведомлений
+func (b *Bot) finishSpyConversation(ctx context.Context, req *Request, values map[string]string) {
+	if !b.startTracking(ctx, req, values["user"], telegram.TargetSettings{}) {
+		return
+	}
+
+	choice := values["notify"]
+	if choice == spyNotifyKeep {
+		return
+	}
+	settings := b.notifyPrefs.Get(req.ChatID)
+	switch choice {
+	case spyNotifyAll:
+		settings.QuietFrom, settings.QuietTo, settings.Digest = 0, 0, false
+	case spyNotifyDigest:
+		settings.Digest = true
+	default:
+		settings.QuietFrom, settings.QuietTo, _ = parseQuietHours(choice)
+	}
+	if err := b.notifyPrefs.Set(req.ChatID, settings); err != nil {
+		b.log.Error("Ошибка сохранения настроек уведомлений", "chat_id", req.ChatID, "error", err)
+		b.reply(req.ChatID, errorText(req.Lang, err))
+		return
+	}
+	b.log.Info("Изменены настройки уведомлений",
+		"chat_id", req.ChatID,
+		"quiet_from", settings.QuietFrom,
+		"quiet_to", settings.QuietTo,
+		"digest", settings.Digest,
+	)
+	b.reply(req.ChatID, notifySettingsText(req.Lang, settings))
+}
+
+// startTracking находит пользователя ref и начинает слежение за ним в чате запроса.
+// Возвращает false, если слежение не начато; причину бот сообщает сам.
+func (b *Bot) startTracking(ctx context.Context, req *Request, ref string, settings telegram.TargetSettings) bool {
+	chatID := req.ChatID
+
+	user, err := b.users.ResolveUser(ctx, ref)
+	if err != nil {
+		b.log.Error("Ошибка поиска пользователя", "ref", ref, "error", err)
+		if errors.Is(err, telegram.ErrUserNotFound) {
+			b.reply(chatID, req.Lang.T("user.not_found", ref))
+			return false
+		}
+		b.reply(chatID, errorText(req.Lang, err))
+		return false
+	}
+
+	target, added, err := b.trackers.Track(chatID, user, settings)
+	if errors.Is(err, telegram.ErrNoConsent) {
+		b.log.Warn("Слежение отклонено: нет согласия пользователя",
+			"user_id", user.ID,
+			"initiator", req.Message.From.UserName,
+			"chat_id", chatID,
+		)
+		b.reply(chatID, req.Lang.T("spy.no_consent", ref))
+		return false
+	}
+	if err != nil {
+		b.log.Error("Ошибка добавления пользователя в реестр слежения", "user_id", user.ID, "error", err)
+		b.reply(chatID, errorText(req.Lang, err))
+		return false
+	}
+
+	b.log.Info("Запуск слежения за пользователем",
+		"user_id", target.UserID,
+		"initiator", req.Message.From.UserName,
+		"chat_id", chatID,
+	)
+	if !added {
+		b.reply(chatID, req.Lang.T("spy.already", target.Label()))
+		return true
+	}
+	b.reply(chatID, req.Lang.T("spy.started", target.Label()))
+	return true
+}
+
+// handleUnspyCommand обрабатывает команду /unspy <пользователь>
+func (b *Bot) handleUnspyCommand(_ context.Context, req *Request) {
+	chatID := req.ChatID
+	ref := req.Args.String("user")
+
+	target, err := b.trackers.Untrack(chatID, ref)
+	if err != nil {
+		if errors.Is(err, telegram.ErrNotTracked) {
+			b.reply(chatID, req.Lang.T("user.not_tracked", ref))
+			return
+		}
+		b.log.Error("Ошибка удаления пользователя из реестра слежения", "ref", ref, "error", err)
+		b.reply(chatID, errorText(req.Lang, err))
+		return
+	}
+
+	b.log.Info("Чат отписан от пользователя",
+		"user_id", target.UserID,
+		"chat_id", chatID,
+		"subscribers_left", len(target.Subscribers),
+	)
+	b.reply(chatID, req.Lang.T("unspy.done", target.Label()))
+}
+
+// handleTrackedCommand обрабатывает команду /tracked
+func (b *Bot) handleTrackedCommand(_ context.Context, req *Request) {
+	chatID := req.ChatID
+	targets := b.trackers.ForChat(chatID)
+	if len(targets) == 0 {
+		b.reply(chatID, req.Lang.T("tracked.empty"))
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString(req.Lang.T("tracked.header") + "\n")
+	for i, t := range targets {
+		fmt.Fprintf(&sb, "%d. %s - %s", i+1, t.Label(), trackerStateText(req.Lang, b.trackers.Status(t.UserID)))
+		if t.Settings.PollInterval > 0 {
+			sb.WriteString(req.Lang.T("tracked.poll", t.Settings.PollInterval))
+		}
+		if len(t.Subscribers) > 1 {
+			sb.WriteString(req.Lang.N("tracked.subscribers", len(t.Subscribers), len(t.Subscribers)))
+		}
+		sb.WriteString("\n")
+	}
+	b.reply(chatID, sb.String())
+}
+
+// handlePauseCommand обрабатывает команды /pause и /resume
+func (b *Bot) handlePauseCommand(req *Request, pause bool) {
+	chatID := req.ChatID
+	ref := req.Args.String("user")
+
+	var (
+		target telegram.Target
+		err    error
+	)
+	if pause {
+		target, err = b.trackers.Pause(chatID, ref)
+	} else {
+		target, err = b.trackers.Resume(chatID, ref)
+	}
+	if err != nil {
+		if errors.Is(err, telegram.ErrNotTracked) {
+			b.reply(chatID, req.Lang.T("user.not_tracked", ref))
+			return
+		}
+		b.log.Error("Ошибка изменения состояния слежения", "ref", ref, "pause", pause, "error", err)
+		b.reply(chatID, errorText(req.Lang, err))
+		return
+	}
+
+	b.log.Info("Изменено состояние слежения",
+		"user_id", target.UserID,
+		"paused", pause,
+		"chat_id", chatID,
+	)
+	if pause {
+		b.reply(chatID, req.Lang.T("pause.done", target.Label()))
+		return
+	}
+	b.reply(chatID, req.Lang.T("resume.done", target.Label()))
+}
+
+// trackerStateText описывает состояние трекера для пользователя на языке lang
+func trackerStateText(lang i18n.Lang, status telegram.TrackerStatus) string {
+	var text string
+	switch status.State {
+	case telegram.TrackerRunning:
+		text = lang.T("tracker.running")
+	case telegram.TrackerPaused:
+		text = lang.T("tracker.paused")
+	default:
+		text = lang.T("tracker.stopped")
+	}
+	if status.LastErr != nil {
+		text += lang.T("tracker.poll_error", status.LastErr)
+	}
+	return text
+}
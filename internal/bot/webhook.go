@@ -0,0 +1,225 @@
+package bot
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"telegram-api-with-go/internal/config"
+	"telegram-api-with-go/internal/logger"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// secretTokenHeader - заголовок, в котором Telegram передает секрет, указанный при регистрации webhook
+const secretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// maxUpdateSize - ограничение на размер тела запроса с обновлением
+const maxUpdateSize = 1 << 20
+
+// allowedUpdates - виды обновлений, которые обрабатывает бот
+const allowedUpdates = `["message","callback_query"]`
+
+// WebhookOptions - параметры webhook
+type WebhookOptions struct {
+	// URL - публичный адрес webhook, который регистрируется в Telegram
+	URL string
+	// Listen - адрес, на котором принимаются запросы, например :8443
+	Listen string
+	// Secret - секрет для заголовка X-Telegram-Bot-Api-Secret-Token;
+	// пустое значение заменяется случайным
+	Secret string
+	// CertFile и KeyFile включают HTTPS; без них сервер принимает HTTP
+	// (например, за обратным прокси, который завершает TLS)
+	CertFile string
+	KeyFile  string
+	// SelfSigned - загрузить сертификат CertFile в Telegram при регистрации
+	SelfSigned bool
+}
+
+// WebhookOptionsFromConfig возвращает параметры webhook из конфигурации
+func WebhookOptionsFromConfig() WebhookOptions {
+	return WebhookOptions{
+		URL:        config.WebhookURL,
+		Listen:     config.WebhookListen,
+		Secret:     config.WebhookSecret,
+		CertFile:   config.WebhookCertFile,
+		KeyFile:    config.WebhookKeyFile,
+		SelfSigned: config.WebhookSelfSigned,
+	}
+}
+
+// WebhookServer принимает обновления от Telegram по HTTP(S)
+// и передает их в канал, который обрабатывает бот так же, как при long polling
+type WebhookServer struct {
+	api     API
+	opts    WebhookOptions
+	log     *slog.Logger
+	updates chan tgbotapi.Update
+	// done закрывается при остановке: обработчики запросов больше не ждут бота
+	done     chan struct{}
+	server   *http.Server
+	listener net.Listener
+	served   chan error
+}
+
+// NewWebhookServer создает сервер webhook
+func NewWebhookServer(api API, opts WebhookOptions) *WebhookServer {
+	return &WebhookServer{
+		api:     api,
+		opts:    opts,
+		log:     logger.Log,
+		updates: make(chan tgbotapi.Update),
+		done:    make(chan struct{}),
+	}
+}
+
+// Start открывает порт, запускает сервер и регистрирует webhook в Telegram.
+// Порт открывается до регистрации, чтобы Telegram мог сразу доставить обновления.
+func (s *WebhookServer) Start() error {
+	u, err := url.Parse(s.opts.URL)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("некорректный адрес webhook %q", s.opts.URL)
+	}
+	if s.opts.Secret == "" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return err
+		}
+		s.opts.Secret = hex.EncodeToString(secret)
+	}
+
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+	mux := http.NewServeMux()
+	mux.Handle(path, s)
+	s.server = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	if s.opts.CertFile != "" {
+		// Сертификат загружается заранее, чтобы ошибка в файлах остановила запуск до регистрации
+		cert, err := tls.LoadX509KeyPair(s.opts.CertFile, s.opts.KeyFile)
+		if err != nil {
+			return fmt.Errorf("загрузка сертификата webhook: %w", err)
+		}
+		s.server.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+	}
+
+	s.listener, err = net.Listen("tcp", s.opts.Listen)
+	if err != nil {
+		return fmt.Errorf("запуск сервера webhook: %w", err)
+	}
+	useTLS := s.server.TLSConfig != nil
+	s.served = make(chan error, 1)
+	go func() {
+		if useTLS {
+			s.served <- s.server.ServeTLS(s.listener, "", "")
+		} else {
+			s.served <- s.server.Serve(s.listener)
+		}
+	}()
+	s.log.Info("Запущен сервер webhook",
+		"listen", s.listener.Addr().String(),
+		"path", path,
+		"tls", useTLS,
+	)
+
+	if err := s.register(); err != nil {
+		s.Shutdown(context.Background())
+		return err
+	}
+	return nil
+}
+
+// register регистрирует webhook методом setWebhook
+func (s *WebhookServer) register() error {
+	var (
+		resp tgbotapi.APIResponse
+		err  error
+	)
+	if s.opts.SelfSigned {
+		resp, err = s.api.UploadFile("setWebhook", map[string]string{
+			"url":             s.opts.URL,
+			"secret_token":    s.opts.Secret,
+			"allowed_updates": allowedUpdates,
+		}, "certificate", s.opts.CertFile)
+	} else {
+		resp, err = s.api.MakeRequest("setWebhook", url.Values{
+			"url":             {s.opts.URL},
+			"secret_token":    {s.opts.Secret},
+			"allowed_updates": {allowedUpdates},
+		})
+	}
+	if err != nil {
+		return fmt.Errorf("регистрация webhook: %w", err)
+	}
+	if !resp.Ok {
+		return fmt.Errorf("регистрация webhook: %s", resp.Description)
+	}
+	s.log.Info("Webhook зарегистрирован", "url", s.opts.URL, "self_signed", s.opts.SelfSigned)
+	return nil
+}
+
+// Addr возвращает адрес, на котором сервер принимает запросы
+func (s *WebhookServer) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// Updates возвращает канал обновлений
+func (s *WebhookServer) Updates() <-chan tgbotapi.Update {
+	return s.updates
+}
+
+// ServeHTTP принимает обновление от Telegram. Запросы без верного секрета отклоняются.
+// Ответ отправляется после того, как бот принял обновление, поэтому Telegram
+// повторит доставку, если бот остановился раньше.
+func (s *WebhookServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	token := r.Header.Get(secretTokenHeader)
+	if subtle.ConstantTimeCompare([]byte(token), []byte(s.opts.Secret)) != 1 {
+		s.log.Warn("Отклонен запрос webhook с неверным секретом", "remote", r.RemoteAddr)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var update tgbotapi.Update
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxUpdateSize)).Decode(&update); err != nil {
+		s.log.Warn("Некорректное обновление webhook", "remote", r.RemoteAddr, "error", err)
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	select {
+	case s.updates <- update:
+		w.WriteHeader(http.StatusOK)
+	case <-s.done:
+		http.Error(w, "shutting down", http.StatusServiceUnavailable)
+	case <-r.Context().Done():
+	}
+}
+
+// Shutdown останавливает сервер и дожидается завершения запросов.
+// Webhook остается зарегистрированным: Telegram накапливает обновления до следующего запуска.
+func (s *WebhookServer) Shutdown(ctx context.Context) error {
+	close(s.done)
+	err := s.server.Shutdown(ctx)
+	if served := <-s.served; !errors.Is(served, http.ErrServerClosed) && err == nil {
+		err = served
+	}
+	s.log.Info("Сервер webhook остановлен")
+	return err
+}
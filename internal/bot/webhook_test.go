@@ -0,0 +1,208 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"telegram-api-with-go/internal/config"
+	"telegram-api-with-go/internal/logger"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// postUpdate отправляет обновление на webhook и возвращает код ответа
+func postUpdate(t *testing.T, client *http.Client, target, secret string, update tgbotapi.Update) int {
+	t.Helper()
+	body, err := json.Marshal(update)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequest(http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if secret != "" {
+		req.Header.Set(secretTokenHeader, secret)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestWebhookServer(t *testing.T) {
+	logger.Log = slog.New(slog.NewTextHandler(io.Discard, nil))
+	api := newFakeAPI()
+	s := NewWebhookServer(api, WebhookOptions{
+		URL:    "https://bot.example.com/telegram/hook",
+		Listen: "127.0.0.1:0",
+		Secret: "s3cret",
+	})
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	stopped := false
+	defer func() {
+		if !stopped {
+			s.Shutdown(context.Background())
+		}
+	}()
+
+	reg := api.requestsTo("setWebhook")
+	if len(reg) != 1 || reg[0].Get("url") != "https://bot.example.com/telegram/hook" || reg[0].Get("secret_token") != "s3cret" {
+		t.Fatalf("setWebhook = %v", reg)
+	}
+	if got := reg[0].Get("allowed_updates"); got != allowedUpdates {
+		t.Fatalf("allowed_updates = %s", got)
+	}
+
+	target := "http://" + s.Addr().String() + "/telegram/hook"
+	client := &http.Client{Timeout: 5 * time.Second}
+	update := commandUpdate("/status")
+
+	if code := postUpdate(t, client, target, "", update); code != http.StatusUnauthorized {
+		t.Fatalf("no secret: %d", code)
+	}
+	if code := postUpdate(t, client, target, "wrong", update); code != http.StatusUnauthorized {
+		t.Fatalf("wrong secret: %d", code)
+	}
+	if code := postUpdate(t, client, "http://"+s.Addr().String()+"/other", "s3cret", update); code != http.StatusNotFound {
+		t.Fatalf("other path: %d", code)
+	}
+	resp, err := client.Get(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("GET: %d", resp.StatusCode)
+	}
+	req, _ := http.NewRequest(http.MethodPost, target, strings.NewReader("{"))
+	req.Header.Set(secretTokenHeader, "s3cret")
+	if resp, err := client.Do(req); err != nil || resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("bad body: %v, %v", resp, err)
+	}
+
+	received := make(chan tgbotapi.Update, 1)
+	go func() { received <- <-s.Updates() }()
+	if code := postUpdate(t, client, target, "s3cret", update); code != http.StatusOK {
+		t.Fatalf("update: %d", code)
+	}
+	if got := <-received; got.Message == nil || got.Message.Text != "/status" {
+		t.Fatalf("received %+v", got)
+	}
+
+	// После остановки обновления не принимаются, и Telegram повторит доставку
+	stopped = true
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Post(target, "application/json", strings.NewReader("{}")); err == nil {
+		t.Fatal("server still accepts requests after shutdown")
+	}
+}
+
+// writeSelfSignedCert создает самоподписанный сертификат для 127.0.0.1
+func writeSelfSignedCert(t *testing.T) (certFile, keyFile string, pool *x509.CertPool) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool = x509.NewCertPool()
+	pool.AddCert(cert)
+	return certFile, keyFile, pool
+}
+
+func TestWebhookMode(t *testing.T) {
+	tb := newTestBot(t)
+	certFile, keyFile, pool := writeSelfSignedCert(t)
+	config.BotMode = "webhook"
+	config.WebhookURL = "https://127.0.0.1:8443/hook"
+	config.WebhookListen = "127.0.0.1:0"
+	config.WebhookSecret = ""
+	config.WebhookCertFile = certFile
+	config.WebhookKeyFile = keyFile
+	config.WebhookSelfSigned = true
+	tb.start(t)
+
+	// Самоподписанный сертификат загружается вместе с регистрацией, секрет генерируется
+	deadline := time.Now().Add(5 * time.Second)
+	for len(tb.api.requestsTo("setWebhook")) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("webhook was not registered")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	reg := tb.api.requestsTo("setWebhook")[0]
+	secret := reg.Get("secret_token")
+	if reg.Get("certificate") != certFile || len(secret) != 64 {
+		t.Fatalf("setWebhook = %v", reg)
+	}
+	if len(tb.api.requestsTo("deleteWebhook")) != 0 {
+		t.Fatal("webhook mode must not delete the webhook")
+	}
+
+	client := &http.Client{
+		Timeout:   5 * time.Second,
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}},
+	}
+	target := "https://" + tb.bot.webhook.Addr().String() + "/hook"
+	if code := postUpdate(t, client, target, secret, commandUpdate("/status")); code != http.StatusOK {
+		t.Fatalf("update: %d", code)
+	}
+	if got := messageText(t, tb.api.waitSent(t, 1)[0]); !strings.HasPrefix(got, "Telegram клиент подключен.") {
+		t.Fatalf("reply = %q", got)
+	}
+}
@@ -1,9 +1,13 @@
 package config
 
 import (
+	"fmt"
 	"log"
 	"os"
 	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // часовые пояса для TIMEZONE на системах без базы tzdata
 
 	"github.com/joho/godotenv"
 )
@@ -13,15 +17,81 @@ var (
 	APIID    int
 	APIHash  string
 	BotToken string
+	// BotAPIEndpoint - адрес Bot API вместо https://api.telegram.org
+	BotAPIEndpoint string
 
 	// File paths
-	SessionFile string
+	SessionFile         string
+	TrackerRegistryFile string
+	NotifySettingsFile  string
+	ConsentFile         string
+	ConsentAuditFile    string
+	RolesFile           string
+	LangFile            string
+	ConversationsFile   string
 
 	// Default settings
 	DefaultSpyUserID int64
 
+	// Access control
+	OwnerIDs []int64
+
+	// Inline keyboards
+	CallbackSecret string
+	CallbackTTL    time.Duration
+
+	// Multi-step commands
+	ConversationTimeout time.Duration
+
+	// Replies
+	ReplyMaxMessages int
+
+	// Update processing
+	UpdateWorkers  int
+	HandlerTimeout time.Duration
+
+	// Shutdown
+	ShutdownTimeout time.Duration
+
+	// Update delivery
+	BotMode           string
+	WebhookURL        string
+	WebhookListen     string
+	WebhookSecret     string
+	WebhookCertFile   string
+	WebhookKeyFile    string
+	WebhookSelfSigned bool
+
 	// Logging
 	LogLevel string
+
+	// Reports
+	Location *time.Location
+
+	// Data centers
+	DCMode          string
+	DCID            int
+	DCAddr          string
+	DCPublicKeyFile string
+
+	// Presence tracking
+	PresencePollInterval time.Duration
+	PresencePollJitter   time.Duration
+	PresenceStore        string
+	PresenceStorePath    string
+
+	// Notifications
+	NotifyDebounce   time.Duration
+	NotifyDigestHour int
+
+	// Client restarts
+	RestartInitialDelay time.Duration
+	RestartMaxDelay     time.Duration
+
+	// Proxy
+	ProxyURL      string
+	MTProxyAddr   string
+	MTProxySecret string
 )
 
 // LoadConfig загружает конфигурацию из .env файла
@@ -50,18 +120,154 @@ func LoadConfig() error {
 		return ErrMissingEnvVar("TELEGRAM_BOT_TOKEN")
 	}
 
+	// BOT_API_ENDPOINT необязателен: адрес локального сервера Bot API
+	BotAPIEndpoint = os.Getenv("BOT_API_ENDPOINT")
+
 	// User settings
-	spyUserIDStr := os.Getenv("DEFAULT_SPY_USER_ID")
-	DefaultSpyUserID, err = strconv.ParseInt(spyUserIDStr, 10, 64)
+	// DEFAULT_SPY_USER_ID необязателен: пользователи добавляются командой /spy <пользователь>.
+	// Если он задан, /spy без аргументов следит за ним вместо диалога.
+	DefaultSpyUserID = 0
+	if spyUserIDStr := os.Getenv("DEFAULT_SPY_USER_ID"); spyUserIDStr != "" {
+		DefaultSpyUserID, err = strconv.ParseInt(spyUserIDStr, 10, 64)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Access control
+	// BOT_OWNER_IDS - ID владельцев бота через запятую; без владельцев доступны только общие команды
+	OwnerIDs = nil
+	for _, idStr := range strings.Split(os.Getenv("BOT_OWNER_IDS"), ",") {
+		idStr = strings.TrimSpace(idStr)
+		if idStr == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			return fmt.Errorf("некорректное значение BOT_OWNER_IDS: %q", idStr)
+		}
+		OwnerIDs = append(OwnerIDs, id)
+	}
+
+	// Inline keyboards
+	// CALLBACK_SECRET - ключ подписи данных кнопок; по умолчанию ключ выводится из TELEGRAM_BOT_TOKEN
+	CallbackSecret = os.Getenv("CALLBACK_SECRET")
+	CallbackTTL, err = durationEnv("CALLBACK_TTL", time.Hour)
 	if err != nil {
 		return err
 	}
+	if CallbackTTL <= 0 {
+		return fmt.Errorf("некорректное значение CALLBACK_TTL: %s (ожидается положительная длительность)", CallbackTTL)
+	}
+
+	// Multi-step commands
+	// CONVERSATION_TIMEOUT - время ожидания ответа на вопрос бота в многошаговой команде
+	ConversationTimeout, err = durationEnv("CONVERSATION_TIMEOUT", 10*time.Minute)
+	if err != nil {
+		return err
+	}
+	if ConversationTimeout <= 0 {
+		return fmt.Errorf("некорректное значение CONVERSATION_TIMEOUT: %s (ожидается положительная длительность)", ConversationTimeout)
+	}
+
+	// Replies
+	ReplyMaxMessages = 3 // значение по умолчанию
+	if maxStr := os.Getenv("REPLY_MAX_MESSAGES"); maxStr != "" {
+		ReplyMaxMessages, err = strconv.Atoi(maxStr)
+		if err != nil || ReplyMaxMessages < 1 {
+			return fmt.Errorf("некорректное значение REPLY_MAX_MESSAGES: %q (ожидается число не меньше 1)", maxStr)
+		}
+	}
+
+	// Update processing
+	// UPDATE_WORKERS - сколько обновлений разных чатов обрабатывается одновременно
+	UpdateWorkers = 8 // значение по умолчанию
+	if workersStr := os.Getenv("UPDATE_WORKERS"); workersStr != "" {
+		UpdateWorkers, err = strconv.Atoi(workersStr)
+		if err != nil || UpdateWorkers < 1 {
+			return fmt.Errorf("некорректное значение UPDATE_WORKERS: %q (ожидается число не меньше 1)", workersStr)
+		}
+	}
+	// HANDLER_TIMEOUT - предельное время обработки одного обновления
+	HandlerTimeout, err = durationEnv("HANDLER_TIMEOUT", 2*time.Minute)
+	if err != nil {
+		return err
+	}
+	if HandlerTimeout <= 0 {
+		return fmt.Errorf("некорректное значение HANDLER_TIMEOUT: %s (ожидается положительная длительность)", HandlerTimeout)
+	}
+
+	// Shutdown
+	// SHUTDOWN_TIMEOUT - предельное время плавной остановки всех компонентов
+	ShutdownTimeout, err = durationEnv("SHUTDOWN_TIMEOUT", 30*time.Second)
+	if err != nil {
+		return err
+	}
+	if ShutdownTimeout <= 0 {
+		return fmt.Errorf("некорректное значение SHUTDOWN_TIMEOUT: %s (ожидается положительная длительность)", ShutdownTimeout)
+	}
+
+	// Update delivery
+	BotMode = os.Getenv("BOT_MODE")
+	if BotMode == "" {
+		BotMode = "polling" // значение по умолчанию
+	}
+	if BotMode != "polling" && BotMode != "webhook" {
+		return fmt.Errorf("некорректное значение BOT_MODE: %q (ожидается polling или webhook)", BotMode)
+	}
+	WebhookURL = os.Getenv("WEBHOOK_URL")
+	if BotMode == "webhook" && WebhookURL == "" {
+		return ErrMissingEnvVar("WEBHOOK_URL")
+	}
+	WebhookListen = os.Getenv("WEBHOOK_LISTEN")
+	if WebhookListen == "" {
+		WebhookListen = ":8443" // значение по умолчанию
+	}
+	// WEBHOOK_SECRET необязателен: без него секрет генерируется при каждом запуске
+	WebhookSecret = os.Getenv("WEBHOOK_SECRET")
+	WebhookCertFile = os.Getenv("WEBHOOK_CERT_FILE")
+	WebhookKeyFile = os.Getenv("WEBHOOK_KEY_FILE")
+	if (WebhookCertFile == "") != (WebhookKeyFile == "") {
+		return fmt.Errorf("WEBHOOK_CERT_FILE и WEBHOOK_KEY_FILE задаются вместе")
+	}
+	WebhookSelfSigned = os.Getenv("WEBHOOK_SELF_SIGNED") == "true"
+	if WebhookSelfSigned && WebhookCertFile == "" {
+		return ErrMissingEnvVar("WEBHOOK_CERT_FILE")
+	}
 
 	// File paths
 	SessionFile = os.Getenv("SESSION_FILE")
 	if SessionFile == "" {
 		SessionFile = "session.data" // значение по умолчанию
 	}
+	TrackerRegistryFile = os.Getenv("TRACKER_REGISTRY_FILE")
+	if TrackerRegistryFile == "" {
+		TrackerRegistryFile = "trackers.json" // значение по умолчанию
+	}
+	NotifySettingsFile = os.Getenv("NOTIFY_SETTINGS_FILE")
+	if NotifySettingsFile == "" {
+		NotifySettingsFile = "notify.json" // значение по умолчанию
+	}
+	ConsentFile = os.Getenv("CONSENT_FILE")
+	if ConsentFile == "" {
+		ConsentFile = "consent.json" // значение по умолчанию
+	}
+	ConsentAuditFile = os.Getenv("CONSENT_AUDIT_FILE")
+	if ConsentAuditFile == "" {
+		ConsentAuditFile = "consent_audit.jsonl" // значение по умолчанию
+	}
+	RolesFile = os.Getenv("ROLES_FILE")
+	if RolesFile == "" {
+		RolesFile = "roles.json" // значение по умолчанию
+	}
+	LangFile = os.Getenv("LANGUAGES_FILE")
+	if LangFile == "" {
+		LangFile = "languages.json" // значение по умолчанию
+	}
+	ConversationsFile = os.Getenv("CONVERSATIONS_FILE")
+	if ConversationsFile == "" {
+		ConversationsFile = "conversations.json" // значение по умолчанию
+	}
 
 	// Logging
 	LogLevel = os.Getenv("LOG_LEVEL")
@@ -69,9 +275,120 @@ func LoadConfig() error {
 		LogLevel = "info" // значение по умолчанию
 	}
 
+	// Reports
+	timezone := os.Getenv("TIMEZONE")
+	if timezone == "" {
+		timezone = "UTC" // значение по умолчанию
+	}
+	Location, err = time.LoadLocation(timezone)
+	if err != nil {
+		return fmt.Errorf("некорректное значение TIMEZONE: %w", err)
+	}
+
+	// Data centers
+	DCMode = os.Getenv("TELEGRAM_DC_MODE")
+	if DCMode == "" {
+		DCMode = "prod" // значение по умолчанию
+	}
+	if DCMode != "prod" && DCMode != "test" && DCMode != "custom" {
+		return fmt.Errorf("некорректное значение TELEGRAM_DC_MODE: %q (ожидается prod, test или custom)", DCMode)
+	}
+	DCID = 2 // значение по умолчанию
+	if dcIDStr := os.Getenv("TELEGRAM_DC_ID"); dcIDStr != "" {
+		DCID, err = strconv.Atoi(dcIDStr)
+		if err != nil {
+			return err
+		}
+	}
+	DCAddr = os.Getenv("TELEGRAM_DC_ADDR")
+	if DCMode == "custom" && DCAddr == "" {
+		return ErrMissingEnvVar("TELEGRAM_DC_ADDR")
+	}
+	DCPublicKeyFile = os.Getenv("TELEGRAM_DC_PUBLIC_KEY")
+
+	// Presence tracking
+	PresencePollInterval, err = durationEnv("PRESENCE_POLL_INTERVAL", 5*time.Minute)
+	if err != nil {
+		return err
+	}
+	PresencePollJitter, err = durationEnv("PRESENCE_POLL_JITTER", 30*time.Second)
+	if err != nil {
+		return err
+	}
+	PresenceStore = os.Getenv("PRESENCE_STORE")
+	if PresenceStore == "" {
+		PresenceStore = "jsonl" // значение по умолчанию
+	}
+	if PresenceStore != "jsonl" && PresenceStore != "bolt" {
+		return fmt.Errorf("некорректное значение PRESENCE_STORE: %q (ожидается jsonl или bolt)", PresenceStore)
+	}
+	PresenceStorePath = os.Getenv("PRESENCE_STORE_PATH")
+	if PresenceStorePath == "" {
+		PresenceStorePath = DefaultPresenceStorePath(PresenceStore)
+	}
+
+	// Notifications
+	NotifyDebounce, err = durationEnv("NOTIFY_DEBOUNCE", time.Minute)
+	if err != nil {
+		return err
+	}
+	NotifyDigestHour = 21 // значение по умолчанию
+	if hourStr := os.Getenv("NOTIFY_DIGEST_HOUR"); hourStr != "" {
+		NotifyDigestHour, err = strconv.Atoi(hourStr)
+		if err != nil || NotifyDigestHour < 0 || NotifyDigestHour > 23 {
+			return fmt.Errorf("некорректное значение NOTIFY_DIGEST_HOUR: %q (ожидается час от 0 до 23)", hourStr)
+		}
+	}
+
+	// Client restarts
+	RestartInitialDelay, err = durationEnv("CLIENT_RESTART_INITIAL_DELAY", time.Second)
+	if err != nil {
+		return err
+	}
+	if RestartInitialDelay <= 0 {
+		return fmt.Errorf("некорректное значение CLIENT_RESTART_INITIAL_DELAY: %s (ожидается положительная длительность)", RestartInitialDelay)
+	}
+	RestartMaxDelay, err = durationEnv("CLIENT_RESTART_MAX_DELAY", 5*time.Minute)
+	if err != nil {
+		return err
+	}
+	if RestartMaxDelay < RestartInitialDelay {
+		return fmt.Errorf("некорректное значение CLIENT_RESTART_MAX_DELAY: %s (ожидается не меньше CLIENT_RESTART_INITIAL_DELAY %s)", RestartMaxDelay, RestartInitialDelay)
+	}
+
+	// Proxy
+	ProxyURL = os.Getenv("PROXY_URL")
+	MTProxyAddr = os.Getenv("MTPROXY_ADDR")
+	MTProxySecret = os.Getenv("MTPROXY_SECRET")
+	if MTProxyAddr != "" && MTProxySecret == "" {
+		return ErrMissingEnvVar("MTPROXY_SECRET")
+	}
+
 	return nil
 }
 
+// durationEnv читает длительность из переменной окружения или возвращает значение по умолчанию
+func durationEnv(name string, def time.Duration) (time.Duration, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("некорректное значение %s: %w", name, err)
+	}
+	return d, nil
+}
+
+// DefaultPresenceStorePath возвращает путь к хранилищу истории вида kind по умолчанию:
+// каталог для jsonl, файл базы для bolt
+func DefaultPresenceStorePath(kind string) string {
+	if kind == "bolt" {
+		return "presence.db"
+	}
+	return "presence"
+}
+
 // ErrMissingEnvVar возвращает ошибку о отсутствующей переменной окружения
 func ErrMissingEnvVar(name string) error {
 	return &MissingEnvVarError{VarName: name}
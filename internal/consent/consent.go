@@ -0,0 +1,210 @@
+package consent
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"telegram-api-with-go/internal/fileutil"
+	"telegram-api-with-go/internal/logger"
+)
+
+// Action - вид записи журнала согласий
+type Action string
+
+const (
+	ActionAllow         Action = "allow"
+	ActionDeny          Action = "deny"
+	ActionDeleteHistory Action = "delete_history"
+)
+
+// Grant - согласие пользователя на отслеживание
+type Grant struct {
+	UserID    int64     `json:"user_id"`
+	Username  string    `json:"username,omitempty"`
+	FirstName string    `json:"first_name,omitempty"`
+	LastName  string    `json:"last_name,omitempty"`
+	GrantedAt time.Time `json:"granted_at"`
+}
+
+// Event - запись журнала согласий
+type Event struct {
+	Time     time.Time `json:"time"`
+	UserID   int64     `json:"user_id"`
+	Username string    `json:"username,omitempty"`
+	Action   Action    `json:"action"`
+}
+
+// Registry хранит согласия пользователей на отслеживание в JSON файле
+// и записывает каждое изменение в журнал: файл JSON Lines, который только дополняется.
+type Registry struct {
+	path      string
+	auditPath string
+	log       *slog.Logger
+	now       func() time.Time
+
+	mu     sync.Mutex
+	grants map[int64]Grant
+}
+
+// NewRegistry создает реестр согласий с файлом path и журналом auditPath
+func NewRegistry(path, auditPath string) *Registry {
+	return &Registry{
+		path:      path,
+		auditPath: auditPath,
+		log:       logger.Log,
+		now:       time.Now,
+		grants:    make(map[int64]Grant),
+	}
+}
+
+// Load читает сохраненные согласия. Отсутствие файла не является ошибкой.
+func (r *Registry) Load() error {
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("чтение реестра согласий: %w", err)
+	}
+
+	var grants []Grant
+	if err := json.Unmarshal(data, &grants); err != nil {
+		return fmt.Errorf("разбор реестра согласий %s: %w", r.path, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.grants = make(map[int64]Grant, len(grants))
+	for _, g := range grants {
+		r.grants[g.UserID] = g
+	}
+	r.log.Info("Загружен реестр согласий", "path", r.path, "grants", len(grants))
+	return nil
+}
+
+// Allowed сообщает, дал ли пользователь согласие на отслеживание
+func (r *Registry) Allowed(userID int64) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.grants[userID]
+	return ok
+}
+
+// Get возвращает согласие пользователя
+func (r *Registry) Get(userID int64) (Grant, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	g, ok := r.grants[userID]
+	return g, ok
+}
+
+// Allow записывает согласие пользователя. added равно false, если согласие уже было дано;
+// в этом случае обновляются только имя и username.
+func (r *Registry) Allow(g Grant) (added bool, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if old, ok := r.grants[g.UserID]; ok {
+		g.GrantedAt = old.GrantedAt
+		r.grants[g.UserID] = g
+		return false, r.saveLocked()
+	}
+
+	g.GrantedAt = r.now().UTC()
+	if err := r.auditLocked(g.UserID, g.Username, ActionAllow); err != nil {
+		return false, err
+	}
+	r.grants[g.UserID] = g
+	return true, r.saveLocked()
+}
+
+// Deny отзывает согласие пользователя. removed равно false, если согласия не было.
+func (r *Registry) Deny(userID int64, username string) (removed bool, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.grants[userID]; !ok {
+		return false, nil
+	}
+	if err := r.auditLocked(userID, username, ActionDeny); err != nil {
+		return false, err
+	}
+	delete(r.grants, userID)
+	return true, r.saveLocked()
+}
+
+// Record записывает в журнал действие, выполненное по решению пользователя,
+// например удаление истории
+func (r *Registry) Record(userID int64, username string, action Action) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.auditLocked(userID, username, action)
+}
+
+// Audit возвращает записи журнала в порядке добавления
+func (r *Registry) Audit() ([]Event, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	f, err := os.Open(r.auditPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("чтение журнала согласий: %w", err)
+	}
+	defer f.Close()
+
+	var events []Event
+	dec := json.NewDecoder(f)
+	for dec.More() {
+		var e Event
+		if err := dec.Decode(&e); err != nil {
+			return nil, fmt.Errorf("разбор журнала согласий %s: %w", r.auditPath, err)
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
+
+// auditLocked дописывает запись в журнал. Изменение не применяется, если запись не удалась.
+func (r *Registry) auditLocked(userID int64, username string, action Action) error {
+	line, err := json.Marshal(Event{Time: r.now().UTC(), UserID: userID, Username: username, Action: action})
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(r.auditPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("запись журнала согласий: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("запись журнала согласий: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("запись журнала согласий: %w", err)
+	}
+
+	r.log.Info("Изменение согласия на отслеживание", "user_id", userID, "action", action)
+	return nil
+}
+
+// saveLocked атомарно перезаписывает файл реестра
+func (r *Registry) saveLocked() error {
+	grants := make([]Grant, 0, len(r.grants))
+	for _, g := range r.grants {
+		grants = append(grants, g)
+	}
+	slices.SortFunc(grants, func(a, b Grant) int { return cmp.Compare(a.UserID, b.UserID) })
+	if err := fileutil.WriteJSONAtomic(r.path, grants); err != nil {
+		return fmt.Errorf("сохранение реестра согласий: %w", err)
+	}
+	return nil
+}
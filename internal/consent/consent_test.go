@@ -0,0 +1,84 @@
+package consent
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestRegistry(t *testing.T) *Registry {
+	t.Helper()
+	dir := t.TempDir()
+	r := NewRegistry(filepath.Join(dir, "consent.json"), filepath.Join(dir, "audit.jsonl"))
+	r.log = slog.New(slog.NewTextHandler(io.Discard, nil))
+	r.now = func() time.Time { return time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC) }
+	return r
+}
+
+func TestRegistry(t *testing.T) {
+	r := newTestRegistry(t)
+
+	if r.Allowed(2002) {
+		t.Fatal("allowed before consent")
+	}
+	if added, err := r.Allow(Grant{UserID: 2002, Username: "alice"}); err != nil || !added {
+		t.Fatalf("Allow = %v, %v", added, err)
+	}
+	if added, err := r.Allow(Grant{UserID: 2002, Username: "alice_new"}); err != nil || added {
+		t.Fatalf("repeated Allow = %v, %v", added, err)
+	}
+
+	// Согласие переживает перезапуск
+	loaded := NewRegistry(r.path, r.auditPath)
+	loaded.log = r.log
+	if err := loaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if g, ok := loaded.Get(2002); !ok || g.Username != "alice_new" || !g.GrantedAt.Equal(r.now()) {
+		t.Fatalf("loaded grant = %+v, %v", g, ok)
+	}
+
+	if removed, err := r.Deny(2002, "alice_new"); err != nil || !removed {
+		t.Fatalf("Deny = %v, %v", removed, err)
+	}
+	if removed, err := r.Deny(2002, "alice_new"); err != nil || removed {
+		t.Fatalf("repeated Deny = %v, %v", removed, err)
+	}
+	if err := r.Record(2002, "alice_new", ActionDeleteHistory); err != nil {
+		t.Fatal(err)
+	}
+	if r.Allowed(2002) {
+		t.Fatal("allowed after deny")
+	}
+
+	events, err := r.Audit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Action{ActionAllow, ActionDeny, ActionDeleteHistory}
+	if len(events) != len(want) {
+		t.Fatalf("audit = %+v", events)
+	}
+	for i, e := range events {
+		if e.Action != want[i] || e.UserID != 2002 {
+			t.Errorf("event %d = %+v, want %s", i, e, want[i])
+		}
+	}
+}
+
+func TestRegistryAuditFailure(t *testing.T) {
+	r := newTestRegistry(t)
+	// Журнал недоступен для записи: изменение не должно примениться
+	if err := os.Mkdir(r.auditPath, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Allow(Grant{UserID: 2002}); err == nil {
+		t.Fatal("Allow must fail when the audit trail cannot be written")
+	}
+	if r.Allowed(2002) {
+		t.Fatal("consent recorded without an audit entry")
+	}
+}
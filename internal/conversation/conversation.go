@@ -0,0 +1,223 @@
+// Package conversation хранит состояние многошаговых команд бота по чатам и пользователям.
+package conversation
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"telegram-api-with-go/internal/fileutil"
+)
+
+// State - состояние диалога пользователя в чате
+type State struct {
+	// Command - команда, начавшая диалог, без '/'
+	Command string `json:"command"`
+	// UserID - пользователь, который отвечает на вопросы; ответы других участников чата не учитываются
+	UserID int64 `json:"user_id"`
+	// Step - номер текущего шага
+	Step int `json:"step"`
+	// Values - ответы на пройденные шаги по именам шагов
+	Values map[string]string `json:"values,omitempty"`
+	// Expires - время, после которого диалог прерывается
+	Expires time.Time `json:"expires"`
+}
+
+// Expired - диалог, прерванный по истечении времени ожидания ответа
+type Expired struct {
+	ChatID int64
+	State  State
+}
+
+// key - диалог пользователя в чате. В файле записывается строкой "чат:пользователь".
+type key struct {
+	chatID int64
+	userID int64
+}
+
+func (k key) MarshalText() ([]byte, error) {
+	return []byte(strconv.FormatInt(k.chatID, 10) + ":" + strconv.FormatInt(k.userID, 10)), nil
+}
+
+// UnmarshalText разбирает ключ. Ключ из одного ID чата записан версией,
+// хранившей один диалог на чат; пользователя Load берет из состояния.
+func (k *key) UnmarshalText(text []byte) error {
+	chat, user, found := strings.Cut(string(text), ":")
+	chatID, err := strconv.ParseInt(chat, 10, 64)
+	if err != nil {
+		return fmt.Errorf("некорректный ключ диалога %q", text)
+	}
+	var userID int64
+	if found {
+		if userID, err = strconv.ParseInt(user, 10, 64); err != nil {
+			return fmt.Errorf("некорректный ключ диалога %q", text)
+		}
+	}
+	*k = key{chatID: chatID, userID: userID}
+	return nil
+}
+
+// Store хранит диалоги в JSON файле, чтобы они переживали перезапуск бота.
+// У каждого участника чата может быть свой диалог.
+type Store struct {
+	path string
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu    sync.Mutex
+	chats map[key]State
+}
+
+// NewStore создает хранилище диалогов с файлом path. Диалог прерывается,
+// если ответ на очередной вопрос не получен за ttl.
+func NewStore(path string, ttl time.Duration) *Store {
+	return &Store{path: path, ttl: ttl, now: time.Now, chats: make(map[key]State)}
+}
+
+// Load загружает диалоги из файла. Отсутствие файла не является ошибкой.
+func (s *Store) Load() error {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("чтение диалогов: %w", err)
+	}
+
+	stored := make(map[key]State)
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return fmt.Errorf("разбор диалогов %s: %w", s.path, err)
+	}
+	chats := make(map[key]State, len(stored))
+	for k, st := range stored {
+		k.userID = st.UserID
+		chats[k] = st
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.chats = chats
+	return nil
+}
+
+// Get возвращает незавершенный диалог пользователя userID в чате. Диалог с истекшим
+// временем ожидания не возвращается и удаляется при следующем вызове Expire.
+func (s *Store) Get(chatID, userID int64) (State, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, ok := s.activeLocked(key{chatID: chatID, userID: userID})
+	if !ok {
+		return State{}, false
+	}
+	st.Values = maps.Clone(st.Values)
+	return st, true
+}
+
+// Save сохраняет диалог пользователя st.UserID в чате и продлевает время ожидания ответа.
+// Диалог заменяет ранее начатый диалог этого пользователя в чате.
+func (s *Store) Save(chatID int64, st State) (State, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.saveStateLocked(chatID, st)
+}
+
+// CompareAndSave сохраняет диалог, как Save, только если диалог пользователя в чате
+// не истек и не изменился с момента, когда было получено состояние old: у него тот же
+// шаг и время ожидания. Истекший или отмененный диалог не восстанавливается.
+func (s *Store) CompareAndSave(chatID int64, old, st State) (State, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.unchangedLocked(chatID, old) {
+		return State{}, false, nil
+	}
+	st, err := s.saveStateLocked(chatID, st)
+	return st, true, err
+}
+
+// Delete завершает диалог пользователя userID в чате и возвращает его состояние
+func (s *Store) Delete(chatID, userID int64) (State, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k := key{chatID: chatID, userID: userID}
+	st, ok := s.chats[k]
+	if !ok {
+		return State{}, false, nil
+	}
+	delete(s.chats, k)
+	return st, true, s.saveLocked()
+}
+
+// CompareAndDelete завершает диалог, только если он не истек и не изменился
+// с момента, когда было получено состояние old
+func (s *Store) CompareAndDelete(chatID int64, old State) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.unchangedLocked(chatID, old) {
+		return false, nil
+	}
+	delete(s.chats, key{chatID: chatID, userID: old.UserID})
+	return true, s.saveLocked()
+}
+
+// Expire удаляет диалоги с истекшим временем ожидания и возвращает их
+// по возрастанию ID чата и пользователя
+func (s *Store) Expire() ([]Expired, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	var expired []Expired
+	for k, st := range s.chats {
+		if !now.Before(st.Expires) {
+			expired = append(expired, Expired{ChatID: k.chatID, State: st})
+			delete(s.chats, k)
+		}
+	}
+	if len(expired) == 0 {
+		return nil, nil
+	}
+	sort.Slice(expired, func(i, j int) bool {
+		if expired[i].ChatID != expired[j].ChatID {
+			return expired[i].ChatID < expired[j].ChatID
+		}
+		return expired[i].State.UserID < expired[j].State.UserID
+	})
+	return expired, s.saveLocked()
+}
+
+// activeLocked возвращает диалог, время ожидания которого не истекло
+func (s *Store) activeLocked(k key) (State, bool) {
+	st, ok := s.chats[k]
+	if !ok || !s.now().Before(st.Expires) {
+		return State{}, false
+	}
+	return st, true
+}
+
+// unchangedLocked сообщает, что диалог пользователя old.UserID активен и находится
+// на том же шаге с тем же временем ожидания, что и old
+func (s *Store) unchangedLocked(chatID int64, old State) bool {
+	cur, ok := s.activeLocked(key{chatID: chatID, userID: old.UserID})
+	return ok && cur.Command == old.Command && cur.Step == old.Step && cur.Expires.Equal(old.Expires)
+}
+
+func (s *Store) saveStateLocked(chatID int64, st State) (State, error) {
+	st.Expires = s.now().Add(s.ttl).UTC()
+	stored := st
+	stored.Values = maps.Clone(st.Values)
+	s.chats[key{chatID: chatID, userID: st.UserID}] = stored
+	return st, s.saveLocked()
+}
+
+func (s *Store) saveLocked() error {
+	if err := fileutil.WriteJSONAtomic(s.path, s.chats); err != nil {
+		return fmt.Errorf("сохранение диалогов: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package conversation
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "conversations.json")
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	s := NewStore(path, 5*time.Minute)
+	s.now = func() time.Time { return now }
+	if err := s.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Save(1, State{Command: "spy", UserID: 7}); err != nil {
+		t.Fatal(err)
+	}
+	st, err := s.Save(2, State{Command: "spy", UserID: 8, Step: 1, Values: map[string]string{"user": "@alice"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !st.Expires.Equal(now.Add(5 * time.Minute)) {
+		t.Fatalf("expires = %s", st.Expires)
+	}
+
+	// Состояние переживает перезапуск
+	loaded := NewStore(path, 5*time.Minute)
+	loaded.now = s.now
+	if err := loaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	got, ok := loaded.Get(2, 8)
+	if !ok || !reflect.DeepEqual(got, st) {
+		t.Fatalf("loaded = %+v, %v, want %+v", got, ok, st)
+	}
+
+	if _, ok, err := loaded.Delete(2, 8); !ok || err != nil {
+		t.Fatalf("delete = %v, %v", ok, err)
+	}
+	if _, ok, _ := loaded.Delete(2, 8); ok {
+		t.Fatal("conversation deleted twice")
+	}
+
+	// Ответ продлевает ожидание, истекшие диалоги не возвращаются и удаляются
+	now = now.Add(4 * time.Minute)
+	if _, err := loaded.Save(3, State{Command: "spy", UserID: 9}); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(time.Minute)
+	if _, ok := loaded.Get(1, 7); ok {
+		t.Fatal("expired conversation returned")
+	}
+	expired, err := loaded.Expire()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(expired) != 1 || expired[0].ChatID != 1 || expired[0].State.Command != "spy" {
+		t.Fatalf("expired = %+v", expired)
+	}
+	if _, ok := loaded.Get(3, 9); !ok {
+		t.Fatal("active conversation expired")
+	}
+	if expired, _ := loaded.Expire(); expired != nil {
+		t.Fatalf("expired twice: %+v", expired)
+	}
+}
+
+func TestStorePerUser(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "conversations.json")
+	s := NewStore(path, 5*time.Minute)
+
+	// Диалоги разных участников одного чата не заменяют друг друга
+	if _, err := s.Save(1, State{Command: "spy", UserID: 7, Step: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Save(1, State{Command: "spy", UserID: 8}); err != nil {
+		t.Fatal(err)
+	}
+	if st, ok := s.Get(1, 7); !ok || st.Step != 1 {
+		t.Fatalf("first user = %+v, %v", st, ok)
+	}
+	if _, ok := s.Get(1, 9); ok {
+		t.Fatal("conversation of another user returned")
+	}
+
+	loaded := NewStore(path, 5*time.Minute)
+	if err := loaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := loaded.Get(1, 8); !ok {
+		t.Fatal("second user conversation was not loaded")
+	}
+}
+
+func TestStoreLegacyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "conversations.json")
+	expires := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	legacy := `{"5": {"command": "spy", "user_id": 7, "step": 1, "expires": "` + expires + `"}}`
+	if err := os.WriteFile(path, []byte(legacy), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Файл с одним диалогом на чат загружается с пользователем из состояния
+	s := NewStore(path, 5*time.Minute)
+	if err := s.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if st, ok := s.Get(5, 7); !ok || st.Step != 1 {
+		t.Fatalf("legacy conversation = %+v, %v", st, ok)
+	}
+}
+
+func TestStoreCompareAndSave(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	s := NewStore(filepath.Join(t.TempDir(), "conversations.json"), 5*time.Minute)
+	s.now = func() time.Time { return now }
+
+	st, err := s.Save(1, State{Command: "spy", UserID: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := st
+	next.Step = 1
+	next.Values = map[string]string{"user": "@alice"}
+	saved, ok, err := s.CompareAndSave(1, st, next)
+	if !ok || err != nil {
+		t.Fatalf("compare and save = %v, %v", ok, err)
+	}
+	// Устаревшее состояние не перезаписывает новый шаг
+	if _, ok, _ := s.CompareAndSave(1, st, next); ok {
+		t.Fatal("stale state saved")
+	}
+	if ok, _ := s.CompareAndDelete(1, st); ok {
+		t.Fatal("stale state deleted")
+	}
+
+	// Истекший диалог не восстанавливается, даже если его еще не удалил Expire
+	now = now.Add(5 * time.Minute)
+	if _, ok, _ := s.CompareAndSave(1, saved, saved); ok {
+		t.Fatal("expired conversation revived")
+	}
+	if ok, _ := s.CompareAndDelete(1, saved); ok {
+		t.Fatal("expired conversation completed")
+	}
+	if expired, _ := s.Expire(); len(expired) != 1 || expired[0].State.Step != 1 {
+		t.Fatalf("expired = %+v", expired)
+	}
+}
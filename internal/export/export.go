@@ -0,0 +1,245 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"telegram-api-with-go/internal/analytics"
+	"telegram-api-with-go/internal/i18n"
+	"telegram-api-with-go/internal/report"
+	"telegram-api-with-go/internal/store"
+)
+
+// Data - что выгружается
+type Data string
+
+const (
+	// DataTransitions - записи истории: смены статуса в том виде, в котором они сохранены
+	DataTransitions Data = "transitions"
+	// DataSessions - сессии в сети, восстановленные по истории
+	DataSessions Data = "sessions"
+)
+
+// Format - формат выгрузки
+type Format string
+
+const (
+	FormatCSV  Format = "csv"
+	FormatJSON Format = "json"
+	// FormatICS - календарь iCalendar, одно событие на сессию в сети
+	FormatICS Format = "ics"
+)
+
+// ErrICSTransitions возвращается при попытке выгрузить переходы в iCalendar
+var ErrICSTransitions = errors.New("формат ics поддерживается только для сессий")
+
+// ErrInvalidRange возвращается, если диапазон дат выгрузки не удалось разобрать
+var ErrInvalidRange = errors.New("некорректный диапазон дат")
+
+// timeLayout - формат времени в CSV и JSON
+const timeLayout = time.RFC3339
+
+// Options - параметры выгрузки
+type Options struct {
+	UserID int64
+	// Label - имя пользователя для названий событий календаря
+	Label  string
+	Data   Data
+	Format Format
+	// From и To задают период [From, To); нулевое значение снимает ограничение
+	From time.Time
+	To   time.Time
+	// Location - часовой пояс времени в выгрузке
+	Location *time.Location
+	// Lang - язык текстов календаря; пустое значение - язык по умолчанию
+	Lang i18n.Lang
+}
+
+// ParseData разбирает вид выгружаемых данных
+func ParseData(s string) (Data, bool) {
+	switch d := Data(s); d {
+	case DataTransitions, DataSessions:
+		return d, true
+	}
+	return "", false
+}
+
+// ParseFormat разбирает формат выгрузки
+func ParseFormat(s string) (Format, bool) {
+	switch f := Format(strings.ToLower(s)); f {
+	case FormatCSV, FormatJSON, FormatICS:
+		return f, true
+	}
+	return "", false
+}
+
+// ParseRange разбирает период выгрузки: диапазон дат YYYY-MM-DD..YYYY-MM-DD
+// включительно в часовом поясе loc или длительность в формате report.ParsePeriod,
+// отсчитанную назад от now. Пустая строка - report.DefaultPeriod.
+func ParseRange(s string, now time.Time, loc *time.Location) (from, to time.Time, err error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	if first, last, ok := strings.Cut(s, ".."); ok {
+		from, err = time.ParseInLocation(time.DateOnly, first, loc)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("%w: дата %q", ErrInvalidRange, first)
+		}
+		to, err = time.ParseInLocation(time.DateOnly, last, loc)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("%w: дата %q", ErrInvalidRange, last)
+		}
+		to = to.AddDate(0, 0, 1)
+		if !from.Before(to) {
+			return time.Time{}, time.Time{}, fmt.Errorf("%w %q", ErrInvalidRange, s)
+		}
+		return from, to, nil
+	}
+
+	period, err := report.ParsePeriod(s)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return now.Add(-period), now, nil
+}
+
+// FileName возвращает имя файла выгрузки
+func FileName(opts Options) string {
+	return fmt.Sprintf("%s_%d.%s", opts.Data, opts.UserID, opts.Format)
+}
+
+// Exporter выгружает историю присутствия из хранилища
+type Exporter struct {
+	store    store.PresenceStore
+	analyzer *analytics.Analyzer
+}
+
+// New создает экспортер поверх хранилища истории
+func New(presenceStore store.PresenceStore) *Exporter {
+	return &Exporter{store: presenceStore, analyzer: analytics.New(presenceStore)}
+}
+
+// Export записывает выгрузку в w
+func (e *Exporter) Export(w io.Writer, opts Options) error {
+	if opts.Location == nil {
+		opts.Location = time.UTC
+	}
+
+	switch opts.Data {
+	case DataTransitions:
+		if opts.Format == FormatICS {
+			return ErrICSTransitions
+		}
+		records, err := e.store.History(opts.UserID, opts.From, opts.To)
+		if err != nil {
+			return fmt.Errorf("чтение истории пользователя %d: %w", opts.UserID, err)
+		}
+		return writeTransitions(w, opts, records)
+	case DataSessions:
+		sessions, err := e.analyzer.Sessions(opts.UserID, opts.From, opts.To)
+		if err != nil {
+			return err
+		}
+		return writeSessions(w, opts, sessions)
+	default:
+		return fmt.Errorf("неизвестный вид данных %q", opts.Data)
+	}
+}
+
+// transition - запись истории в выгрузке
+type transition struct {
+	RecordedAt string `json:"recorded_at"`
+	Status     string `json:"status"`
+	SeenFrom   string `json:"seen_from,omitempty"`
+	SeenTo     string `json:"seen_to,omitempty"`
+	Expires    string `json:"expires,omitempty"`
+}
+
+// session - сессия в выгрузке
+type session struct {
+	Start           string `json:"start"`
+	End             string `json:"end"`
+	DurationSeconds int64  `json:"duration_seconds"`
+	Confidence      string `json:"confidence"`
+	Ongoing         bool   `json:"ongoing,omitempty"`
+}
+
+func writeTransitions(w io.Writer, opts Options, records []store.Record) error {
+	rows := make([]transition, 0, len(records))
+	for _, rec := range records {
+		status := rec.Status()
+		rows = append(rows, transition{
+			RecordedAt: formatTime(rec.RecordedAt, opts.Location),
+			Status:     string(status.Kind),
+			SeenFrom:   formatTime(status.SeenFrom, opts.Location),
+			SeenTo:     formatTime(status.SeenTo, opts.Location),
+			Expires:    formatTime(status.Expires, opts.Location),
+		})
+	}
+
+	switch opts.Format {
+	case FormatJSON:
+		return writeJSON(w, rows)
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"recorded_at", "status", "seen_from", "seen_to", "expires"})
+		for _, r := range rows {
+			cw.Write([]string{r.RecordedAt, r.Status, r.SeenFrom, r.SeenTo, r.Expires})
+		}
+		cw.Flush()
+		return cw.Error()
+	default:
+		return fmt.Errorf("неизвестный формат %q", opts.Format)
+	}
+}
+
+func writeSessions(w io.Writer, opts Options, sessions []analytics.Session) error {
+	if opts.Format == FormatICS {
+		return writeICS(w, opts, sessions)
+	}
+
+	rows := make([]session, 0, len(sessions))
+	for _, s := range sessions {
+		rows = append(rows, session{
+			Start:           formatTime(s.Start, opts.Location),
+			End:             formatTime(s.End, opts.Location),
+			DurationSeconds: int64(s.Duration().Seconds()),
+			Confidence:      string(s.Confidence),
+			Ongoing:         s.Ongoing,
+		})
+	}
+
+	switch opts.Format {
+	case FormatJSON:
+		return writeJSON(w, rows)
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"start", "end", "duration_seconds", "confidence", "ongoing"})
+		for _, r := range rows {
+			cw.Write([]string{r.Start, r.End, strconv.FormatInt(r.DurationSeconds, 10), r.Confidence, strconv.FormatBool(r.Ongoing)})
+		}
+		cw.Flush()
+		return cw.Error()
+	default:
+		return fmt.Errorf("неизвестный формат %q", opts.Format)
+	}
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func formatTime(t time.Time, loc *time.Location) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.In(loc).Format(timeLayout)
+}
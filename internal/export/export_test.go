@@ -0,0 +1,205 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"telegram-api-with-go/internal/i18n"
+	"telegram-api-with-go/internal/presence"
+	"telegram-api-with-go/internal/report"
+	"telegram-api-with-go/internal/store"
+)
+
+var day = time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC)
+
+func at(h, m int) time.Time {
+	return day.Add(time.Duration(h)*time.Hour + time.Duration(m)*time.Minute)
+}
+
+func testExporter(t *testing.T) *Exporter {
+	t.Helper()
+	s, err := store.OpenJSONL(filepath.Join(t.TempDir(), "presence"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rec := range []store.Record{
+		store.NewRecord(1, presence.Status{Kind: presence.KindOnline, SeenFrom: at(9, 0), SeenTo: at(9, 0), Expires: at(9, 5)}, at(9, 0)),
+		store.NewRecord(1, presence.Status{Kind: presence.KindOffline, SeenFrom: at(9, 30), SeenTo: at(9, 30)}, at(9, 30)),
+		store.NewRecord(1, presence.Status{Kind: presence.KindOnline, SeenFrom: at(20, 0), SeenTo: at(20, 0), Expires: at(20, 5)}, at(20, 0)),
+		store.NewRecord(1, presence.Status{Kind: presence.KindOffline, SeenFrom: at(21, 15), SeenTo: at(21, 15)}, at(21, 15)),
+	} {
+		if err := s.Append(rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return New(s)
+}
+
+func TestExportTransitionsCSV(t *testing.T) {
+	moscow := time.FixedZone("MSK", 3*60*60)
+	var buf bytes.Buffer
+	err := testExporter(t).Export(&buf, Options{
+		UserID:   1,
+		Data:     DataTransitions,
+		Format:   FormatCSV,
+		From:     at(9, 0),
+		To:       at(12, 0),
+		Location: moscow,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"recorded_at", "status", "seen_from", "seen_to", "expires"},
+		{"2024-05-10T12:00:00+03:00", "online", "2024-05-10T12:00:00+03:00", "2024-05-10T12:00:00+03:00", "2024-05-10T12:05:00+03:00"},
+		{"2024-05-10T12:30:00+03:00", "offline", "2024-05-10T12:30:00+03:00", "2024-05-10T12:30:00+03:00", ""},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("rows = %q", rows)
+	}
+	for i := range want {
+		if strings.Join(rows[i], ",") != strings.Join(want[i], ",") {
+			t.Errorf("row %d = %q, want %q", i, rows[i], want[i])
+		}
+	}
+}
+
+func TestExportSessionsJSON(t *testing.T) {
+	var buf bytes.Buffer
+	err := testExporter(t).Export(&buf, Options{UserID: 1, Data: DataSessions, Format: FormatJSON, To: at(23, 0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sessions []session
+	if err := json.Unmarshal(buf.Bytes(), &sessions); err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("sessions = %+v", sessions)
+	}
+	if got := sessions[1]; got.Start != "2024-05-10T20:00:00Z" || got.DurationSeconds != 75*60 || got.Confidence != "high" {
+		t.Fatalf("session = %+v", got)
+	}
+}
+
+func TestExportICS(t *testing.T) {
+	var buf bytes.Buffer
+	err := testExporter(t).Export(&buf, Options{
+		UserID:   1,
+		Label:    "@alice (1)",
+		Data:     DataSessions,
+		Format:   FormatICS,
+		To:       at(23, 0),
+		Location: time.UTC,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, line := range strings.Split(buf.String(), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line longer than 75 bytes: %q", line)
+		}
+	}
+
+	got := strings.ReplaceAll(buf.String(), "\r\n ", "")
+	if n := strings.Count(got, "BEGIN:VEVENT\r\n"); n != 2 {
+		t.Fatalf("events = %d:\n%s", n, got)
+	}
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"DTSTART:20240510T200000Z\r\nDTEND:20240510T211500Z\r\n",
+		"SUMMARY:@alice (1) в сети\r\n",
+		"DESCRIPTION:Длительность: 1 ч 15 мин\\, достоверность: high\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("calendar does not contain %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestExportICSLang(t *testing.T) {
+	var buf bytes.Buffer
+	err := testExporter(t).Export(&buf, Options{
+		UserID:   1,
+		Label:    "@alice (1)",
+		Data:     DataSessions,
+		Format:   FormatICS,
+		To:       at(23, 0),
+		Location: time.UTC,
+		Lang:     i18n.English,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := strings.ReplaceAll(buf.String(), "\r\n ", "")
+	for _, want := range []string{
+		"X-WR-CALNAME:Online: @alice (1)\r\n",
+		"SUMMARY:@alice (1) online\r\n",
+		"DESCRIPTION:Duration: 1 h 15 min\\, confidence: high\r\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("calendar does not contain %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestExportICSTransitions(t *testing.T) {
+	err := testExporter(t).Export(&bytes.Buffer{}, Options{UserID: 1, Data: DataTransitions, Format: FormatICS})
+	if !errors.Is(err, ErrICSTransitions) {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	moscow := time.FixedZone("MSK", 3*60*60)
+	from, to, err := ParseRange("2024-05-01..2024-05-31", day, moscow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !from.Equal(time.Date(2024, 5, 1, 0, 0, 0, 0, moscow)) || !to.Equal(time.Date(2024, 6, 1, 0, 0, 0, 0, moscow)) {
+		t.Fatalf("range = %s - %s", from, to)
+	}
+
+	from, to, err = ParseRange("2d", day, moscow)
+	if err != nil || !to.Equal(day) || !from.Equal(day.AddDate(0, 0, -2)) {
+		t.Fatalf("range = %s - %s, %v", from, to, err)
+	}
+
+	for in, want := range map[string]error{
+		"2024-05-31..2024-05-01": ErrInvalidRange,
+		"2024-05..2024-06":       ErrInvalidRange,
+		"month":                  report.ErrInvalidPeriod,
+	} {
+		if _, _, err := ParseRange(in, day, moscow); !errors.Is(err, want) {
+			t.Errorf("ParseRange(%q) = %v, want %v", in, err, want)
+		}
+	}
+}
+
+func TestFoldLine(t *testing.T) {
+	long := "SUMMARY:" + strings.Repeat("сессия ", 20)
+	folded := foldLine(long)
+	for _, line := range strings.Split(folded, "\r\n") {
+		if len(line) > 75 {
+			t.Fatalf("line longer than 75 bytes: %q", line)
+		}
+	}
+	if unfolded := strings.ReplaceAll(folded, "\r\n ", ""); unfolded != long {
+		t.Fatalf("unfolded = %q", unfolded)
+	}
+}
@@ -0,0 +1,80 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"telegram-api-with-go/internal/analytics"
+	"telegram-api-with-go/internal/report"
+)
+
+// icsLayout - время в UTC по RFC 5545
+const icsLayout = "20060102T150405Z"
+
+// writeICS записывает сессии в формате iCalendar (RFC 5545), одно событие на сессию.
+// Время событий указывается в UTC, а часовой пояс выгрузки передается календарю
+// в X-WR-TIMEZONE. Тексты событий выводятся на языке opts.Lang.
+func writeICS(w io.Writer, opts Options, sessions []analytics.Session) error {
+	label := opts.Label
+	if label == "" {
+		label = fmt.Sprint(opts.UserID)
+	}
+
+	bw := bufio.NewWriter(w)
+	line := func(format string, args ...any) {
+		bw.WriteString(foldLine(fmt.Sprintf(format, args...)))
+		bw.WriteString("\r\n")
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//telegram-api-with-go//presence export//RU")
+	line("CALSCALE:GREGORIAN")
+	line("X-WR-CALNAME:%s", escapeText(opts.Lang.T("export.ics.calendar", label)))
+	line("X-WR-TIMEZONE:%s", opts.Location)
+	for _, s := range sessions {
+		line("BEGIN:VEVENT")
+		line("UID:%d-%d@presence", opts.UserID, s.Start.Unix())
+		line("DTSTAMP:%s", s.End.UTC().Format(icsLayout))
+		line("DTSTART:%s", s.Start.UTC().Format(icsLayout))
+		line("DTEND:%s", s.End.UTC().Format(icsLayout))
+		line("SUMMARY:%s", escapeText(opts.Lang.T("export.ics.summary", label)))
+		description := opts.Lang.T("export.ics.description", report.FormatDuration(opts.Lang, s.Duration()), s.Confidence)
+		if s.Ongoing {
+			description += opts.Lang.T("export.ics.ongoing")
+		}
+		line("DESCRIPTION:%s", escapeText(description))
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+	return bw.Flush()
+}
+
+// escapeText экранирует значение типа TEXT
+func escapeText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(s)
+}
+
+// foldLine переносит строки длиннее 75 байт, не разрывая символы UTF-8
+func foldLine(s string) string {
+	const limit = 75
+	if len(s) <= limit {
+		return s
+	}
+
+	var sb strings.Builder
+	n := 0
+	for _, r := range s {
+		size := len(string(r))
+		if n+size > limit {
+			sb.WriteString("\r\n ")
+			// Пробел в начале строки продолжения занимает один байт
+			n = 1
+		}
+		sb.WriteRune(r)
+		n += size
+	}
+	return sb.String()
+}
@@ -0,0 +1,62 @@
+package fileutil
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteJSONAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+
+	for _, want := range []map[string]int{{"a": 1}, {"b": 2}} {
+		if err := WriteJSONAtomic(path, want); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got map[string]int
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 1 || got["a"] != want["a"] || got["b"] != want["b"] {
+			t.Fatalf("file = %s, want %v", data, want)
+		}
+	}
+
+	// Временные файлы не остаются в каталоге
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("dir entries = %v", entries)
+	}
+}
+
+func TestWriteJSONAtomicKeepsPreviousFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := WriteJSONAtomic(path, []int{1}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Ошибка сериализации не затрагивает сохраненный файл
+	if err := WriteJSONAtomic(path, make(chan int)); err == nil {
+		t.Fatal("expected marshal error")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "[\n  1\n]" {
+		t.Fatalf("file = %q", data)
+	}
+
+	if err := WriteJSONAtomic(filepath.Join(t.TempDir(), "missing", "state.json"), 1); err == nil {
+		t.Fatal("expected error for missing directory")
+	}
+}
@@ -0,0 +1,168 @@
+package i18n
+
+// en - каталог сообщений на английском языке
+var en = map[string]Message{
+	// Общие ответы
+	"error":                 {Other: "Error: %v"},
+	"error.no_store":        {Other: "Error: status history storage is not configured."},
+	"error.internal":        {Other: "Internal error while handling the request. Please try again later."},
+	"error.bad_period":      {Other: "Error: invalid period. Examples: 7d, 2w, 12h."},
+	"error.period_too_long": {Other: "Error: the period is longer than the maximum (%d days)."},
+	"error.bad_range":       {Other: "Error: invalid date range. Example: 2024-05-01..2024-05-31."},
+	"error.ics_transitions": {Other: "Error: the ics format is only supported for sessions."},
+	"error.unknown_arg":     {Other: "Error: unknown argument or time zone %q."},
+	"usage":                 {Other: "Usage: %s"},
+	"usage.error":           {Other: "Error: %s\nUsage: %s"},
+	"command.unknown":       {Other: "Unknown command. List of commands: /help"},
+	"command.forbidden":     {Other: "You are not allowed to use /%s."},
+	"user.not_found":        {Other: "User %s not found."},
+	"user.not_tracked":      {Other: "You are not tracking user %s."},
+	"reply.document":        {Other: "The reply is too long for a message and was sent as a file."},
+
+	// Справка и описания команд
+	"help.header":          {Other: "Available commands:"},
+	"help.aliases":         {Other: " (also /%s)"},
+	"command.help":         {Other: "list of commands"},
+	"command.spy":          {Other: "start tracking a user; the interval sets how often the status is checked"},
+	"command.unspy":        {Other: "stop tracking a user in this chat"},
+	"command.tracked":      {Other: "users tracked by this chat"},
+	"command.pause":        {Other: "pause tracking a user"},
+	"command.resume":       {Other: "resume tracking a user"},
+	"command.notify":       {Other: "status change notifications: quiet hours and daily digest"},
+	"command.report":       {Other: "presence summary and chart for a period (7d, 2w, 12h)"},
+	"command.export":       {Other: "export history as CSV, JSON or iCalendar"},
+	"command.allow":        {Other: "consent to being tracked"},
+	"command.deny":         {Other: "withdraw consent; with delete the history is deleted too"},
+	"command.chats":        {Other: "chats of the account"},
+	"command.members":      {Other: "members of a group or channel as CSV"},
+	"command.status":       {Other: "Telegram connection state"},
+	"command.grant":        {Other: "grant a role to a user"},
+	"command.revoke":       {Other: "revoke a user's role"},
+	"command.roles":        {Other: "bot users and their roles"},
+	"command.lang":         {Other: "reply language"},
+	"command.cancel":       {Other: "cancel the current command"},
+	"arg.user":             {Other: "user"},
+	"arg.interval":         {Other: "interval"},
+	"arg.setting":          {Other: "setting"},
+	"arg.value":            {Other: "value"},
+	"arg.period":           {Other: "period"},
+	"arg.options":          {Other: "options"},
+	"arg.chat":             {Other: "chat"},
+	"arg.filter":           {Other: "filter"},
+	"arg.role":             {Other: "role"},
+	"arg.language":         {Other: "language"},
+	"arg.error.quote":      {Other: "unclosed quote"},
+	"arg.error.missing":    {Other: "missing argument %s"},
+	"arg.error.too_many":   {Other: "too many arguments"},
+	"arg.error.user":       {Other: "invalid argument %s: %q (expected @username or ID)"},
+	"arg.error.int":        {Other: "invalid argument %s: %q (expected an integer)"},
+	"arg.error.duration":   {Other: "invalid argument %s: %q (expected a duration such as 30s or 2m)"},
+	"arg.error.choice":     {Other: "invalid argument %s: %q (expected %s)"},
+	"callback.expired":     {Other: "This button has expired, please repeat the command."},
+	"callback.invalid":     {Other: "Invalid button."},
+	"callback.forbidden":   {Other: "Not allowed."},
+	"client.ready":         {Other: "Telegram client is connected."},
+	"client.starting":      {Other: "Telegram client is connecting..."},
+	"client.reconnecting":  {Other: "Connection to Telegram lost, reconnecting in %s (attempt %d).\nLast error: %v"},
+	"client.failed":        {Other: "Telegram client stopped due to an unrecoverable error: %v\nAn administrator needs to intervene."},
+	"client.stopped":       {Other: "Telegram client is stopped."},
+	"client.unavailable":   {Other: "%s\nThe command will be available once the connection is restored."},
+	"status.transitions":   {Other: "%s\nStatus transitions: events %d, polling %d, online expiry %d (events received %d, polls %d)."},
+	"members.requesting":   {Other: "Fetching the member list..."},
+	"members.not_admin":    {Other: "Denied: the account is not an administrator of this chat."},
+	"members.not_found":    {Other: "Chat not found among the account's dialogs."},
+	"members.caption":      {One: "%s: %d member (%s)", Other: "%s: %d members (%s)"},
+	"chats.requesting":     {Other: "Fetching the chat list..."},
+	"chats.title":          {Other: "Chats"},
+	"chats.page":           {Other: " (page %d of %d)"},
+	"chats.empty":          {Other: "No chats found."},
+	"chats.gone":           {Other: "The chat is no longer in the dialog list."},
+	"chats.prev":           {Other: "« Back"},
+	"chats.next":           {Other: "Next »"},
+	"chats.back":           {Other: "« To the list"},
+	"chats.group":          {Other: "Group: %s"},
+	"chats.group.banned":   {Other: "Forbidden group: %s"},
+	"chats.banned":         {Other: "The account was removed from the group or the group is unavailable."},
+	"chats.members":        {Other: "Members: %d"},
+	"chats.created":        {Other: "Created: %s"},
+	"spy.bad_interval":     {Other: "Invalid polling interval %s. The minimum interval is 1s."},
+	"spy.no_consent":       {Other: "User %s has not consented to being tracked. Tracking becomes possible once the user sends the /allow command to the bot."},
+	"spy.already":          {Other: "You are already tracking user %s."},
+	"spy.started":          {Other: "You are now tracking user %s."},
+	"unspy.done":           {Other: "You are no longer tracking user %s."},
+	"tracked.empty":        {Other: "You are not tracking anyone. Add a user with /spy <@username|ID>."},
+	"tracked.header":       {Other: "Tracked users:"},
+	"tracked.poll":         {Other: ", polled every %s"},
+	"tracked.subscribers":  {One: ", %d subscribed chat", Other: ", %d subscribed chats"},
+	"tracker.running":      {Other: "tracking"},
+	"tracker.paused":       {Other: "paused"},
+	"tracker.stopped":      {Other: "stopped"},
+	"tracker.poll_error":   {Other: " (polling error: %v)"},
+	"pause.done":           {Other: "Tracking of user %s is paused."},
+	"resume.done":          {Other: "Tracking of user %s is resumed."},
+	"report.chart_error":   {Other: "Failed to build the chart: %v"},
+	"export.hint":          {Other: "Options in any order: sessions|transitions, csv|json|ics, a period (7d, 2w, 12h or 2024-05-01..2024-05-31) and a time zone. Example: /export @alice sessions ics 30d Europe/Moscow"},
+	"notify.usage":         {Other: "Usage:\n/notify - current settings\n/notify quiet <from-to>|off - quiet hours, for example /notify quiet 23-7\n/notify digest on|off - a daily digest instead of a notification for every transition"},
+	"notify.bad_quiet":     {Other: "Invalid quiet hours %q. Example: 23-7."},
+	"notify.header":        {Other: "Notification settings:"},
+	"notify.quiet":         {Other: "Quiet hours: %02d:00-%02d:00 (%s)"},
+	"notify.quiet.off":     {Other: "Quiet hours: not set"},
+	"notify.digest":        {Other: "Daily digest at %02d:00 instead of a notification for every transition"},
+	"notify.digest.off":    {Other: "A notification for every transition"},
+	"allow.already":        {Other: "You have already consented to being tracked. To withdraw consent: /deny, or /deny delete to also delete the history."},
+	"allow.done":           {Other: "Your consent to being tracked is recorded. To withdraw it: /deny, or /deny delete to also delete the history."},
+	"deny.done":            {Other: "Consent withdrawn, tracking stopped."},
+	"deny.not_given":       {Other: "You had not consented to being tracked, nobody is tracking you."},
+	"deny.subscriber":      {Other: "User %s withdrew consent, tracking stopped."},
+	"deny.history_deleted": {Other: "Status history deleted."},
+	"deny.history_error":   {Other: "Failed to delete the history: %v"},
+	"roles.owner":          {Other: "User %s is a bot owner, their role is set in BOT_OWNER_IDS."},
+	"roles.header":         {Other: "User roles:"},
+	"grant.done":           {Other: "User %s is granted the %s role."},
+	"revoke.done":          {Other: "The role of user %s is revoked."},
+	"revoke.none":          {Other: "User %s has no role."},
+	"lang.chosen":          {Other: "Reply language: %s (chosen with /lang)."},
+	"lang.auto":            {Other: "Reply language: %s (from Telegram settings)."},
+	"lang.usage":           {Other: "Choose a language: /lang ru|en\nUse the language from Telegram settings: /lang auto"},
+	"lang.set":             {Other: "Reply language: %s."},
+	"lang.reset":           {Other: "The reply language follows Telegram settings again: %s."},
+
+	// Многошаговые команды
+	"conversation.cancel_hint": {Other: "Cancel: /cancel"},
+	"conversation.cancelled":   {Other: "The /%s command was cancelled."},
+	"conversation.none":        {Other: "Nothing to cancel: the bot is not waiting for your answer."},
+	"conversation.timeout":     {Other: "No answer in time, the /%s command was cancelled."},
+	"spy.ask.user":             {Other: "Whom should I track? Send a @username or user ID."},
+	"spy.ask.notify":           {Other: "How should status changes be reported in this chat?\nall - every transition\ndigest - a daily digest\n<from>-<to> - quiet hours, e.g. 23-7\nkeep - keep the current settings"},
+	"spy.bad_user":             {Other: "%q does not look like a @username or user ID."},
+	"spy.bad_notify":           {Other: "Unrecognized answer %q."},
+
+	// Отчеты и уведомления
+	"report.title":           {Other: "Report for user %s"},
+	"report.period":          {Other: "Period: %s - %s (%s)"},
+	"report.empty":           {Other: "No activity recorded for the period."},
+	"report.online":          {Other: "Time online: %s (%s per day on average)"},
+	"report.sessions":        {Other: "Sessions: %d"},
+	"report.first_seen":      {Other: "First seen: %s"},
+	"report.last_seen":       {Other: "Last seen: %s"},
+	"report.longest":         {Other: "Longest session: %s, %s"},
+	"report.peak":            {Other: "Most often online: %02d:00-%02d:00"},
+	"report.low_confidence":  {Other: "Sessions of unknown duration: %d"},
+	"duration.minutes":       {Other: "%d min"},
+	"duration.hours":         {Other: "%d h"},
+	"duration.hours_minutes": {Other: "%d h %d min"},
+	"export.ics.calendar":    {Other: "Online: %s"},
+	"export.ics.summary":     {Other: "%s online"},
+	"export.ics.description": {Other: "Duration: %s, confidence: %s"},
+	"export.ics.ongoing":     {Other: ", session in progress"},
+	"digest.header":          {Other: "Status transitions digest:"},
+	"digest.more":            {Other: "... and %d more"},
+	"presence.online":        {Other: "online"},
+	"presence.offline":       {Other: "offline"},
+	"presence.offline_at":    {Other: "went offline at %s"},
+	"presence.recently":      {Other: "last seen recently"},
+	"presence.last_week":     {Other: "last seen within a week"},
+	"presence.last_month":    {Other: "last seen within a month"},
+	"presence.hidden":        {Other: "status hidden"},
+	"presence.unknown":       {Other: "status unknown"},
+}
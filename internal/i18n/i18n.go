@@ -0,0 +1,185 @@
+// Package i18n содержит каталог сообщений бота на поддерживаемых языках,
+// правила множественного числа и сохраненный выбор языка пользователей.
+package i18n
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Lang - язык сообщений бота
+type Lang string
+
+const (
+	// Russian - русский язык
+	Russian Lang = "ru"
+	// English - английский язык
+	English Lang = "en"
+)
+
+// Default - язык для пользователей, язык которых не поддерживается
+const Default = Russian
+
+// Message - сообщение каталога. Обычные сообщения задают только Other.
+// Сообщения с числом задают формы, которые требует язык: для русского
+// One (1, 21), Few (2-4, 22) и Many (5-20, 0), для английского One и Other.
+type Message struct {
+	One   string
+	Few   string
+	Many  string
+	Other string
+}
+
+// Plural сообщает, задано ли сообщение формами множественного числа
+func (m Message) Plural() bool {
+	return m.One != "" || m.Few != "" || m.Many != ""
+}
+
+// pluralForm - форма множественного числа
+type pluralForm int
+
+const (
+	formOne pluralForm = iota
+	formFew
+	formMany
+	formOther
+)
+
+// locale - каталог и правило множественного числа языка
+type locale struct {
+	name     string
+	messages map[string]Message
+	plural   func(n int) pluralForm
+	// forms - формы, которые должны быть заданы у сообщений с числом
+	forms []pluralForm
+}
+
+var locales = map[Lang]locale{
+	Russian: {name: "Русский", messages: ru, plural: russianPlural, forms: []pluralForm{formOne, formFew, formMany}},
+	English: {name: "English", messages: en, plural: englishPlural, forms: []pluralForm{formOne, formOther}},
+}
+
+// Langs возвращает поддерживаемые языки; первым идет язык по умолчанию
+func Langs() []Lang {
+	return []Lang{Russian, English}
+}
+
+// Parse возвращает поддерживаемый язык по коду вида ru, en или en-US
+func Parse(code string) (Lang, bool) {
+	base, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(code)), "-")
+	lang := Lang(base)
+	_, ok := locales[lang]
+	return lang, ok
+}
+
+// FromCode возвращает язык по коду языка Telegram (User.LanguageCode).
+// Неподдерживаемые и пустые коды дают язык по умолчанию.
+func FromCode(code string) Lang {
+	if lang, ok := Parse(code); ok {
+		return lang
+	}
+	return Default
+}
+
+// Name возвращает название языка на нем самом
+func (l Lang) Name() string {
+	return l.locale().name
+}
+
+func (l Lang) locale() locale {
+	if loc, ok := locales[l]; ok {
+		return loc
+	}
+	return locales[Default]
+}
+
+// Has сообщает, есть ли ключ key в каталоге языка
+func (l Lang) Has(key string) bool {
+	_, ok := l.locale().messages[key]
+	return ok
+}
+
+// Plural сообщает, задает ли сообщение key формы для числа и должно выводиться через N
+func (l Lang) Plural(key string) bool {
+	msg := l.locale().messages[key]
+	return msg.One != "" || msg.Few != "" || msg.Many != ""
+}
+
+// T возвращает сообщение key, отформатированное с аргументами args.
+// Ключ без перевода возвращается как есть, поэтому в каталог можно не выносить
+// строки, заданные в тестах. Для сообщения с числом возвращается форма для
+// неопределенного количества, как N с нулем.
+func (l Lang) T(key string, args ...any) string {
+	msg, ok := l.locale().messages[key]
+	if !ok {
+		return format(key, args)
+	}
+	if msg.Other == "" {
+		return l.N(key, 0, args...)
+	}
+	return format(msg.Other, args)
+}
+
+// N возвращает форму сообщения key для числа n, отформатированную с аргументами args.
+// Число не добавляется в аргументы автоматически: его передают в args там, где оно нужно в тексте.
+func (l Lang) N(key string, n int, args ...any) string {
+	loc := l.locale()
+	msg, ok := loc.messages[key]
+	if !ok {
+		return format(key, args)
+	}
+	text := msg.Other
+	switch loc.plural(n) {
+	case formOne:
+		text = msg.One
+	case formFew:
+		text = msg.Few
+	case formMany:
+		text = msg.Many
+	}
+	if text == "" {
+		text = msg.Other
+	}
+	return format(text, args)
+}
+
+func format(text string, args []any) string {
+	if len(args) == 0 {
+		return text
+	}
+	return fmt.Sprintf(text, args...)
+}
+
+// russianPlural - правило множественного числа русского языка:
+// 1 пользователь, 2 пользователя, 5 пользователей, 21 пользователь
+func russianPlural(n int) pluralForm {
+	if n < 0 {
+		n = -n
+	}
+	switch {
+	case n%10 == 1 && n%100 != 11:
+		return formOne
+	case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+		return formFew
+	default:
+		return formMany
+	}
+}
+
+// englishPlural - правило множественного числа английского языка: 1 user, 2 users
+func englishPlural(n int) pluralForm {
+	if n == 1 {
+		return formOne
+	}
+	return formOther
+}
+
+// Keys возвращает ключи каталога языка
+func (l Lang) Keys() []string {
+	messages := l.locale().messages
+	keys := make([]string, 0, len(messages))
+	for key := range messages {
+		keys = append(keys, key)
+	}
+	return keys
+}
@@ -0,0 +1,147 @@
+package i18n
+
+import (
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+// verbPattern находит директивы форматирования, кроме %%
+var verbPattern = regexp.MustCompile(`%[-+# 0-9.\[\]]*[a-zA-Z]`)
+
+// TestCatalogComplete падает, если ключ есть в одном каталоге и отсутствует в другом,
+// если у сообщения с числом нет нужной языку формы или переводы ожидают разные аргументы
+func TestCatalogComplete(t *testing.T) {
+	keys := make(map[string]bool)
+	for _, lang := range Langs() {
+		for _, key := range lang.Keys() {
+			keys[key] = true
+		}
+	}
+	if len(keys) == 0 {
+		t.Fatal("catalog is empty")
+	}
+
+	for key := range keys {
+		var (
+			want []string
+			seen bool
+		)
+		for _, lang := range Langs() {
+			loc := locales[lang]
+			msg, ok := loc.messages[key]
+			if !ok {
+				t.Errorf("%s: key %q is missing", lang, key)
+				continue
+			}
+
+			texts := []string{msg.Other}
+			if msg.Plural() {
+				texts = texts[:0]
+				for _, form := range loc.forms {
+					text := [...]string{formOne: msg.One, formFew: msg.Few, formMany: msg.Many, formOther: msg.Other}[form]
+					if text == "" {
+						t.Errorf("%s: key %q has no plural form %d", lang, key, form)
+					}
+					texts = append(texts, text)
+				}
+			} else if msg.Other == "" {
+				t.Errorf("%s: key %q is empty", lang, key)
+			}
+
+			for _, text := range texts {
+				verbs := verbPattern.FindAllString(text, -1)
+				if !seen {
+					want, seen = verbs, true
+					continue
+				}
+				if !reflect.DeepEqual(verbs, want) {
+					t.Errorf("%s: key %q expects arguments %v, want %v", lang, key, verbs, want)
+				}
+			}
+		}
+	}
+}
+
+func TestPlural(t *testing.T) {
+	for _, tc := range []struct {
+		lang Lang
+		n    int
+		want string
+	}{
+		{Russian, 1, ", 1 подписанный чат"},
+		{Russian, 2, ", 2 подписанных чата"},
+		{Russian, 5, ", 5 подписанных чатов"},
+		{Russian, 11, ", 11 подписанных чатов"},
+		{Russian, 12, ", 12 подписанных чатов"},
+		{Russian, 21, ", 21 подписанный чат"},
+		{Russian, 22, ", 22 подписанных чата"},
+		{Russian, 111, ", 111 подписанных чатов"},
+		{Russian, 0, ", 0 подписанных чатов"},
+		{English, 1, ", 1 subscribed chat"},
+		{English, 2, ", 2 subscribed chats"},
+		{English, 0, ", 0 subscribed chats"},
+	} {
+		if got := tc.lang.N("tracked.subscribers", tc.n, tc.n); got != tc.want {
+			t.Errorf("%s.N(%d) = %q, want %q", tc.lang, tc.n, got, tc.want)
+		}
+	}
+
+	// T не возвращает пустую строку для сообщения с числом
+	if got := Russian.T("tracked.subscribers", 3); got != ", 3 подписанных чатов" {
+		t.Errorf("T on plural key = %q", got)
+	}
+	if !Russian.Plural("tracked.subscribers") || !English.Plural("tracked.subscribers") || Russian.Plural("error") {
+		t.Error("Plural does not match the catalog")
+	}
+}
+
+func TestLookup(t *testing.T) {
+	if got := English.T("user.not_found", "@alice"); got != "User @alice not found." {
+		t.Errorf("English.T = %q", got)
+	}
+	if got := Lang("de").T("user.not_found", "@alice"); got != "Пользователь @alice не найден." {
+		t.Errorf("unsupported language must fall back to the default, got %q", got)
+	}
+	if got := English.T("не ключ"); got != "не ключ" {
+		t.Errorf("missing key must be returned as is, got %q", got)
+	}
+
+	for code, want := range map[string]Lang{"en": English, "en-US": English, "RU": Russian, "de": Default, "": Default} {
+		if got := FromCode(code); got != want {
+			t.Errorf("FromCode(%q) = %q, want %q", code, got, want)
+		}
+	}
+}
+
+func TestPreferences(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "languages.json")
+	p := NewPreferences(path)
+	if err := p.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := p.Get(1); ok {
+		t.Fatal("empty preferences returned a language")
+	}
+	if err := p.Set(1, English); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Set(2, Russian); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Reset(2); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := NewPreferences(path)
+	if err := loaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if lang, ok := loaded.Get(1); !ok || lang != English {
+		t.Fatalf("loaded language = %q, %v", lang, ok)
+	}
+	if _, ok := loaded.Get(2); ok {
+		t.Fatal("reset language was persisted")
+	}
+}
@@ -0,0 +1,81 @@
+package i18n
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"telegram-api-with-go/internal/fileutil"
+)
+
+// Preferences хранит языки, выбранные пользователями командой /lang, в JSON файле
+type Preferences struct {
+	path string
+
+	mu    sync.Mutex
+	users map[int64]Lang
+}
+
+// NewPreferences создает хранилище выбранных языков с файлом path
+func NewPreferences(path string) *Preferences {
+	return &Preferences{path: path, users: make(map[int64]Lang)}
+}
+
+// Load загружает выбранные языки из файла. Отсутствие файла не является ошибкой.
+func (p *Preferences) Load() error {
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("чтение выбранных языков: %w", err)
+	}
+
+	users := make(map[int64]Lang)
+	if err := json.Unmarshal(data, &users); err != nil {
+		return fmt.Errorf("разбор выбранных языков %s: %w", p.path, err)
+	}
+	// Язык, поддержка которого удалена, считается не выбранным
+	for userID, lang := range users {
+		if _, ok := locales[lang]; !ok {
+			delete(users, userID)
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.users = users
+	return nil
+}
+
+// Get возвращает язык, выбранный пользователем
+func (p *Preferences) Get(userID int64) (Lang, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	lang, ok := p.users[userID]
+	return lang, ok
+}
+
+// Set сохраняет язык пользователя
+func (p *Preferences) Set(userID int64, lang Lang) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.users[userID] = lang
+	return p.saveLocked()
+}
+
+// Reset удаляет выбранный язык: язык снова определяется настройками Telegram
+func (p *Preferences) Reset(userID int64) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.users, userID)
+	return p.saveLocked()
+}
+
+func (p *Preferences) saveLocked() error {
+	if err := fileutil.WriteJSONAtomic(p.path, p.users); err != nil {
+		return fmt.Errorf("сохранение выбранных языков: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package i18n
+
+// ru - каталог сообщений на русском языке
+var ru = map[string]Message{
+	// Общие ответы
+	"error":                 {Other: "Ошибка: %v"},
+	"error.no_store":        {Other: "Ошибка: хранилище истории не настроено."},
+	"error.internal":        {Other: "Внутренняя ошибка при обработке запроса. Попробуйте еще раз позже."},
+	"error.bad_period":      {Other: "Ошибка: некорректный период. Примеры: 7d, 2w, 12h."},
+	"error.period_too_long": {Other: "Ошибка: период больше максимального (%d дней)."},
+	"error.bad_range":       {Other: "Ошибка: некорректный диапазон дат. Пример: 2024-05-01..2024-05-31."},
+	"error.ics_transitions": {Other: "Ошибка: формат ics поддерживается только для сессий."},
+	"error.unknown_arg":     {Other: "Ошибка: неизвестный аргумент или часовой пояс %q."},
+	"usage":                 {Other: "Использование: %s"},
+	"usage.error":           {Other: "Ошибка: %s\nИспользование: %s"},
+	"command.unknown":       {Other: "Неизвестная команда. Список команд: /help"},
+	"command.forbidden":     {Other: "Недостаточно прав для команды /%s."},
+	"user.not_found":        {Other: "Пользователь %s не найден."},
+	"user.not_tracked":      {Other: "Вы не следите за пользователем %s."},
+	"reply.document":        {Other: "Ответ слишком длинный для сообщения и отправлен файлом."},
+
+	// Справка и описания команд
+	"help.header":          {Other: "Доступные команды:"},
+	"help.aliases":         {Other: " (также /%s)"},
+	"command.help":         {Other: "список команд"},
+	"command.spy":          {Other: "начать отслеживание пользователя; интервал задает частоту сверки статуса"},
+	"command.unspy":        {Other: "прекратить отслеживание пользователя в этом чате"},
+	"command.tracked":      {Other: "пользователи, за которыми следит чат"},
+	"command.pause":        {Other: "приостановить слежение за пользователем"},
+	"command.resume":       {Other: "возобновить слежение за пользователем"},
+	"command.notify":       {Other: "уведомления о сменах статуса: часы тишины и ежедневная сводка"},
+	"command.report":       {Other: "сводка присутствия и график за период (7d, 2w, 12h)"},
+	"command.export":       {Other: "выгрузка истории в CSV, JSON или iCalendar"},
+	"command.allow":        {Other: "дать согласие на отслеживание себя"},
+	"command.deny":         {Other: "отозвать согласие; с delete удаляется и история"},
+	"command.chats":        {Other: "список чатов аккаунта"},
+	"command.members":      {Other: "участники группы или канала в CSV"},
+	"command.status":       {Other: "состояние подключения к Telegram"},
+	"command.grant":        {Other: "выдать пользователю роль"},
+	"command.revoke":       {Other: "отозвать роль пользователя"},
+	"command.roles":        {Other: "пользователи бота и их роли"},
+	"command.lang":         {Other: "язык ответов бота"},
+	"command.cancel":       {Other: "отменить начатую команду"},
+	"arg.user":             {Other: "пользователь"},
+	"arg.interval":         {Other: "интервал"},
+	"arg.setting":          {Other: "настройка"},
+	"arg.value":            {Other: "значение"},
+	"arg.period":           {Other: "период"},
+	"arg.options":          {Other: "параметры"},
+	"arg.chat":             {Other: "чат"},
+	"arg.filter":           {Other: "фильтр"},
+	"arg.role":             {Other: "роль"},
+	"arg.language":         {Other: "язык"},
+	"arg.error.quote":      {Other: "не закрыта кавычка"},
+	"arg.error.missing":    {Other: "не указан аргумент %s"},
+	"arg.error.too_many":   {Other: "слишком много аргументов"},
+	"arg.error.user":       {Other: "некорректный аргумент %s: %q (ожидается @username или ID)"},
+	"arg.error.int":        {Other: "некорректный аргумент %s: %q (ожидается целое число)"},
+	"arg.error.duration":   {Other: "некорректный аргумент %s: %q (ожидается длительность, например 30s или 2m)"},
+	"arg.error.choice":     {Other: "некорректный аргумент %s: %q (ожидается %s)"},
+	"callback.expired":     {Other: "Кнопка устарела, повторите команду."},
+	"callback.invalid":     {Other: "Некорректная кнопка."},
+	"callback.forbidden":   {Other: "Недостаточно прав."},
+	"client.ready":         {Other: "Telegram клиент подключен."},
+	"client.starting":      {Other: "Telegram клиент подключается..."},
+	"client.reconnecting":  {Other: "Соединение с Telegram потеряно, переподключение через %s (попытка %d).\nПоследняя ошибка: %v"},
+	"client.failed":        {Other: "Telegram клиент остановлен из-за неустранимой ошибки: %v\nТребуется вмешательство администратора."},
+	"client.stopped":       {Other: "Telegram клиент остановлен."},
+	"client.unavailable":   {Other: "%s\nКоманда будет доступна после восстановления соединения."},
+	"status.transitions":   {Other: "%s\nПереходы статуса: события %d, опрос %d, истечение online %d (получено событий %d, опросов %d)."},
+	"members.requesting":   {Other: "Запрашиваю список участников..."},
+	"members.not_admin":    {Other: "Отказано: аккаунт не является администратором этого чата."},
+	"members.not_found":    {Other: "Чат не найден среди диалогов аккаунта."},
+	"members.caption":      {One: "%s: %d участник (%s)", Few: "%s: %d участника (%s)", Many: "%s: %d участников (%s)"},
+	"chats.requesting":     {Other: "Запрашиваю список чатов..."},
+	"chats.title":          {Other: "Список чатов"},
+	"chats.page":           {Other: " (страница %d из %d)"},
+	"chats.empty":          {Other: "Чаты не найдены."},
+	"chats.gone":           {Other: "Чат больше не найден в списке диалогов."},
+	"chats.prev":           {Other: "« Назад"},
+	"chats.next":           {Other: "Вперед »"},
+	"chats.back":           {Other: "« К списку"},
+	"chats.group":          {Other: "Группа: %s"},
+	"chats.group.banned":   {Other: "Запрещенная группа: %s"},
+	"chats.banned":         {Other: "Аккаунт исключен из группы или группа недоступна."},
+	"chats.members":        {Other: "Участников: %d"},
+	"chats.created":        {Other: "Создана: %s"},
+	"spy.bad_interval":     {Other: "Некорректный интервал опроса %s. Минимальный интервал - 1s."},
+	"spy.no_consent":       {Other: "Пользователь %s не давал согласия на отслеживание. Слежение станет возможным, когда пользователь сам отправит боту команду /allow."},
+	"spy.already":          {Other: "Вы уже следите за пользователем %s."},
+	"spy.started":          {Other: "Теперь вы следите за пользователем %s."},
+	"unspy.done":           {Other: "Вы больше не следите за пользователем %s."},
+	"tracked.empty":        {Other: "Вы ни за кем не следите. Добавьте пользователя командой /spy <@username|ID>."},
+	"tracked.header":       {Other: "Отслеживаемые пользователи:"},
+	"tracked.poll":         {Other: ", опрос каждые %s"},
+	"tracked.subscribers":  {One: ", %d подписанный чат", Few: ", %d подписанных чата", Many: ", %d подписанных чатов"},
+	"tracker.running":      {Other: "отслеживается"},
+	"tracker.paused":       {Other: "приостановлен"},
+	"tracker.stopped":      {Other: "остановлен"},
+	"tracker.poll_error":   {Other: " (ошибка опроса: %v)"},
+	"pause.done":           {Other: "Слежение за пользователем %s приостановлено."},
+	"resume.done":          {Other: "Слежение за пользователем %s возобновлено."},
+	"report.chart_error":   {Other: "Ошибка построения графика: %v"},
+	"export.hint":          {Other: "Параметры в любом порядке: sessions|transitions, csv|json|ics, период (7d, 2w, 12h или 2024-05-01..2024-05-31) и часовой пояс. Пример: /export @alice sessions ics 30d Europe/Moscow"},
+	"notify.usage":         {Other: "Использование:\n/notify - текущие настройки\n/notify quiet <с-до>|off - часы тишины, например /notify quiet 23-7\n/notify digest on|off - ежедневная сводка вместо уведомлений о каждом переходе"},
+	"notify.bad_quiet":     {Other: "Некорректные часы тишины %q. Пример: 23-7."},
+	"notify.header":        {Other: "Настройки уведомлений:"},
+	"notify.quiet":         {Other: "Часы тишины: %02d:00-%02d:00 (%s)"},
+	"notify.quiet.off":     {Other: "Часы тишины: не заданы"},
+	"notify.digest":        {Other: "Ежедневная сводка в %02d:00 вместо уведомлений о каждом переходе"},
+	"notify.digest.off":    {Other: "Уведомления о каждом переходе"},
+	"allow.already":        {Other: "Вы уже дали согласие на отслеживание. Отозвать его: /deny или /deny delete с удалением истории."},
+	"allow.done":           {Other: "Согласие на отслеживание записано. Отозвать его: /deny или /deny delete с удалением истории."},
+	"deny.done":            {Other: "Согласие отозвано, слежение прекращено."},
+	"deny.not_given":       {Other: "Согласие на отслеживание не было дано, слежение не ведется."},
+	"deny.subscriber":      {Other: "Пользователь %s отозвал согласие, слежение прекращено."},
+	"deny.history_deleted": {Other: "История статусов удалена."},
+	"deny.history_error":   {Other: "Ошибка удаления истории: %v"},
+	"roles.owner":          {Other: "Пользователь %s - владелец бота, его роль задается в BOT_OWNER_IDS."},
+	"roles.header":         {Other: "Роли пользователей:"},
+	"grant.done":           {Other: "Пользователю %s выдана роль %s."},
+	"revoke.done":          {Other: "Роль пользователя %s отозвана."},
+	"revoke.none":          {Other: "У пользователя %s нет роли."},
+	"lang.chosen":          {Other: "Язык ответов: %s (выбран командой /lang)."},
+	"lang.auto":            {Other: "Язык ответов: %s (из настроек Telegram)."},
+	"lang.usage":           {Other: "Выбрать язык: /lang ru|en\nВернуть язык из настроек Telegram: /lang auto"},
+	"lang.set":             {Other: "Язык ответов: %s."},
+	"lang.reset":           {Other: "Язык ответов снова определяется настройками Telegram: %s."},
+
+	// Многошаговые команды
+	"conversation.cancel_hint": {Other: "Отменить: /cancel"},
+	"conversation.cancelled":   {Other: "Команда /%s отменена."},
+	"conversation.none":        {Other: "Нечего отменять: бот ничего у вас не спрашивает."},
+	"conversation.timeout":     {Other: "Ответ не получен вовремя, команда /%s отменена."},
+	"spy.ask.user":             {Other: "За кем следить? Отправьте @username или ID пользователя."},
+	"spy.ask.notify":           {Other: "Как уведомлять о смене статуса в этом чате?\nall - о каждом переходе\ndigest - ежедневной сводкой\n<с>-<до> - часы тишины, например 23-7\nkeep - оставить текущие настройки"},
+	"spy.bad_user":             {Other: "%q не похоже на @username или ID пользователя."},
+	"spy.bad_notify":           {Other: "Непонятный ответ %q."},
+
+	// Отчеты и уведомления
+	"report.title":           {Other: "Отчет по пользователю %s"},
+	"report.period":          {Other: "Период: %s - %s (%s)"},
+	"report.empty":           {Other: "За период активность не зафиксирована."},
+	"report.online":          {Other: "Время в сети: %s (в среднем %s в день)"},
+	"report.sessions":        {Other: "Сессий: %d"},
+	"report.first_seen":      {Other: "Первое появление: %s"},
+	"report.last_seen":       {Other: "Последнее появление: %s"},
+	"report.longest":         {Other: "Самая длинная сессия: %s, %s"},
+	"report.peak":            {Other: "Чаще всего в сети: %02d:00-%02d:00"},
+	"report.low_confidence":  {Other: "Сессий с неизвестной длительностью: %d"},
+	"duration.minutes":       {Other: "%d мин"},
+	"duration.hours":         {Other: "%d ч"},
+	"duration.hours_minutes": {Other: "%d ч %d мин"},
+	"export.ics.calendar":    {Other: "В сети: %s"},
+	"export.ics.summary":     {Other: "%s в сети"},
+	"export.ics.description": {Other: "Длительность: %s, достоверность: %s"},
+	"export.ics.ongoing":     {Other: ", сессия продолжается"},
+	"digest.header":          {Other: "Сводка переходов статуса:"},
+	"digest.more":            {Other: "... и еще %d"},
+	"presence.online":        {Other: "в сети"},
+	"presence.offline":       {Other: "не в сети"},
+	"presence.offline_at":    {Other: "вышел из сети в %s"},
+	"presence.recently":      {Other: "был недавно"},
+	"presence.last_week":     {Other: "был на этой неделе"},
+	"presence.last_month":    {Other: "был в этом месяце"},
+	"presence.hidden":        {Other: "статус скрыт"},
+	"presence.unknown":       {Other: "статус неизвестен"},
+}
@@ -0,0 +1,178 @@
+// Package lifecycle запускает компоненты приложения в порядке зависимостей
+// и останавливает их в обратном порядке с общим ограничением времени.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"telegram-api-with-go/internal/logger"
+)
+
+// ErrSkipped - компонент не останавливался: время остановки истекло раньше
+var ErrSkipped = errors.New("не остановлен: истекло время остановки")
+
+// Component - часть приложения с запуском и остановкой
+type Component struct {
+	// Name - имя компонента в логе и отчете об остановке
+	Name string
+	// Start запускает компонент и возвращается, не дожидаясь окончания его работы.
+	// nil - компонент не требует запуска.
+	Start func(ctx context.Context) error
+	// Stop останавливает компонент и должен вернуться не позже истечения ctx.
+	// nil - компонент не требует остановки.
+	Stop func(ctx context.Context) error
+}
+
+// Result - итог остановки компонента
+type Result struct {
+	Name     string
+	Duration time.Duration
+	Err      error
+}
+
+// Report - итог остановки приложения
+type Report struct {
+	Duration time.Duration
+	// Results - итоги в порядке остановки
+	Results []Result
+}
+
+// OK сообщает, что все компоненты остановлены без ошибок
+func (r Report) OK() bool {
+	for _, res := range r.Results {
+		if res.Err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// String кратко описывает итог остановки для лога
+func (r Report) String() string {
+	parts := make([]string, 0, len(r.Results))
+	for _, res := range r.Results {
+		status := "ok"
+		if res.Err != nil {
+			status = res.Err.Error()
+		}
+		parts = append(parts, fmt.Sprintf("%s: %s (%s)", res.Name, status, res.Duration.Round(time.Millisecond)))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// exit - компонент, завершивший работу сам
+type exit struct {
+	name string
+	err  error
+}
+
+// Orchestrator управляет жизненным циклом компонентов. Компоненты запускаются
+// в порядке добавления, поэтому компонент добавляется после тех, от которых зависит.
+type Orchestrator struct {
+	components []Component
+	// started - число запущенных компонентов; останавливаются только они
+	started int
+	exits   chan exit
+	log     *slog.Logger
+}
+
+// New создает оркестратор без компонентов
+func New() *Orchestrator {
+	return &Orchestrator{exits: make(chan exit, 1), log: logger.Log}
+}
+
+// Add добавляет компонент
+func (o *Orchestrator) Add(c Component) {
+	o.components = append(o.components, c)
+}
+
+// Start запускает компоненты по порядку. При ошибке остальные компоненты не запускаются,
+// а уже запущенные нужно остановить вызовом Shutdown.
+func (o *Orchestrator) Start(ctx context.Context) error {
+	for _, c := range o.components[o.started:] {
+		if c.Start != nil {
+			start := time.Now()
+			if err := c.Start(ctx); err != nil {
+				o.log.Error("Ошибка запуска компонента", "component", c.Name, "error", err)
+				return fmt.Errorf("запуск %s: %w", c.Name, err)
+			}
+			o.log.Debug("Компонент запущен", "component", c.Name, "elapsed", time.Since(start).Round(time.Millisecond))
+		}
+		o.started++
+	}
+	o.log.Info("Компоненты запущены", "count", o.started)
+	return nil
+}
+
+// Exited сообщает, что компонент name завершил работу сам, например из-за ошибки.
+// Wait возвращается после первого такого сообщения, остальные не учитываются.
+func (o *Orchestrator) Exited(name string, err error) {
+	select {
+	case o.exits <- exit{name: name, err: err}:
+	default:
+	}
+}
+
+// Wait ждет отмены ctx (сигнала завершения) или завершения одного из компонентов.
+// Возвращает nil после отмены ctx и причину завершения компонента в остальных случаях.
+func (o *Orchestrator) Wait(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return nil
+	case e := <-o.exits:
+		if e.err != nil {
+			return fmt.Errorf("%s: %w", e.name, e.err)
+		}
+		return fmt.Errorf("%s завершил работу", e.name)
+	}
+}
+
+// Shutdown останавливает запущенные компоненты в обратном порядке. Вся остановка
+// ограничена timeout: компоненты, до которых не дошла очередь, получают ErrSkipped.
+func (o *Orchestrator) Shutdown(timeout time.Duration) Report {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	o.log.Info("Остановка компонентов", "count", o.started, "timeout", timeout)
+	begin := time.Now()
+	var report Report
+	for i := o.started - 1; i >= 0; i-- {
+		c := o.components[i]
+		res := Result{Name: c.Name}
+		start := time.Now()
+		switch {
+		case ctx.Err() != nil:
+			res.Err = ErrSkipped
+		case c.Stop != nil:
+			res.Err = stop(ctx, c.Stop)
+		}
+		res.Duration = time.Since(start)
+		report.Results = append(report.Results, res)
+
+		if res.Err != nil {
+			o.log.Error("Ошибка остановки компонента", "component", c.Name, "error", res.Err, "elapsed", res.Duration.Round(time.Millisecond))
+		} else {
+			o.log.Info("Компонент остановлен", "component", c.Name, "elapsed", res.Duration.Round(time.Millisecond))
+		}
+	}
+	o.started = 0
+	report.Duration = time.Since(begin)
+	return report
+}
+
+// stop вызывает Stop и не ждет его дольше срока ctx
+func stop(ctx context.Context, f func(ctx context.Context) error) error {
+	done := make(chan error, 1)
+	go func() { done <- f(ctx) }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,125 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// recorder записывает порядок запуска и остановки компонентов
+type recorder struct {
+	events []string
+}
+
+func (r *recorder) component(name string, startErr error, stopDelay time.Duration) Component {
+	return Component{
+		Name: name,
+		Start: func(context.Context) error {
+			r.events = append(r.events, "start "+name)
+			return startErr
+		},
+		Stop: func(ctx context.Context) error {
+			r.events = append(r.events, "stop "+name)
+			select {
+			case <-time.After(stopDelay):
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	}
+}
+
+func newTestOrchestrator() *Orchestrator {
+	o := New()
+	o.log = slog.New(slog.NewTextHandler(io.Discard, nil))
+	return o
+}
+
+func TestOrder(t *testing.T) {
+	var r recorder
+	o := newTestOrchestrator()
+	o.Add(r.component("client", nil, 0))
+	o.Add(r.component("store", nil, 0))
+	o.Add(Component{Name: "noop"})
+	o.Add(r.component("bot", nil, 0))
+	if err := o.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	report := o.Shutdown(time.Second)
+	if !report.OK() {
+		t.Fatalf("report = %s", report)
+	}
+	want := []string{"start client", "start store", "start bot", "stop bot", "stop store", "stop client"}
+	if !reflect.DeepEqual(r.events, want) {
+		t.Fatalf("events = %v, want %v", r.events, want)
+	}
+	if len(report.Results) != 4 || report.Results[0].Name != "bot" || report.Results[3].Name != "client" {
+		t.Fatalf("results = %+v", report.Results)
+	}
+}
+
+func TestStartFailure(t *testing.T) {
+	var r recorder
+	o := newTestOrchestrator()
+	o.Add(r.component("client", nil, 0))
+	o.Add(r.component("store", errors.New("disk full"), 0))
+	o.Add(r.component("bot", nil, 0))
+	if err := o.Start(context.Background()); err == nil {
+		t.Fatal("start error expected")
+	}
+
+	// Останавливаются только запущенные компоненты
+	o.Shutdown(time.Second)
+	want := []string{"start client", "start store", "stop client"}
+	if !reflect.DeepEqual(r.events, want) {
+		t.Fatalf("events = %v, want %v", r.events, want)
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	var r recorder
+	o := newTestOrchestrator()
+	o.Add(r.component("client", nil, 0))
+	o.Add(Component{
+		Name: "bot",
+		// Компонент не соблюдает срок ctx
+		Stop: func(context.Context) error {
+			time.Sleep(time.Second)
+			return nil
+		},
+	})
+	if err := o.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	report := o.Shutdown(50 * time.Millisecond)
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("shutdown took %s", elapsed)
+	}
+	if report.OK() || !errors.Is(report.Results[0].Err, context.DeadlineExceeded) ||
+		!errors.Is(report.Results[1].Err, ErrSkipped) {
+		t.Fatalf("report = %s", report)
+	}
+}
+
+func TestWait(t *testing.T) {
+	o := newTestOrchestrator()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := o.Wait(ctx); err != nil {
+		t.Fatalf("wait after signal = %v", err)
+	}
+
+	o.Exited("bot", errors.New("updates closed"))
+	o.Exited("client", nil)
+	if err := o.Wait(context.Background()); err == nil || err.Error() != "bot: updates closed" {
+		t.Fatalf("wait after exit = %v", err)
+	}
+}
@@ -0,0 +1,268 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"telegram-api-with-go/internal/i18n"
+	"telegram-api-with-go/internal/logger"
+	"telegram-api-with-go/internal/presence"
+	"telegram-api-with-go/internal/telegram"
+)
+
+// maxDigestLines - максимальное число переходов в одной сводке
+const maxDigestLines = 50
+
+// SendFunc отправляет текстовое сообщение в чат
+type SendFunc func(chatID int64, text string) error
+
+// Options - параметры уведомлений
+type Options struct {
+	// Debounce - время, в течение которого переходы пользователя накапливаются.
+	// Если за это время вид статуса вернулся к прежнему, уведомление не отправляется.
+	// Нулевое значение отправляет уведомления о каждой смене состояния сразу.
+	Debounce time.Duration
+	// DigestHour - час отправки ежедневной сводки
+	DigestHour int
+	// Location - часовой пояс часов тишины, сводки и времени в сообщениях
+	Location *time.Location
+	// Lang возвращает язык уведомлений чата. nil - язык по умолчанию.
+	Lang func(chatID int64) i18n.Lang
+}
+
+// Notifier рассылает смены статуса чатам, подписанным на пользователя.
+// Отложенные переходы и накопленные сводки хранятся только в памяти
+// и теряются при перезапуске бота.
+type Notifier struct {
+	send  SendFunc
+	prefs *Preferences
+	opts  Options
+	log   *slog.Logger
+	now   func() time.Time
+
+	mu      sync.Mutex
+	pending map[int64]*pendingTransition
+	digests map[int64][]digestEntry
+	// stopped выставляется при завершении Run: новые переходы больше не принимаются
+	stopped bool
+	// flushing - рассылки отложенных переходов, начатые до завершения Run
+	flushing sync.WaitGroup
+}
+
+// pendingTransition - переходы пользователя, накопленные за время Debounce
+type pendingTransition struct {
+	target telegram.Target
+	from   presence.Status
+	to     presence.Status
+	at     time.Time
+	timer  *time.Timer
+}
+
+type digestEntry struct {
+	label  string
+	status presence.Status
+	at     time.Time
+}
+
+// New создает рассыльщик уведомлений
+func New(send SendFunc, prefs *Preferences, opts Options) *Notifier {
+	if opts.Location == nil {
+		opts.Location = time.UTC
+	}
+	if opts.Lang == nil {
+		opts.Lang = func(int64) i18n.Lang { return i18n.Default }
+	}
+	return &Notifier{
+		send:    send,
+		prefs:   prefs,
+		opts:    opts,
+		log:     logger.Log,
+		now:     time.Now,
+		pending: make(map[int64]*pendingTransition),
+		digests: make(map[int64][]digestEntry),
+	}
+}
+
+// Notify принимает смену статуса пользователя target.
+// Первый известный статус после запуска слежения не рассылается.
+func (n *Notifier) Notify(target telegram.Target, tr telegram.Transition) {
+	if !tr.From.Known() || len(target.Subscribers) == 0 {
+		return
+	}
+
+	n.mu.Lock()
+	if n.stopped {
+		n.mu.Unlock()
+		return
+	}
+	if n.opts.Debounce <= 0 {
+		n.mu.Unlock()
+		// Без накопления рассылается каждая смена состояния, в том числе
+		// новое время ухода из сети при прежнем виде статуса
+		if !tr.From.SameState(tr.To) {
+			n.deliver(target, tr.To, tr.At)
+		}
+		return
+	}
+	defer n.mu.Unlock()
+
+	p, ok := n.pending[target.UserID]
+	if !ok {
+		userID := target.UserID
+		p = &pendingTransition{from: tr.From}
+		p.timer = time.AfterFunc(n.opts.Debounce, func() { n.flush(userID) })
+		n.pending[userID] = p
+	} else {
+		p.timer.Reset(n.opts.Debounce)
+	}
+	p.target = target
+	p.to = tr.To
+	p.at = tr.At
+}
+
+// flush рассылает накопленный переход пользователя
+func (n *Notifier) flush(userID int64) {
+	n.mu.Lock()
+	p, ok := n.pending[userID]
+	delete(n.pending, userID)
+	if !ok || n.stopped {
+		n.mu.Unlock()
+		return
+	}
+	n.flushing.Add(1)
+	n.mu.Unlock()
+	defer n.flushing.Done()
+
+	if p.from.Kind == p.to.Kind {
+		n.log.Debug("Переходы статуса взаимно компенсировались, уведомление не отправлено",
+			"user_id", p.target.UserID,
+			"status", string(p.to.Kind),
+		)
+		return
+	}
+	n.deliver(p.target, p.to, p.at)
+}
+
+// deliver отправляет уведомление подписчикам или откладывает его в сводку
+func (n *Notifier) deliver(target telegram.Target, to presence.Status, at time.Time) {
+	now := n.now().In(n.opts.Location)
+	for _, chatID := range target.Subscribers {
+		settings := n.prefs.Get(chatID)
+		switch {
+		case settings.Digest:
+			n.mu.Lock()
+			n.digests[chatID] = append(n.digests[chatID], digestEntry{label: target.Label(), status: to, at: at})
+			n.mu.Unlock()
+		case settings.Quiet(now):
+			n.log.Debug("Уведомление подавлено часами тишины", "chat_id", chatID, "user_id", target.UserID)
+		default:
+			text := Message(n.opts.Lang(chatID), target.Label(), to, n.opts.Location)
+			if err := n.send(chatID, text); err != nil {
+				n.log.Error("Ошибка отправки уведомления", "chat_id", chatID, "user_id", target.UserID, "error", err)
+			}
+		}
+	}
+}
+
+// Run отправляет ежедневные сводки до отмены контекста.
+// При завершении отложенные переходы и неотправленные сводки отбрасываются,
+// а Run возвращается после окончания уже начатых рассылок.
+func (n *Notifier) Run(ctx context.Context) {
+	for {
+		now := n.now()
+		timer := time.NewTimer(n.nextDigest(now).Sub(now))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			n.stopPending()
+			return
+		case <-timer.C:
+			n.SendDigests()
+		}
+	}
+}
+
+// SendDigests рассылает накопленные сводки и очищает их
+func (n *Notifier) SendDigests() {
+	n.mu.Lock()
+	digests := n.digests
+	n.digests = make(map[int64][]digestEntry)
+	n.mu.Unlock()
+
+	for chatID, entries := range digests {
+		if err := n.send(chatID, digestText(n.opts.Lang(chatID), entries, n.opts.Location)); err != nil {
+			n.log.Error("Ошибка отправки сводки", "chat_id", chatID, "error", err)
+		}
+	}
+}
+
+// nextDigest возвращает ближайшее после now время отправки сводки
+func (n *Notifier) nextDigest(now time.Time) time.Time {
+	now = now.In(n.opts.Location)
+	next := time.Date(now.Year(), now.Month(), now.Day(), n.opts.DigestHour, 0, 0, 0, n.opts.Location)
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// stopPending останавливает таймеры отложенных переходов и ждет завершения
+// рассылок, которые таймеры уже начали
+func (n *Notifier) stopPending() {
+	n.mu.Lock()
+	n.stopped = true
+	for userID, p := range n.pending {
+		p.timer.Stop()
+		delete(n.pending, userID)
+	}
+	n.mu.Unlock()
+	n.flushing.Wait()
+}
+
+// Message формирует уведомление о новом статусе пользователя label на языке lang
+func Message(lang i18n.Lang, label string, status presence.Status, loc *time.Location) string {
+	return fmt.Sprintf("%s: %s", label, statusText(lang, status, loc))
+}
+
+// digestText формирует ежедневную сводку переходов
+func digestText(lang i18n.Lang, entries []digestEntry, loc *time.Location) string {
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].at.Before(entries[j].at) })
+
+	var sb strings.Builder
+	sb.WriteString(lang.T("digest.header") + "\n")
+	for i, e := range entries {
+		if i == maxDigestLines {
+			sb.WriteString(lang.T("digest.more", len(entries)-maxDigestLines) + "\n")
+			break
+		}
+		fmt.Fprintf(&sb, "%s %s: %s\n", e.at.In(loc).Format("02.01 15:04"), e.label, statusText(lang, e.status, loc))
+	}
+	return sb.String()
+}
+
+func statusText(lang i18n.Lang, status presence.Status, loc *time.Location) string {
+	switch status.Kind {
+	case presence.KindOnline:
+		return lang.T("presence.online")
+	case presence.KindOffline:
+		if status.SeenTo.IsZero() {
+			return lang.T("presence.offline")
+		}
+		return lang.T("presence.offline_at", status.SeenTo.In(loc).Format("15:04"))
+	case presence.KindRecently:
+		return lang.T("presence.recently")
+	case presence.KindLastWeek:
+		return lang.T("presence.last_week")
+	case presence.KindLastMonth:
+		return lang.T("presence.last_month")
+	case presence.KindEmpty:
+		return lang.T("presence.hidden")
+	default:
+		return lang.T("presence.unknown")
+	}
+}
@@ -0,0 +1,252 @@
+package notify
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"telegram-api-with-go/internal/i18n"
+	"telegram-api-with-go/internal/presence"
+	"telegram-api-with-go/internal/telegram"
+)
+
+var night = time.Date(2024, 5, 10, 2, 0, 0, 0, time.UTC)
+
+type recorder struct {
+	mu   sync.Mutex
+	sent map[int64][]string
+}
+
+func (r *recorder) send(chatID int64, text string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.sent == nil {
+		r.sent = make(map[int64][]string)
+	}
+	r.sent[chatID] = append(r.sent[chatID], text)
+	return nil
+}
+
+func (r *recorder) messages(chatID int64) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.sent[chatID]...)
+}
+
+func newTestNotifier(t *testing.T, debounce time.Duration) (*Notifier, *recorder) {
+	t.Helper()
+	rec := &recorder{}
+	n := New(rec.send, NewPreferences(filepath.Join(t.TempDir(), "notify.json")), Options{Debounce: debounce, DigestHour: 21})
+	n.log = slog.New(slog.NewTextHandler(io.Discard, nil))
+	n.now = func() time.Time { return night }
+	return n, rec
+}
+
+var alice = telegram.Target{UserID: 2002, Username: "alice", Subscribers: []int64{1, 2, 3}}
+
+func online() presence.Status {
+	return presence.Status{Kind: presence.KindOnline, SeenFrom: night, SeenTo: night, Expires: night.Add(5 * time.Minute)}
+}
+
+func offline(at time.Time) presence.Status {
+	return presence.Status{Kind: presence.KindOffline, SeenFrom: at, SeenTo: at}
+}
+
+func transition(from, to presence.Status) telegram.Transition {
+	return telegram.Transition{UserID: alice.UserID, From: from, To: to, Source: telegram.SourceEvent, At: night}
+}
+
+func TestSettingsQuiet(t *testing.T) {
+	for _, tc := range []struct {
+		settings Settings
+		hour     int
+		want     bool
+	}{
+		{Settings{}, 3, false},
+		{Settings{QuietFrom: 23, QuietTo: 7}, 23, true},
+		{Settings{QuietFrom: 23, QuietTo: 7}, 2, true},
+		{Settings{QuietFrom: 23, QuietTo: 7}, 7, false},
+		{Settings{QuietFrom: 13, QuietTo: 15}, 14, true},
+		{Settings{QuietFrom: 13, QuietTo: 15}, 15, false},
+	} {
+		at := time.Date(2024, 5, 10, tc.hour, 30, 0, 0, time.UTC)
+		if got := tc.settings.Quiet(at); got != tc.want {
+			t.Errorf("%+v.Quiet(%02d:30) = %v, want %v", tc.settings, tc.hour, got, tc.want)
+		}
+	}
+}
+
+func TestNotifierSkipsInitialStatus(t *testing.T) {
+	n, rec := newTestNotifier(t, 0)
+	n.Notify(alice, transition(presence.Status{}, online()))
+	if got := rec.messages(1); len(got) != 0 {
+		t.Fatalf("sent %q for the first known status", got)
+	}
+}
+
+func TestNotifierDebouncesFlapping(t *testing.T) {
+	n, rec := newTestNotifier(t, 50*time.Millisecond)
+	target := telegram.Target{UserID: alice.UserID, Username: "alice", Subscribers: []int64{1}}
+
+	// Пользователь зашел и сразу вышел: статус вернулся к offline
+	n.Notify(target, transition(offline(night.Add(-time.Hour)), online()))
+	n.Notify(target, transition(online(), offline(night)))
+	time.Sleep(150 * time.Millisecond)
+	if got := rec.messages(1); len(got) != 0 {
+		t.Fatalf("flapping produced notifications: %q", got)
+	}
+
+	// Устойчивый переход рассылается один раз после паузы
+	n.Notify(target, transition(offline(night), online()))
+	deadline := time.Now().Add(5 * time.Second)
+	for len(rec.messages(1)) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("notification was not sent")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := rec.messages(1); len(got) != 1 || got[0] != "@alice (2002): в сети" {
+		t.Fatalf("sent %q", got)
+	}
+}
+
+func TestNotifierImmediateOfflineChange(t *testing.T) {
+	n, rec := newTestNotifier(t, 0)
+	target := telegram.Target{UserID: alice.UserID, Username: "alice", Subscribers: []int64{1}}
+
+	// Без накопления новое время ухода из сети - смена состояния, хотя вид статуса прежний
+	n.Notify(target, transition(offline(night.Add(-time.Hour)), offline(night.Add(10*time.Minute))))
+	if got := rec.messages(1); len(got) != 1 || got[0] != "@alice (2002): вышел из сети в 02:10" {
+		t.Fatalf("sent %q", got)
+	}
+
+	// Продление online не является сменой состояния
+	extended := online()
+	extended.Expires = extended.Expires.Add(time.Minute)
+	n.Notify(target, transition(online(), extended))
+	if got := rec.messages(1); len(got) != 1 {
+		t.Fatalf("sent %q", got)
+	}
+}
+
+func TestNotifierStopsPendingOnShutdown(t *testing.T) {
+	n, rec := newTestNotifier(t, 50*time.Millisecond)
+	target := telegram.Target{UserID: alice.UserID, Username: "alice", Subscribers: []int64{1}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		n.Run(ctx)
+		close(done)
+	}()
+
+	n.Notify(target, transition(offline(night), online()))
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return")
+	}
+
+	// Отложенный переход и переходы после завершения не рассылаются
+	n.Notify(target, transition(online(), offline(night.Add(time.Minute))))
+	time.Sleep(150 * time.Millisecond)
+	if got := rec.messages(1); len(got) != 0 {
+		t.Fatalf("sent %q after shutdown", got)
+	}
+}
+
+func TestNotifierQuietHoursAndDigest(t *testing.T) {
+	n, rec := newTestNotifier(t, 0)
+	if err := n.prefs.Set(1, Settings{QuietFrom: 23, QuietTo: 7}); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.prefs.Set(2, Settings{Digest: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	n.Notify(alice, transition(offline(night.Add(-time.Hour)), online()))
+	n.Notify(alice, transition(online(), offline(night.Add(10*time.Minute))))
+
+	if got := rec.messages(1); len(got) != 0 {
+		t.Fatalf("quiet chat received %q", got)
+	}
+	if got := rec.messages(2); len(got) != 0 {
+		t.Fatalf("digest chat received %q before the digest", got)
+	}
+	if got := rec.messages(3); len(got) != 2 || got[1] != "@alice (2002): вышел из сети в 02:10" {
+		t.Fatalf("regular chat received %q", got)
+	}
+
+	n.SendDigests()
+	digest := rec.messages(2)
+	if len(digest) != 1 || !strings.Contains(digest[0], "10.05 02:00 @alice (2002): в сети\n") ||
+		!strings.Contains(digest[0], "@alice (2002): вышел из сети в 02:10\n") {
+		t.Fatalf("digest = %q", digest)
+	}
+
+	// Сводка отправляется только один раз
+	n.SendDigests()
+	if got := rec.messages(2); len(got) != 1 {
+		t.Fatalf("digest sent again: %q", got)
+	}
+}
+
+func TestNotifierChatLanguage(t *testing.T) {
+	n, rec := newTestNotifier(t, 0)
+	n.opts.Lang = func(chatID int64) i18n.Lang {
+		if chatID == 1 {
+			return i18n.English
+		}
+		return i18n.Russian
+	}
+	if err := n.prefs.Set(2, Settings{Digest: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	n.Notify(alice, transition(online(), offline(night.Add(10*time.Minute))))
+	if got := rec.messages(1); len(got) != 1 || got[0] != "@alice (2002): went offline at 02:10" {
+		t.Fatalf("english chat received %q", got)
+	}
+	if got := rec.messages(3); len(got) != 1 || got[0] != "@alice (2002): вышел из сети в 02:10" {
+		t.Fatalf("russian chat received %q", got)
+	}
+
+	n.opts.Lang = func(int64) i18n.Lang { return i18n.English }
+	n.SendDigests()
+	if got := rec.messages(2); len(got) != 1 || !strings.HasPrefix(got[0], "Status transitions digest:\n") {
+		t.Fatalf("digest = %q", got)
+	}
+}
+
+func TestNextDigest(t *testing.T) {
+	n, _ := newTestNotifier(t, 0)
+	if got, want := n.nextDigest(night), time.Date(2024, 5, 10, 21, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Fatalf("next digest = %s, want %s", got, want)
+	}
+	if got, want := n.nextDigest(night.Add(19*time.Hour)), time.Date(2024, 5, 11, 21, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Fatalf("next digest = %s, want %s", got, want)
+	}
+}
+
+func TestPreferencesPersist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notify.json")
+	prefs := NewPreferences(path)
+	want := Settings{QuietFrom: 22, QuietTo: 8, Digest: true}
+	if err := prefs.Set(42, want); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := NewPreferences(path)
+	if err := loaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if got := loaded.Get(42); got != want {
+		t.Fatalf("settings = %+v, want %+v", got, want)
+	}
+}
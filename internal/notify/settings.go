@@ -0,0 +1,99 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"telegram-api-with-go/internal/fileutil"
+)
+
+// Settings - настройки уведомлений чата-подписчика
+type Settings struct {
+	// QuietFrom и QuietTo - часы тишины [QuietFrom, QuietTo) в часовом поясе уведомлений.
+	// Интервал может переходить через полночь (23-7); равные значения отключают тишину.
+	QuietFrom int `json:"quiet_from"`
+	QuietTo   int `json:"quiet_to"`
+	// Digest заменяет уведомления о каждом переходе ежедневной сводкой
+	Digest bool `json:"digest,omitempty"`
+}
+
+// HasQuietHours сообщает, заданы ли часы тишины
+func (s Settings) HasQuietHours() bool {
+	return s.QuietFrom != s.QuietTo
+}
+
+// Quiet сообщает, приходится ли момент t на часы тишины
+func (s Settings) Quiet(t time.Time) bool {
+	if !s.HasQuietHours() {
+		return false
+	}
+	h := t.Hour()
+	if s.QuietFrom < s.QuietTo {
+		return h >= s.QuietFrom && h < s.QuietTo
+	}
+	return h >= s.QuietFrom || h < s.QuietTo
+}
+
+// Preferences хранит настройки уведомлений чатов в JSON файле
+type Preferences struct {
+	path string
+
+	mu    sync.Mutex
+	chats map[int64]Settings
+}
+
+// NewPreferences создает хранилище настроек уведомлений с файлом path
+func NewPreferences(path string) *Preferences {
+	return &Preferences{path: path, chats: make(map[int64]Settings)}
+}
+
+// Load загружает настройки из файла. Отсутствие файла не является ошибкой.
+func (p *Preferences) Load() error {
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("чтение настроек уведомлений: %w", err)
+	}
+
+	chats := make(map[int64]Settings)
+	if err := json.Unmarshal(data, &chats); err != nil {
+		return fmt.Errorf("разбор настроек уведомлений %s: %w", p.path, err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.chats = chats
+	return nil
+}
+
+// Get возвращает настройки чата; для чата без настроек - нулевые
+func (p *Preferences) Get(chatID int64) Settings {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.chats[chatID]
+}
+
+// Set сохраняет настройки чата
+func (p *Preferences) Set(chatID int64, s Settings) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if s == (Settings{}) {
+		delete(p.chats, chatID)
+	} else {
+		p.chats[chatID] = s
+	}
+	return p.saveLocked()
+}
+
+func (p *Preferences) saveLocked() error {
+	if err := fileutil.WriteJSONAtomic(p.path, p.chats); err != nil {
+		return fmt.Errorf("сохранение настроек уведомлений: %w", err)
+	}
+	return nil
+}
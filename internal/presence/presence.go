@@ -0,0 +1,128 @@
+package presence
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gotd/td/tg"
+)
+
+// Kind - вид статуса присутствия пользователя
+type Kind string
+
+const (
+	// KindOnline - пользователь в сети
+	KindOnline Kind = "online"
+	// KindOffline - пользователь не в сети, точное время последнего визита известно
+	KindOffline Kind = "offline"
+	// KindRecently - был недавно (время скрыто настройками приватности)
+	KindRecently Kind = "recently"
+	// KindLastWeek - был на этой неделе
+	KindLastWeek Kind = "last_week"
+	// KindLastMonth - был в этом месяце
+	KindLastMonth Kind = "last_month"
+	// KindEmpty - статус неизвестен (давно не заходил или скрыт полностью)
+	KindEmpty Kind = "empty"
+)
+
+// Границы приблизительных статусов. Telegram не публикует точные значения,
+// используются интервалы, которые показывают официальные клиенты.
+const (
+	RecentlyWithin  = 3 * 24 * time.Hour
+	LastWeekWithin  = 7 * 24 * time.Hour
+	LastMonthWithin = 30 * 24 * time.Hour
+)
+
+// Status - статус присутствия с точным временем или его оценкой
+type Status struct {
+	Kind Kind
+	// SeenFrom и SeenTo - интервал, в котором пользователь был в сети последний раз.
+	// Для KindOnline и KindOffline границы совпадают, для KindEmpty они нулевые.
+	SeenFrom time.Time
+	SeenTo   time.Time
+	// Expires - время, после которого статус KindOnline считается устаревшим
+	Expires time.Time
+}
+
+// FromTG преобразует статус Telegram, полученный в момент now
+func FromTG(status tg.UserStatusClass, now time.Time) Status {
+	switch s := status.(type) {
+	case *tg.UserStatusOnline:
+		return Status{Kind: KindOnline, SeenFrom: now, SeenTo: now, Expires: unix(s.Expires)}
+	case *tg.UserStatusOffline:
+		at := unix(s.WasOnline)
+		return Status{Kind: KindOffline, SeenFrom: at, SeenTo: at}
+	case *tg.UserStatusRecently:
+		return Status{Kind: KindRecently, SeenFrom: now.Add(-RecentlyWithin), SeenTo: now}
+	case *tg.UserStatusLastWeek:
+		return Status{Kind: KindLastWeek, SeenFrom: now.Add(-LastWeekWithin), SeenTo: now.Add(-RecentlyWithin)}
+	case *tg.UserStatusLastMonth:
+		return Status{Kind: KindLastMonth, SeenFrom: now.Add(-LastMonthWithin), SeenTo: now.Add(-LastWeekWithin)}
+	default:
+		return Status{Kind: KindEmpty}
+	}
+}
+
+// Known сообщает, получен ли статус
+func (s Status) Known() bool {
+	return s.Kind != ""
+}
+
+// Exact сообщает, известно ли точное время последнего визита
+func (s Status) Exact() bool {
+	return s.Kind == KindOnline || s.Kind == KindOffline
+}
+
+// OnlineAt сообщает, считается ли пользователь в сети в момент now
+func (s Status) OnlineAt(now time.Time) bool {
+	return s.Kind == KindOnline && (s.Expires.IsZero() || now.Before(s.Expires))
+}
+
+// SameState сообщает, описывают ли статусы одно и то же состояние.
+// Продление Expires у KindOnline и сдвиг оценочных интервалов со временем
+// не являются сменой состояния.
+func (s Status) SameState(o Status) bool {
+	if s.Kind != o.Kind {
+		return false
+	}
+	if s.Kind == KindOffline {
+		return s.SeenTo.Equal(o.SeenTo)
+	}
+	return true
+}
+
+// Expired возвращает статус KindOffline для онлайн-статуса, срок которого истек
+// к моменту now без нового события; ok равно false, если статус актуален
+func (s Status) Expired(now time.Time) (Status, bool) {
+	if s.Kind != KindOnline || s.Expires.IsZero() || now.Before(s.Expires) {
+		return Status{}, false
+	}
+	return Status{Kind: KindOffline, SeenFrom: s.Expires, SeenTo: s.Expires}, true
+}
+
+// String описывает статус для логов и сообщений
+func (s Status) String() string {
+	switch s.Kind {
+	case KindOnline:
+		return "в сети"
+	case KindOffline:
+		return fmt.Sprintf("был в сети %s", s.SeenTo.Format("2006-01-02 15:04:05 MST"))
+	case KindRecently:
+		return "был недавно"
+	case KindLastWeek:
+		return "был на этой неделе"
+	case KindLastMonth:
+		return "был в этом месяце"
+	case KindEmpty:
+		return "статус скрыт"
+	default:
+		return "статус неизвестен"
+	}
+}
+
+func unix(ts int) time.Time {
+	if ts == 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(ts), 0).UTC()
+}
@@ -0,0 +1,117 @@
+package presence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gotd/td/tg"
+)
+
+func TestFromTG(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	wasOnline := time.Date(2024, 5, 10, 11, 30, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		name   string
+		status tg.UserStatusClass
+		want   Status
+		exact  bool
+	}{
+		{
+			name:   "online",
+			status: &tg.UserStatusOnline{Expires: int(now.Add(5 * time.Minute).Unix())},
+			want:   Status{Kind: KindOnline, SeenFrom: now, SeenTo: now, Expires: now.Add(5 * time.Minute)},
+			exact:  true,
+		},
+		{
+			name:   "offline",
+			status: &tg.UserStatusOffline{WasOnline: int(wasOnline.Unix())},
+			want:   Status{Kind: KindOffline, SeenFrom: wasOnline, SeenTo: wasOnline},
+			exact:  true,
+		},
+		{
+			name:   "recently",
+			status: &tg.UserStatusRecently{},
+			want:   Status{Kind: KindRecently, SeenFrom: now.Add(-RecentlyWithin), SeenTo: now},
+		},
+		{
+			name:   "last week",
+			status: &tg.UserStatusLastWeek{},
+			want:   Status{Kind: KindLastWeek, SeenFrom: now.Add(-LastWeekWithin), SeenTo: now.Add(-RecentlyWithin)},
+		},
+		{
+			name:   "last month",
+			status: &tg.UserStatusLastMonth{},
+			want:   Status{Kind: KindLastMonth, SeenFrom: now.Add(-LastMonthWithin), SeenTo: now.Add(-LastWeekWithin)},
+		},
+		{
+			name:   "empty",
+			status: &tg.UserStatusEmpty{},
+			want:   Status{Kind: KindEmpty},
+		},
+		{
+			name:   "nil",
+			status: nil,
+			want:   Status{Kind: KindEmpty},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := FromTG(tc.status, now)
+			if got != tc.want {
+				t.Fatalf("FromTG = %+v, want %+v", got, tc.want)
+			}
+			if got.Exact() != tc.exact {
+				t.Fatalf("Exact = %v, want %v", got.Exact(), tc.exact)
+			}
+		})
+	}
+}
+
+func TestSameState(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	online := FromTG(&tg.UserStatusOnline{Expires: int(now.Add(time.Minute).Unix())}, now)
+	extended := FromTG(&tg.UserStatusOnline{Expires: int(now.Add(5 * time.Minute).Unix())}, now.Add(time.Minute))
+	offline := FromTG(&tg.UserStatusOffline{WasOnline: int(now.Unix())}, now)
+	offlineLater := FromTG(&tg.UserStatusOffline{WasOnline: int(now.Add(time.Hour).Unix())}, now)
+	recently := FromTG(&tg.UserStatusRecently{}, now)
+	recentlyLater := FromTG(&tg.UserStatusRecently{}, now.Add(time.Hour))
+
+	for _, tc := range []struct {
+		name string
+		a, b Status
+		want bool
+	}{
+		{"online extended", online, extended, true},
+		{"online to offline", online, offline, false},
+		{"offline same time", offline, offline, true},
+		{"offline new visit", offline, offlineLater, false},
+		{"recently shifted estimate", recently, recentlyLater, true},
+		{"unknown to recently", Status{}, recently, false},
+		{"recently to empty", recently, Status{Kind: KindEmpty}, false},
+	} {
+		if got := tc.a.SameState(tc.b); got != tc.want {
+			t.Errorf("%s: SameState = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestExpired(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	online := FromTG(&tg.UserStatusOnline{Expires: int(now.Add(time.Minute).Unix())}, now)
+
+	if !online.OnlineAt(now) {
+		t.Fatal("online status must be online before Expires")
+	}
+	if _, ok := online.Expired(now); ok {
+		t.Fatal("status must not expire before Expires")
+	}
+
+	later := now.Add(2 * time.Minute)
+	if online.OnlineAt(later) {
+		t.Fatal("online status must not be online after Expires")
+	}
+	offline, ok := online.Expired(later)
+	if !ok || offline.Kind != KindOffline || !offline.SeenTo.Equal(now.Add(time.Minute)) {
+		t.Fatalf("Expired = %+v, %v", offline, ok)
+	}
+}
@@ -0,0 +1,359 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// startEcho запускает TCP сервер, возвращающий полученные данные
+func startEcho(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_, _ = io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+// startSOCKS5 запускает минимальный SOCKS5 сервер с авторизацией по логину и паролю
+func startSOCKS5(t *testing.T, user, password string) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveSOCKS5(conn, user, password)
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func serveSOCKS5(conn net.Conn, user, password string) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	// Приветствие: версия и список методов
+	head := make([]byte, 2)
+	if _, err := io.ReadFull(r, head); err != nil {
+		return
+	}
+	if _, err := io.ReadFull(r, make([]byte, head[1])); err != nil {
+		return
+	}
+	_, _ = conn.Write([]byte{0x05, 0x02})
+
+	// Авторизация по логину и паролю (RFC 1929)
+	ver := make([]byte, 2)
+	if _, err := io.ReadFull(r, ver); err != nil {
+		return
+	}
+	gotUser := make([]byte, ver[1])
+	if _, err := io.ReadFull(r, gotUser); err != nil {
+		return
+	}
+	plen, err := r.ReadByte()
+	if err != nil {
+		return
+	}
+	gotPassword := make([]byte, plen)
+	if _, err := io.ReadFull(r, gotPassword); err != nil {
+		return
+	}
+	if string(gotUser) != user || string(gotPassword) != password {
+		_, _ = conn.Write([]byte{0x01, 0x01})
+		return
+	}
+	_, _ = conn.Write([]byte{0x01, 0x00})
+
+	// Запрос CONNECT
+	req := make([]byte, 4)
+	if _, err := io.ReadFull(r, req); err != nil {
+		return
+	}
+	var host string
+	switch req[3] {
+	case 0x01:
+		ip := make([]byte, 4)
+		if _, err := io.ReadFull(r, ip); err != nil {
+			return
+		}
+		host = net.IP(ip).String()
+	case 0x03:
+		n, err := r.ReadByte()
+		if err != nil {
+			return
+		}
+		name := make([]byte, n)
+		if _, err := io.ReadFull(r, name); err != nil {
+			return
+		}
+		host = string(name)
+	default:
+		return
+	}
+	portBytes := make([]byte, 2)
+	if _, err := io.ReadFull(r, portBytes); err != nil {
+		return
+	}
+	port := binary.BigEndian.Uint16(portBytes)
+
+	target, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(int(port))))
+	if err != nil {
+		_, _ = conn.Write([]byte{0x05, 0x05, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	defer target.Close()
+	_, _ = conn.Write([]byte{0x05, 0x00, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+
+	go func() {
+		_, _ = io.Copy(target, r)
+		target.Close()
+	}()
+	_, _ = io.Copy(conn, target)
+}
+
+// startConnectProxy запускает HTTP прокси, поддерживающий только CONNECT
+func startConnectProxy(t *testing.T, user, password string) string {
+	t.Helper()
+	srv := newConnectProxy(t, user, password)
+	srv.Start()
+	return srv.Listener.Addr().String()
+}
+
+// newConnectProxy создает незапущенный прокси с CONNECT для запуска по HTTP или HTTPS
+func newConnectProxy(t *testing.T, user, password string) *httptest.Server {
+	t.Helper()
+	want := "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+password))
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			http.Error(w, "only CONNECT", http.StatusMethodNotAllowed)
+			return
+		}
+		if r.Header.Get("Proxy-Authorization") != want {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		target, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		defer target.Close()
+
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+		go func() {
+			_, _ = io.Copy(target, buf)
+			target.Close()
+		}()
+		_, _ = io.Copy(conn, target)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func roundTrip(t *testing.T, dial DialFunc, addr string) error {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn, err := dial(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	got := make([]byte, 4)
+	if _, err := io.ReadFull(conn, got); err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "ping" {
+		t.Fatalf("got %q, want %q", got, "ping")
+	}
+	return nil
+}
+
+func TestDialerThroughProxies(t *testing.T) {
+	echo := startEcho(t)
+	socks := startSOCKS5(t, "user", "secret")
+	connect := startConnectProxy(t, "user", "secret")
+
+	for _, tt := range []struct {
+		name string
+		url  string
+	}{
+		{"direct", ""},
+		{"socks5", "socks5://user:secret@" + socks},
+		{"http connect", "http://user:secret@" + connect},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dial, err := Settings{URL: tt.url}.Dialer()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := roundTrip(t, dial, echo); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestDialerReportsProxy(t *testing.T) {
+	echo := startEcho(t)
+	socks := startSOCKS5(t, "user", "secret")
+	connect := startConnectProxy(t, "user", "secret")
+
+	// Адрес закрытого порта для проверки недоступного прокси
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := ln.Addr().String()
+	ln.Close()
+
+	for _, tt := range []struct {
+		name  string
+		url   string
+		proxy string
+	}{
+		{"socks5 bad credentials", "socks5://user:wrong@" + socks, "socks5://" + socks},
+		{"http bad credentials", "http://user:wrong@" + connect, "http://" + connect},
+		{"unreachable", "socks5://" + closed, "socks5://" + closed},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dial, err := Settings{URL: tt.url}.Dialer()
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = roundTrip(t, dial, echo)
+
+			var proxyErr *Error
+			if !errors.As(err, &proxyErr) {
+				t.Fatalf("expected *Error, got %v", err)
+			}
+			if proxyErr.Proxy != tt.proxy {
+				t.Fatalf("proxy = %q, want %q", proxyErr.Proxy, tt.proxy)
+			}
+			if proxyErr.Addr != echo {
+				t.Fatalf("addr = %q, want %q", proxyErr.Addr, echo)
+			}
+		})
+	}
+}
+
+func TestDialerHTTPSProxy(t *testing.T) {
+	echo := startEcho(t)
+	srv := newConnectProxy(t, "user", "secret")
+	srv.StartTLS()
+	addr := srv.Listener.Addr().String()
+
+	// Сертификат тестового прокси не подписан доверенным центром: без TLS
+	// запрос CONNECT с учетными данными ушел бы открытым текстом
+	dial, err := Settings{URL: "https://user:secret@" + addr}.Dialer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var certErr *tls.CertificateVerificationError
+	if err := roundTrip(t, dial, echo); !errors.As(err, &certErr) {
+		t.Fatalf("expected certificate error, got %v", err)
+	}
+
+	u, err := url.Parse("https://user:secret@" + addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+	d := &connectDialer{proxy: u, tls: &tls.Config{ServerName: u.Hostname(), RootCAs: roots}}
+	if err := roundTrip(t, d.DialContext, echo); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHTTPClientThroughProxy(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"ok":true}`)
+	}))
+	defer api.Close()
+
+	socks := startSOCKS5(t, "user", "secret")
+	connect := startConnectProxy(t, "user", "secret")
+
+	for _, proxyURL := range []string{
+		"socks5://user:secret@" + socks,
+		"http://user:secret@" + connect,
+	} {
+		client, err := Settings{URL: proxyURL}.HTTPClient()
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.Get(api.URL)
+		if err != nil {
+			t.Fatalf("%s: %v", proxyURL, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		client.CloseIdleConnections()
+		if string(body) != `{"ok":true}` {
+			t.Fatalf("%s: unexpected body %q", proxyURL, body)
+		}
+	}
+}
+
+func TestResolver(t *testing.T) {
+	if _, err := (Settings{URL: "socks5://127.0.0.1:1080"}).Resolver(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (Settings{MTProxyAddr: "127.0.0.1:443", MTProxySecret: "dd00112233445566778899aabbccddeeff"}).Resolver(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (Settings{MTProxyAddr: "127.0.0.1:443", MTProxySecret: "%%%"}).Resolver(); err == nil {
+		t.Fatal("expected error for invalid secret")
+	}
+	if _, err := (Settings{URL: "ftp://127.0.0.1:21"}).Dialer(); err == nil {
+		t.Fatal("expected error for unsupported scheme")
+	}
+}
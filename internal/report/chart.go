@@ -0,0 +1,160 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"time"
+
+	"telegram-api-with-go/internal/analytics"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// Размеры графика. Подписи выполнены встроенным растровым шрифтом,
+// который содержит только ASCII, поэтому они на английском.
+const (
+	chartWidth   = 760
+	marginLeft   = 48
+	marginTop    = 30
+	cellWidth    = 28
+	cellHeight   = 22
+	barsTop      = marginTop + 7*cellHeight + 60
+	barsHeight   = 150
+	chartHeight  = barsTop + barsHeight + 50
+	maxBarLabels = 14
+)
+
+var (
+	colorBackground = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	colorText       = color.RGBA{0x33, 0x33, 0x33, 0xff}
+	colorGrid       = color.RGBA{0xe0, 0xe0, 0xe0, 0xff}
+	colorEmpty      = color.RGBA{0xf2, 0xf2, 0xf2, 0xff}
+	colorActive     = color.RGBA{0x1f, 0x77, 0xb4, 0xff}
+)
+
+var weekdays = [7]string{"Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"}
+
+// Render рисует PNG-график: тепловую карту активности по часам и дням недели
+// и столбцы времени в сети по дням
+func Render(w io.Writer, sum analytics.Summary) error {
+	img := image.NewRGBA(image.Rect(0, 0, chartWidth, chartHeight))
+	draw.Draw(img, img.Bounds(), image.NewUniform(colorBackground), image.Point{}, draw.Src)
+
+	loc := sum.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	drawHeatmap(img, sum, loc)
+	drawDailyBars(img, sum)
+
+	if err := png.Encode(w, img); err != nil {
+		return fmt.Errorf("кодирование PNG: %w", err)
+	}
+	return nil
+}
+
+// RenderPNG возвращает график в виде PNG
+func RenderPNG(sum analytics.Summary) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := Render(&buf, sum); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func drawHeatmap(img *image.RGBA, sum analytics.Summary, loc *time.Location) {
+	drawText(img, marginLeft, marginTop-12, fmt.Sprintf("Activity by hour and weekday (%s)", loc))
+
+	var peak time.Duration
+	for _, day := range sum.Heatmap {
+		for _, d := range day {
+			peak = max(peak, d)
+		}
+	}
+
+	for day := range 7 {
+		y := marginTop + day*cellHeight
+		drawText(img, 8, y+cellHeight-7, weekdays[day])
+		for hour := range 24 {
+			x := marginLeft + hour*cellWidth
+			c := colorEmpty
+			if d := sum.Heatmap[day][hour]; d > 0 && peak > 0 {
+				c = blend(colorEmpty, colorActive, 0.15+0.85*float64(d)/float64(peak))
+			}
+			fillRect(img, x+1, y+1, cellWidth-1, cellHeight-1, c)
+		}
+	}
+
+	for hour := 0; hour < 24; hour += 3 {
+		drawText(img, marginLeft+hour*cellWidth+4, marginTop+7*cellHeight+14, fmt.Sprintf("%02d", hour))
+	}
+}
+
+func drawDailyBars(img *image.RGBA, sum analytics.Summary) {
+	drawText(img, marginLeft, barsTop-12, "Online time per day")
+
+	plotWidth := 24 * cellWidth
+	fillRect(img, marginLeft, barsTop+barsHeight, plotWidth, 1, colorText)
+
+	var peak time.Duration
+	for _, day := range sum.Daily {
+		peak = max(peak, day.Online)
+	}
+	// Шкала округляется вверх до целого часа, минимум час
+	scale := max(peak.Round(time.Hour), time.Hour)
+	if scale < peak {
+		scale += time.Hour
+	}
+	for _, frac := range []float64{0.5, 1} {
+		y := barsTop + barsHeight - int(frac*barsHeight)
+		fillRect(img, marginLeft, y, plotWidth, 1, colorGrid)
+		drawText(img, 8, y+4, fmt.Sprintf("%gh", frac*scale.Hours()))
+	}
+
+	n := len(sum.Daily)
+	if n == 0 {
+		return
+	}
+	slot := plotWidth / n
+	barWidth := max(slot*3/4, 1)
+	labelEvery := (n + maxBarLabels - 1) / maxBarLabels
+	for i, day := range sum.Daily {
+		x := marginLeft + i*slot + (slot-barWidth)/2
+		h := int(float64(barsHeight) * float64(day.Online) / float64(scale))
+		if h > 0 {
+			fillRect(img, x, barsTop+barsHeight-h, barWidth, h, colorActive)
+		}
+		if i%labelEvery == 0 {
+			drawText(img, marginLeft+i*slot, barsTop+barsHeight+16, day.Date.Format("01-02"))
+		}
+	}
+}
+
+func fillRect(img *image.RGBA, x, y, w, h int, c color.Color) {
+	draw.Draw(img, image.Rect(x, y, x+w, y+h), image.NewUniform(c), image.Point{}, draw.Src)
+}
+
+func drawText(img *image.RGBA, x, y int, text string) {
+	d := font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(colorText),
+		Face: basicfont.Face7x13,
+		Dot:  fixed.P(x, y),
+	}
+	d.DrawString(text)
+}
+
+// blend смешивает цвета a и b в пропорции t
+func blend(a, b color.RGBA, t float64) color.RGBA {
+	mix := func(x, y uint8) uint8 {
+		return uint8(float64(x) + (float64(y)-float64(x))*t + 0.5)
+	}
+	return color.RGBA{mix(a.R, b.R), mix(a.G, b.G), mix(a.B, b.B), 0xff}
+}
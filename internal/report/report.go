@@ -0,0 +1,145 @@
+package report
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"telegram-api-with-go/internal/analytics"
+	"telegram-api-with-go/internal/i18n"
+)
+
+// DefaultPeriod - период отчета по умолчанию
+const DefaultPeriod = 7 * 24 * time.Hour
+
+// MaxPeriod - максимальный период отчета
+const MaxPeriod = 366 * 24 * time.Hour
+
+var (
+	// ErrInvalidPeriod возвращается, если период не удалось разобрать
+	ErrInvalidPeriod = errors.New("некорректный период")
+	// ErrPeriodTooLong возвращается, если период больше MaxPeriod
+	ErrPeriodTooLong = errors.New("период больше максимального")
+)
+
+// ParsePeriod разбирает период отчета: число дней с суффиксом d (7d),
+// недель с суффиксом w (2w) или длительность Go (12h). Пустая строка - DefaultPeriod.
+func ParsePeriod(s string) (time.Duration, error) {
+	if s == "" {
+		return DefaultPeriod, nil
+	}
+
+	var period time.Duration
+	switch {
+	case strings.HasSuffix(s, "d"), strings.HasSuffix(s, "w"):
+		n, err := strconv.ParseInt(s[:len(s)-1], 10, 64)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("%w %q", ErrInvalidPeriod, s)
+		}
+		unit := 24 * time.Hour
+		if strings.HasSuffix(s, "w") {
+			unit *= 7
+		}
+		// Проверка до умножения: большое число дней переполняет time.Duration
+		if n > int64(MaxPeriod/unit) {
+			return 0, fmt.Errorf("%w: %q (%d дней)", ErrPeriodTooLong, s, int(MaxPeriod.Hours()/24))
+		}
+		period = time.Duration(n) * unit
+	default:
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			return 0, fmt.Errorf("%w %q", ErrInvalidPeriod, s)
+		}
+		period = d
+	}
+
+	if period <= 0 {
+		return 0, fmt.Errorf("%w %q", ErrInvalidPeriod, s)
+	}
+	if period > MaxPeriod {
+		return 0, fmt.Errorf("%w: %q (%d дней)", ErrPeriodTooLong, s, int(MaxPeriod.Hours()/24))
+	}
+	return period, nil
+}
+
+// Text формирует текстовую сводку присутствия пользователя label на языке lang
+func Text(lang i18n.Lang, label string, sum analytics.Summary) string {
+	loc := sum.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	var sb strings.Builder
+	sb.WriteString(lang.T("report.title", label) + "\n")
+	sb.WriteString(lang.T("report.period",
+		sum.From.In(loc).Format("02.01.2006 15:04"),
+		sum.To.In(loc).Format("02.01.2006 15:04"),
+		loc,
+	) + "\n")
+
+	if len(sum.Sessions) == 0 {
+		sb.WriteString(lang.T("report.empty") + "\n")
+		return sb.String()
+	}
+
+	days := len(sum.Daily)
+	if days == 0 {
+		days = 1
+	}
+	sb.WriteString(lang.T("report.online",
+		FormatDuration(lang, sum.Total), FormatDuration(lang, sum.Total/time.Duration(days))) + "\n")
+	sb.WriteString(lang.T("report.sessions", len(sum.Sessions)) + "\n")
+	sb.WriteString(lang.T("report.first_seen", sum.FirstSeen.In(loc).Format("02.01.2006 15:04")) + "\n")
+	sb.WriteString(lang.T("report.last_seen", sum.LastSeen.In(loc).Format("02.01.2006 15:04")) + "\n")
+	sb.WriteString(lang.T("report.longest",
+		FormatDuration(lang, sum.Longest.Duration()), sum.Longest.Start.In(loc).Format("02.01.2006 15:04")) + "\n")
+
+	if hour, ok := peakHour(sum); ok {
+		sb.WriteString(lang.T("report.peak", hour, (hour+1)%24) + "\n")
+	}
+
+	low := 0
+	for _, s := range sum.Sessions {
+		if s.Confidence == analytics.ConfidenceLow {
+			low++
+		}
+	}
+	if low > 0 {
+		sb.WriteString(lang.T("report.low_confidence", low) + "\n")
+	}
+	return sb.String()
+}
+
+// peakHour возвращает час суток с наибольшим временем в сети
+func peakHour(sum analytics.Summary) (int, bool) {
+	var byHour [24]time.Duration
+	for _, day := range sum.Heatmap {
+		for hour, d := range day {
+			byHour[hour] += d
+		}
+	}
+	best := -1
+	for hour, d := range byHour {
+		if d > 0 && (best < 0 || d > byHour[best]) {
+			best = hour
+		}
+	}
+	return best, best >= 0
+}
+
+// FormatDuration форматирует длительность в часах и минутах на языке lang
+func FormatDuration(lang i18n.Lang, d time.Duration) string {
+	d = d.Round(time.Minute)
+	h := int(d.Hours())
+	m := int(d.Minutes()) % 60
+	switch {
+	case h == 0:
+		return lang.T("duration.minutes", m)
+	case m == 0:
+		return lang.T("duration.hours", h)
+	default:
+		return lang.T("duration.hours_minutes", h, m)
+	}
+}
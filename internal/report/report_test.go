@@ -0,0 +1,162 @@
+package report
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"telegram-api-with-go/internal/analytics"
+	"telegram-api-with-go/internal/i18n"
+)
+
+var update = flag.Bool("update", false, "перезаписать эталонные изображения в testdata")
+
+var start = time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC) // понедельник
+
+func testSummary() analytics.Summary {
+	var sessions []analytics.Session
+	for day := range 7 {
+		base := start.AddDate(0, 0, day)
+		sessions = append(sessions,
+			analytics.Session{Start: base.Add(9 * time.Hour), End: base.Add(9*time.Hour + time.Duration(10+day*5)*time.Minute), Confidence: analytics.ConfidenceHigh},
+			analytics.Session{Start: base.Add(21*time.Hour + 30*time.Minute), End: base.Add(23 * time.Hour), Confidence: analytics.ConfidenceHigh},
+		)
+	}
+	sessions = append(sessions, analytics.Session{Start: start.Add(14 * time.Hour), End: start.Add(14 * time.Hour), Confidence: analytics.ConfidenceLow})
+	return analytics.Summarize(1, sessions, start, start.AddDate(0, 0, 7), time.UTC)
+}
+
+func TestParsePeriod(t *testing.T) {
+	for in, want := range map[string]time.Duration{
+		"":    DefaultPeriod,
+		"1d":  24 * time.Hour,
+		"30d": 30 * 24 * time.Hour,
+		"2w":  14 * 24 * time.Hour,
+		"12h": 12 * time.Hour,
+		"52w": 364 * 24 * time.Hour,
+	} {
+		got, err := ParsePeriod(in)
+		if err != nil || got != want {
+			t.Errorf("ParsePeriod(%q) = %s, %v; want %s", in, got, err, want)
+		}
+	}
+	for in, want := range map[string]error{
+		"0d":                   ErrInvalidPeriod,
+		"-1d":                  ErrInvalidPeriod,
+		"week":                 ErrInvalidPeriod,
+		"400d":                 ErrPeriodTooLong,
+		"53w":                  ErrPeriodTooLong,
+		"106752d":              ErrPeriodTooLong,
+		"1000000d":             ErrPeriodTooLong,
+		"200000w":              ErrPeriodTooLong,
+		"9223372036854775807d": ErrPeriodTooLong,
+	} {
+		if _, err := ParsePeriod(in); !errors.Is(err, want) {
+			t.Errorf("ParsePeriod(%q) = %v, want %v", in, err, want)
+		}
+	}
+}
+
+func TestText(t *testing.T) {
+	got := Text(i18n.Russian, "@alice (2002)", testSummary())
+	for _, want := range []string{
+		"Отчет по пользователю @alice (2002)\n",
+		"Период: 06.05.2024 00:00 - 13.05.2024 00:00 (UTC)\n",
+		"Время в сети: 13 ч 25 мин (в среднем 1 ч 55 мин в день)\n",
+		"Сессий: 15\n",
+		"Первое появление: 06.05.2024 09:00\n",
+		"Последнее появление: 12.05.2024 23:00\n",
+		"Самая длинная сессия: 1 ч 30 мин, 06.05.2024 21:30\n",
+		"Чаще всего в сети: 22:00-23:00\n",
+		"Сессий с неизвестной длительностью: 1\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("report does not contain %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestTextEnglish(t *testing.T) {
+	got := Text(i18n.English, "@alice (2002)", testSummary())
+	for _, want := range []string{
+		"Report for user @alice (2002)\n",
+		"Time online: 13 h 25 min (1 h 55 min per day on average)\n",
+		"Sessions: 15\n",
+		"Longest session: 1 h 30 min, 06.05.2024 21:30\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("report does not contain %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestTextWithoutActivity(t *testing.T) {
+	sum := analytics.Summarize(1, nil, start, start.AddDate(0, 0, 1), time.UTC)
+	if got := Text(i18n.Russian, "1", sum); !strings.HasSuffix(got, "За период активность не зафиксирована.\n") {
+		t.Fatalf("report = %q", got)
+	}
+}
+
+func TestRenderGolden(t *testing.T) {
+	for name, sum := range map[string]analytics.Summary{
+		"week":  testSummary(),
+		"empty": analytics.Summarize(1, nil, start, start.AddDate(0, 0, 3), time.UTC),
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, err := RenderPNG(sum)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			golden := filepath.Join("testdata", name+".golden.png")
+			if *update {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v (запустите go test ./internal/report -update)", err)
+			}
+			comparePixels(t, got, want)
+		})
+	}
+}
+
+// comparePixels сравнивает изображения попиксельно, а не побайтно,
+// чтобы тест не зависел от параметров сжатия PNG
+func comparePixels(t *testing.T, got, want []byte) {
+	t.Helper()
+	gotImg, err := png.Decode(bytes.NewReader(got))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantImg, err := png.Decode(bytes.NewReader(want))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotImg.Bounds() != wantImg.Bounds() {
+		t.Fatalf("bounds = %v, want %v", gotImg.Bounds(), wantImg.Bounds())
+	}
+	b := gotImg.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if !sameColor(gotImg, wantImg, x, y) {
+				t.Fatalf("pixel (%d, %d) differs from golden image", x, y)
+			}
+		}
+	}
+}
+
+func sameColor(a, b image.Image, x, y int) bool {
+	r1, g1, b1, a1 := a.At(x, y).RGBA()
+	r2, g2, b2, a2 := b.At(x, y).RGBA()
+	return r1 == r2 && g1 == g2 && b1 == b2 && a1 == a2
+}
@@ -0,0 +1,122 @@
+package store
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// BoltStore хранит историю во встроенной базе bbolt: отдельный bucket на пользователя,
+// ключ - порядковый номер записи, поэтому порядок добавления сохраняется
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// OpenBolt открывает или создает базу в файле path
+func OpenBolt(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("открытие базы истории %s: %w", path, err)
+	}
+	return &BoltStore{db: db}, nil
+}
+
+func userBucket(userID int64) []byte {
+	return []byte("user:" + strconv.FormatInt(userID, 10))
+}
+
+// Append добавляет запись в bucket пользователя. bbolt допускает только
+// одну пишущую транзакцию, поэтому записи сериализуются.
+func (s *BoltStore) Append(rec Record) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(userBucket(rec.UserID))
+		if err != nil {
+			return err
+		}
+		seq, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		key := make([]byte, 8)
+		binary.BigEndian.PutUint64(key, seq)
+		return b.Put(key, data)
+	})
+	return s.wrap(err, "запись истории пользователя %d", rec.UserID)
+}
+
+// Last возвращает последнюю запись пользователя
+func (s *BoltStore) Last(userID int64) (rec Record, ok bool, err error) {
+	err = s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(userBucket(userID))
+		if b == nil {
+			return nil
+		}
+		_, v := b.Cursor().Last()
+		if v == nil {
+			return nil
+		}
+		ok = true
+		return json.Unmarshal(v, &rec)
+	})
+	return rec, ok, s.wrap(err, "чтение истории пользователя %d", userID)
+}
+
+// History возвращает записи пользователя в диапазоне [from, to)
+func (s *BoltStore) History(userID int64, from, to time.Time) ([]Record, error) {
+	var records []Record
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(userBucket(userID))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(_, v []byte) error {
+			var rec Record
+			if err := json.Unmarshal(v, &rec); err != nil {
+				return err
+			}
+			if inRange(rec.RecordedAt, from, to) {
+				records = append(records, rec)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, s.wrap(err, "чтение истории пользователя %d", userID)
+	}
+	return records, nil
+}
+
+// Delete удаляет bucket пользователя
+func (s *BoltStore) Delete(userID int64) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		err := tx.DeleteBucket(userBucket(userID))
+		if err == bolt.ErrBucketNotFound {
+			return nil
+		}
+		return err
+	})
+	return s.wrap(err, "удаление истории пользователя %d", userID)
+}
+
+// Close дожидается завершения транзакций и закрывает базу
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *BoltStore) wrap(err error, format string, userID int64) error {
+	if err == nil {
+		return nil
+	}
+	if err == bolt.ErrDatabaseNotOpen {
+		return ErrClosed
+	}
+	return fmt.Errorf(format+": %w", userID, err)
+}
@@ -0,0 +1,164 @@
+package store
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// JSONLStore хранит историю каждого пользователя в отдельном файле <dir>/<user_id>.jsonl,
+// по одной JSON-записи на строку
+type JSONLStore struct {
+	dir string
+
+	mu     sync.Mutex
+	last   map[int64]Record
+	closed bool
+}
+
+// OpenJSONL открывает хранилище в каталоге dir, создавая его при необходимости
+func OpenJSONL(dir string) (*JSONLStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("создание каталога истории %s: %w", dir, err)
+	}
+	return &JSONLStore{dir: dir, last: make(map[int64]Record)}, nil
+}
+
+func (s *JSONLStore) file(userID int64) string {
+	return filepath.Join(s.dir, strconv.FormatInt(userID, 10)+".jsonl")
+}
+
+// Append дописывает запись в файл пользователя
+func (s *JSONLStore) Append(rec Record) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrClosed
+	}
+
+	f, err := os.OpenFile(s.file(rec.UserID), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("открытие истории пользователя %d: %w", rec.UserID, err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("запись истории пользователя %d: %w", rec.UserID, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("запись истории пользователя %d: %w", rec.UserID, err)
+	}
+	s.last[rec.UserID] = rec
+	return nil
+}
+
+// Last возвращает последнюю запись пользователя
+func (s *JSONLStore) Last(userID int64) (Record, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return Record{}, false, ErrClosed
+	}
+	if rec, ok := s.last[userID]; ok {
+		return rec, true, nil
+	}
+
+	records, err := s.readLocked(userID)
+	if err != nil || len(records) == 0 {
+		return Record{}, false, err
+	}
+	rec := records[len(records)-1]
+	s.last[userID] = rec
+	return rec, true, nil
+}
+
+// History возвращает записи пользователя в диапазоне [from, to)
+func (s *JSONLStore) History(userID int64, from, to time.Time) ([]Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, ErrClosed
+	}
+
+	records, err := s.readLocked(userID)
+	if err != nil {
+		return nil, err
+	}
+	result := records[:0]
+	for _, rec := range records {
+		if inRange(rec.RecordedAt, from, to) {
+			result = append(result, rec)
+		}
+	}
+	return result, nil
+}
+
+// Delete удаляет файл истории пользователя
+func (s *JSONLStore) Delete(userID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrClosed
+	}
+	delete(s.last, userID)
+	if err := os.Remove(s.file(userID)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("удаление истории пользователя %d: %w", userID, err)
+	}
+	return nil
+}
+
+// Close закрывает хранилище. Файлы закрываются после каждой записи,
+// поэтому достаточно дождаться текущей записи.
+func (s *JSONLStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+// readLocked читает все записи пользователя. Поврежденная последняя строка
+// (например, после аварийного завершения) пропускается.
+func (s *JSONLStore) readLocked(userID int64) ([]Record, error) {
+	f, err := os.Open(s.file(userID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("чтение истории пользователя %d: %w", userID, err)
+	}
+	defer f.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(f)
+	line := 0
+	var badLine int
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		if badLine != 0 {
+			return nil, fmt.Errorf("история пользователя %d повреждена в строке %d", userID, badLine)
+		}
+		var rec Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			badLine = line
+			continue
+		}
+		records = append(records, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("чтение истории пользователя %d: %w", userID, err)
+	}
+	return records, nil
+}
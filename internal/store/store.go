@@ -0,0 +1,124 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"telegram-api-with-go/internal/config"
+	"telegram-api-with-go/internal/presence"
+)
+
+// Виды хранилища истории присутствия
+const (
+	KindJSONL = "jsonl"
+	KindBolt  = "bolt"
+)
+
+// ErrClosed возвращается при обращении к закрытому хранилищу
+var ErrClosed = errors.New("хранилище закрыто")
+
+// Record - запись истории присутствия пользователя
+type Record struct {
+	UserID int64 `json:"user_id"`
+	// Kind - вид статуса; в записях старого формата отсутствует и означает offline
+	Kind presence.Kind `json:"status,omitempty"`
+	// LastOnline - точное время последнего визита в секундах Unix, 0 для оценочных статусов
+	LastOnline int64 `json:"last_online"`
+	// SeenFrom и SeenTo - оценка времени последнего визита в секундах Unix
+	SeenFrom int64 `json:"seen_from,omitempty"`
+	SeenTo   int64 `json:"seen_to,omitempty"`
+	// Expires - срок действия статуса online в секундах Unix
+	Expires int64 `json:"expires,omitempty"`
+	// RecordedAt - время сохранения записи
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+// NewRecord создает запись статуса пользователя, наблюдавшегося в момент at
+func NewRecord(userID int64, status presence.Status, at time.Time) Record {
+	rec := Record{
+		UserID:     userID,
+		Kind:       status.Kind,
+		SeenFrom:   unixOrZero(status.SeenFrom),
+		SeenTo:     unixOrZero(status.SeenTo),
+		Expires:    unixOrZero(status.Expires),
+		RecordedAt: at.UTC(),
+	}
+	if status.Exact() {
+		rec.LastOnline = rec.SeenTo
+	}
+	return rec
+}
+
+// Status восстанавливает статус присутствия из записи
+func (r Record) Status() presence.Status {
+	if r.Kind == "" {
+		// Старый формат хранил только время последнего визита
+		at := timeOrZero(r.LastOnline)
+		return presence.Status{Kind: presence.KindOffline, SeenFrom: at, SeenTo: at}
+	}
+	return presence.Status{
+		Kind:     r.Kind,
+		SeenFrom: timeOrZero(r.SeenFrom),
+		SeenTo:   timeOrZero(r.SeenTo),
+		Expires:  timeOrZero(r.Expires),
+	}
+}
+
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+func timeOrZero(ts int64) time.Time {
+	if ts == 0 {
+		return time.Time{}
+	}
+	return time.Unix(ts, 0).UTC()
+}
+
+// PresenceStore хранит историю присутствия отдельно для каждого пользователя.
+// Реализации сериализуют запись и возвращают ошибки ввода-вывода вызывающему.
+type PresenceStore interface {
+	// Append добавляет запись в историю пользователя rec.UserID
+	Append(rec Record) error
+	// Last возвращает последнюю запись пользователя; ok равно false, если истории нет
+	Last(userID int64) (rec Record, ok bool, err error)
+	// History возвращает записи пользователя с RecordedAt в [from, to) в порядке добавления.
+	// Нулевые from и to не ограничивают диапазон.
+	History(userID int64, from, to time.Time) ([]Record, error)
+	// Delete удаляет всю историю пользователя
+	Delete(userID int64) error
+	// Close дожидается завершения записи и закрывает хранилище
+	Close() error
+}
+
+// Open открывает хранилище указанного вида по пути path
+func Open(kind, path string) (PresenceStore, error) {
+	switch kind {
+	case KindJSONL:
+		return OpenJSONL(path)
+	case KindBolt:
+		return OpenBolt(path)
+	default:
+		return nil, fmt.Errorf("неизвестный вид хранилища %q (ожидается %s или %s)", kind, KindJSONL, KindBolt)
+	}
+}
+
+// FromConfig открывает хранилище, заданное в конфигурации
+func FromConfig() (PresenceStore, error) {
+	return Open(config.PresenceStore, config.PresenceStorePath)
+}
+
+// inRange проверяет попадание времени в диапазон [from, to)
+func inRange(t, from, to time.Time) bool {
+	if !from.IsZero() && t.Before(from) {
+		return false
+	}
+	if !to.IsZero() && !t.Before(to) {
+		return false
+	}
+	return true
+}
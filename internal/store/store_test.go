@@ -0,0 +1,176 @@
+package store
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// openers открывают каждую реализацию в отдельном временном каталоге
+var openers = map[string]func(t *testing.T) PresenceStore{
+	KindJSONL: func(t *testing.T) PresenceStore {
+		s, err := OpenJSONL(filepath.Join(t.TempDir(), "presence"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	},
+	KindBolt: func(t *testing.T) PresenceStore {
+		s, err := OpenBolt(filepath.Join(t.TempDir(), "presence.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	},
+}
+
+func forEachStore(t *testing.T, test func(t *testing.T, s PresenceStore)) {
+	for kind, open := range openers {
+		t.Run(kind, func(t *testing.T) {
+			s := open(t)
+			t.Cleanup(func() { s.Close() })
+			test(t, s)
+		})
+	}
+}
+
+func record(userID, lastOnline int64, at time.Time) Record {
+	return Record{UserID: userID, LastOnline: lastOnline, RecordedAt: at}
+}
+
+func TestStoreKeyedPerUser(t *testing.T) {
+	base := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	forEachStore(t, func(t *testing.T, s PresenceStore) {
+		if _, ok, err := s.Last(1); err != nil || ok {
+			t.Fatalf("Last on empty store = %v, %v", ok, err)
+		}
+
+		for i, rec := range []Record{
+			record(1, 100, base),
+			record(2, 500, base.Add(time.Minute)),
+			record(1, 200, base.Add(2*time.Minute)),
+			record(1, 300, base.Add(3*time.Minute)),
+		} {
+			if err := s.Append(rec); err != nil {
+				t.Fatalf("Append #%d: %v", i, err)
+			}
+		}
+
+		last, ok, err := s.Last(1)
+		if err != nil || !ok || last.LastOnline != 300 {
+			t.Fatalf("Last(1) = %+v, %v, %v", last, ok, err)
+		}
+
+		all, err := s.History(1, time.Time{}, time.Time{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(all) != 3 || all[0].LastOnline != 100 || all[2].LastOnline != 300 {
+			t.Fatalf("History(1) = %+v", all)
+		}
+
+		ranged, err := s.History(1, base.Add(time.Minute), base.Add(3*time.Minute))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ranged) != 1 || ranged[0].LastOnline != 200 {
+			t.Fatalf("ranged History(1) = %+v", ranged)
+		}
+
+		if err := s.Delete(1); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok, err := s.Last(1); err != nil || ok {
+			t.Fatalf("Last after Delete = %v, %v", ok, err)
+		}
+		if other, err := s.History(2, time.Time{}, time.Time{}); err != nil || len(other) != 1 {
+			t.Fatalf("History(2) after Delete(1) = %+v, %v", other, err)
+		}
+		if err := s.Delete(42); err != nil {
+			t.Fatalf("Delete of unknown user: %v", err)
+		}
+	})
+}
+
+func TestStoreConcurrentAppends(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s PresenceStore) {
+		const writers, perWriter = 8, 25
+		var wg sync.WaitGroup
+		for w := range writers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range perWriter {
+					if err := s.Append(record(7, int64(w*perWriter+i), time.Now())); err != nil {
+						t.Error(err)
+						return
+					}
+				}
+			}()
+		}
+		wg.Wait()
+
+		all, err := s.History(7, time.Time{}, time.Time{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(all) != writers*perWriter {
+			t.Fatalf("got %d records, want %d", len(all), writers*perWriter)
+		}
+	})
+}
+
+func TestStoreClosed(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s PresenceStore) {
+		if err := s.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Append(record(1, 1, time.Now())); !errors.Is(err, ErrClosed) {
+			t.Fatalf("Append after Close = %v, want ErrClosed", err)
+		}
+	})
+}
+
+func TestJSONLReturnsIOErrors(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "presence")
+	s, err := OpenJSONL(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Каталог вместо файла пользователя делает запись невозможной
+	if err := os.Mkdir(filepath.Join(dir, "1.jsonl"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Append(record(1, 1, time.Now())); err == nil {
+		t.Fatal("Append must return an error instead of panicking")
+	}
+}
+
+func TestJSONLSkipsTruncatedTail(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "presence")
+	s, err := OpenJSONL(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Append(record(1, 100, time.Now())); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.OpenFile(filepath.Join(dir, "1.jsonl"), os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"user_id":1,"last_on`)
+	f.Close()
+
+	reopened, err := OpenJSONL(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	last, ok, err := reopened.Last(1)
+	if err != nil || !ok || last.LastOnline != 100 {
+		t.Fatalf("Last = %+v, %v, %v", last, ok, err)
+	}
+}
@@ -2,38 +2,131 @@ package telegram
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"telegram-api-with-go/internal/config"
 	"telegram-api-with-go/internal/logger"
+	"telegram-api-with-go/internal/proxy"
 
 	"github.com/gotd/td/telegram"
 	"github.com/gotd/td/telegram/auth"
 	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgerr"
 )
 
+// ErrUserNotFound возвращается, когда пользователь не найден
+var ErrUserNotFound = errors.New("пользователь не найден")
+
 // Client представляет клиент Telegram
 type Client struct {
+	apiID   int
+	apiHash string
+	opts    telegram.Options
+
+	mu     sync.RWMutex
 	client *telegram.Client
-	log    *slog.Logger
+
+	log       *slog.Logger
+	ready     chan struct{}
+	readyOnce sync.Once
+	onReady   []func()
+
+	presenceHandlers map[int]PresenceHandler
+	nextHandlerID    int
 }
 
 // NewClient создает новый экземпляр клиента Telegram
-func NewClient(sessionStorage telegram.SessionStorage) *Client {
-	client := telegram.NewClient(config.APIID, config.APIHash, telegram.Options{
+func NewClient(sessionStorage telegram.SessionStorage) (*Client, error) {
+	resolver, err := proxy.FromConfig().Resolver()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка настройки прокси: %w", err)
+	}
+
+	dispatcher := tg.NewUpdateDispatcher()
+	opts := telegram.Options{
 		SessionStorage: sessionStorage,
-	})
-	return &Client{
-		client: client,
-		log:    logger.Log,
+		Resolver:       resolver,
+		UpdateHandler:  dispatcher,
 	}
+	if err := applyDCOptions(&opts); err != nil {
+		return nil, err
+	}
+
+	c := &Client{
+		apiID:            config.APIID,
+		apiHash:          config.APIHash,
+		opts:             opts,
+		client:           telegram.NewClient(config.APIID, config.APIHash, opts),
+		log:              logger.Log,
+		ready:            make(chan struct{}),
+		presenceHandlers: make(map[int]PresenceHandler),
+	}
+	dispatcher.OnUserStatus(c.handleUserStatus)
+	return c, nil
+}
+
+// Ready возвращает канал, который закрывается после первой успешной авторизации клиента
+func (c *Client) Ready() <-chan struct{} {
+	return c.ready
+}
+
+// OnReady регистрирует функцию, вызываемую после каждой успешной авторизации
+func (c *Client) OnReady(f func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onReady = append(c.onReady, f)
 }
 
-// Run запускает клиент Telegram
+// AuthError - ошибка авторизации аккаунта, повторный запуск без вмешательства не поможет
+type AuthError struct {
+	Err error
+}
+
+func (e *AuthError) Error() string {
+	return "ошибка авторизации: " + e.Err.Error()
+}
+
+func (e *AuthError) Unwrap() error {
+	return e.Err
+}
+
+// authRejections - отказы Telegram в авторизации, которые не исправить повторной попыткой
+var authRejections = []string{
+	"PHONE_CODE_INVALID",
+	"PHONE_CODE_EXPIRED",
+	"PHONE_CODE_EMPTY",
+	"PHONE_NUMBER_INVALID",
+	"PHONE_NUMBER_BANNED",
+	"PHONE_NUMBER_UNOCCUPIED",
+	"PASSWORD_HASH_INVALID",
+	"SESSION_REVOKED",
+	"AUTH_KEY_UNREGISTERED",
+}
+
+// isAuthRejection сообщает, отказал ли Telegram в авторизации аккаунта
+func isAuthRejection(err error) bool {
+	return tgerr.Is(err, authRejections...) ||
+		errors.Is(err, auth.ErrPasswordInvalid) ||
+		errors.Is(err, auth.ErrPasswordNotProvided)
+}
+
+// Run запускает клиент Telegram. Остановленный клиент gotd нельзя запустить повторно,
+// поэтому каждый вызов Run создает новое подключение с той же сессией.
 func (c *Client) Run(ctx context.Context, clientAuth auth.UserAuthenticator) error {
-	return c.client.Run(ctx, func(ctx context.Context) error {
-		status, err := c.client.Auth().Status(ctx)
+	client := telegram.NewClient(c.apiID, c.apiHash, c.opts)
+	c.mu.Lock()
+	c.client = client
+	onReady := c.onReady
+	c.mu.Unlock()
+
+	return client.Run(ctx, func(ctx context.Context) error {
+		status, err := client.Auth().Status(ctx)
 		if err != nil {
 			c.log.Error("Ошибка получения статуса авторизации", "error", err)
 			return err
@@ -42,24 +135,51 @@ func (c *Client) Run(ctx context.Context, clientAuth auth.UserAuthenticator) err
 		if !status.Authorized {
 			c.log.Info("Требуется авторизация")
 			flow := auth.NewFlow(clientAuth, auth.SendCodeOptions{})
-			if err := c.client.Auth().IfNecessary(ctx, flow); err != nil {
+			if err := client.Auth().IfNecessary(ctx, flow); err != nil {
 				c.log.Error("Ошибка авторизации", "error", err)
-				return err
+				if isAuthRejection(err) {
+					return &AuthError{Err: err}
+				}
+				// Обрыв связи, FLOOD_WAIT или отмена контекста: перезапуск может помочь
+				return fmt.Errorf("авторизация: %w", err)
 			}
 		}
 
 		c.log.Info("Telegram клиент авторизован")
+		c.readyOnce.Do(func() { close(c.ready) })
+		for _, f := range onReady {
+			f()
+		}
 		<-ctx.Done()
 		return ctx.Err()
 	})
 }
 
-// GetChats получает список чатов
-func (c *Client) GetChats(ctx context.Context) ([]string, error) {
+// api возвращает RPC клиент текущего подключения
+func (c *Client) api() *tg.Client {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.client.API()
+}
+
+// Dialog - групповой чат из списка диалогов аккаунта
+type Dialog struct {
+	ID    int64
+	Title string
+	// Forbidden - аккаунт исключен из группы или группа недоступна
+	Forbidden bool
+	// Members - число участников; 0, если неизвестно
+	Members int
+	// Created - дата создания группы; нулевое значение, если неизвестна
+	Created time.Time
+}
+
+// GetDialogs получает групповые чаты из списка диалогов аккаунта
+func (c *Client) GetDialogs(ctx context.Context) ([]Dialog, error) {
 	c.log.Info("Запрос списка чатов")
-	var chats []string
+	var chats []Dialog
 
-	api := c.client.API()
+	api := c.api()
 	res, err := api.MessagesGetDialogs(ctx, &tg.MessagesGetDialogsRequest{
 		OffsetDate: 0,
 		OffsetPeer: &tg.InputPeerEmpty{},
@@ -85,18 +205,84 @@ func (c *Client) GetChats(ctx context.Context) ([]string, error) {
 	}
 
 	for _, chatObj := range dialogs.Chats {
-		var title string
 		switch chat := chatObj.(type) {
 		case *tg.Chat:
-			title = fmt.Sprintf("Группа: %s", chat.Title)
+			d := Dialog{ID: chat.ID, Title: chat.Title, Members: chat.ParticipantsCount}
+			if chat.Date != 0 {
+				d.Created = time.Unix(int64(chat.Date), 0)
+			}
+			chats = append(chats, d)
 		case *tg.ChatForbidden:
-			title = fmt.Sprintf("Запрещенная группа: %s", chat.Title)
-		}
-		if title != "" {
-			chats = append(chats, title)
+			chats = append(chats, Dialog{ID: chat.ID, Title: chat.Title, Forbidden: true})
 		}
 	}
 
 	c.log.Info("Получен список чатов", "count", len(chats))
 	return chats, nil
-} 
\ No newline at end of file
+}
+
+// GetUser получает информацию о пользователе по ID. Без access hash Telegram
+// находит только пользователей, уже известных аккаунту.
+func (c *Client) GetUser(ctx context.Context, userID int64) (*tg.User, error) {
+	return c.getUser(ctx, userID, 0)
+}
+
+// getUser получает информацию о пользователе по ID и access hash
+func (c *Client) getUser(ctx context.Context, userID, accessHash int64) (*tg.User, error) {
+	api := c.api()
+	users, err := api.UsersGetUsers(ctx, []tg.InputUserClass{
+		&tg.InputUser{
+			UserID:     userID,
+			AccessHash: accessHash,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ошибка UsersGetUsers: %w", err)
+	}
+
+	user, ok := tg.UserClassArray(users).FirstAsNotEmpty()
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrUserNotFound, userID)
+	}
+	return user, nil
+}
+
+// ResolveUser находит пользователя по @username или числовому ID
+func (c *Client) ResolveUser(ctx context.Context, ref string) (*tg.User, error) {
+	if username, ok := strings.CutPrefix(ref, "@"); ok {
+		res, err := c.api().ContactsResolveUsername(ctx, &tg.ContactsResolveUsernameRequest{
+			Username: username,
+		})
+		if err != nil {
+			if tgerr.Is(err, "USERNAME_NOT_OCCUPIED", "USERNAME_INVALID") {
+				return nil, ErrUserNotFound
+			}
+			return nil, fmt.Errorf("ошибка ContactsResolveUsername: %w", err)
+		}
+		for _, u := range res.Users {
+			if user, ok := u.AsNotEmpty(); ok {
+				return user, nil
+			}
+		}
+		return nil, ErrUserNotFound
+	}
+
+	userID, err := strconv.ParseInt(ref, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("некорректный идентификатор пользователя %q", ref)
+	}
+	return c.GetUser(ctx, userID)
+}
+
+// GetUserStatus получает статус присутствия пользователя по ID и access hash,
+// полученному при поиске пользователя
+func (c *Client) GetUserStatus(ctx context.Context, userID, accessHash int64) (tg.UserStatusClass, error) {
+	user, err := c.getUser(ctx, userID, accessHash)
+	if err != nil {
+		return nil, err
+	}
+	if user.Status == nil {
+		return &tg.UserStatusEmpty{}, nil
+	}
+	return user.Status, nil
+}
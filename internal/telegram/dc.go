@@ -0,0 +1,70 @@
+package telegram
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+
+	"telegram-api-with-go/internal/config"
+
+	"github.com/gotd/td/crypto"
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/telegram/dcs"
+	"github.com/gotd/td/tg"
+)
+
+// applyDCOptions настраивает список дата-центров и ключи сервера согласно конфигурации:
+// prod - рабочие ДЦ Telegram, test - тестовые ДЦ, custom - произвольный адрес
+func applyDCOptions(opts *telegram.Options) error {
+	opts.DC = config.DCID
+
+	switch config.DCMode {
+	case "", "prod":
+	case "test":
+		opts.DCList = dcs.Test()
+	case "custom":
+		host, portStr, err := net.SplitHostPort(config.DCAddr)
+		if err != nil {
+			return fmt.Errorf("некорректный адрес ДЦ %q: %w", config.DCAddr, err)
+		}
+		port, err := strconv.Atoi(portStr)
+		if err != nil {
+			return fmt.Errorf("некорректный порт ДЦ %q: %w", config.DCAddr, err)
+		}
+		ip := net.ParseIP(host)
+		if ip == nil {
+			return fmt.Errorf("адрес ДЦ должен быть IP-адресом: %q", host)
+		}
+		opts.DCList = dcs.List{
+			Options: []tg.DCOption{{
+				ID:        config.DCID,
+				Ipv6:      ip.To4() == nil,
+				Static:    true,
+				IPAddress: host,
+				Port:      port,
+			}},
+		}
+	default:
+		return fmt.Errorf("неизвестный режим ДЦ %q", config.DCMode)
+	}
+
+	if config.DCPublicKeyFile != "" {
+		data, err := os.ReadFile(config.DCPublicKeyFile)
+		if err != nil {
+			return fmt.Errorf("ошибка чтения ключа сервера: %w", err)
+		}
+		keys, err := crypto.ParseRSAPublicKeys(data)
+		if err != nil {
+			return fmt.Errorf("ошибка разбора ключа сервера: %w", err)
+		}
+		if len(keys) == 0 {
+			return fmt.Errorf("в файле %s нет публичных ключей", config.DCPublicKeyFile)
+		}
+		for _, key := range keys {
+			opts.PublicKeys = append(opts.PublicKeys, telegram.PublicKey{RSA: key})
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,468 @@
+package telegram
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"telegram-api-with-go/internal/config"
+	"telegram-api-with-go/internal/logger"
+
+	"github.com/gotd/td/session"
+	"github.com/gotd/td/telegram/auth"
+	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgerr"
+	"github.com/gotd/td/tgtest"
+	"github.com/gotd/td/tgtest/cluster"
+)
+
+const (
+	testDC    = 2
+	testPhone = "+99966" + "20000"
+	testCode  = "22222"
+)
+
+var (
+	testSelf = &tg.User{
+		ID:         100,
+		AccessHash: 100,
+		Self:       true,
+		FirstName:  "Owner",
+		Phone:      testPhone,
+	}
+	testTarget = &tg.User{
+		ID:         200,
+		AccessHash: 200,
+		FirstName:  "Target",
+		Username:   "target",
+		Status:     &tg.UserStatusOffline{WasOnline: 1700000000},
+	}
+
+	// testChats - чаты, которые находит contacts.resolveUsername
+	testChats = map[string]tg.ChatClass{
+		// Канал, в котором аккаунт - администратор
+		"channel": &tg.Channel{ID: 500, AccessHash: 500, Title: "Канал", Username: "channel",
+			Photo: &tg.ChatPhotoEmpty{}, AdminRights: tg.ChatAdminRights{InviteUsers: true}},
+		// Канал без прав администратора
+		"foreign": &tg.Channel{ID: 501, AccessHash: 501, Title: "Чужой канал", Username: "foreign",
+			Photo: &tg.ChatPhotoEmpty{}},
+		// Обычная группа, созданная аккаунтом
+		"group": &tg.Chat{ID: 3, Title: "Группа", Creator: true, Photo: &tg.ChatPhotoEmpty{},
+			ParticipantsCount: 3, Date: 1700000000},
+	}
+	testMemberUsers = []tg.UserClass{
+		&tg.User{ID: 301, AccessHash: 301, FirstName: "Анна", Username: "anna"},
+		&tg.User{ID: 302, AccessHash: 302, FirstName: "Helper", Username: "helper_bot", Bot: true},
+		&tg.User{ID: 303, AccessHash: 303, FirstName: "Борис", LastName: "Петров"},
+	}
+	// testParticipants - участники канала testChats["channel"] независимо от фильтра
+	testParticipants = []tg.ChannelParticipantClass{
+		&tg.ChannelParticipantCreator{UserID: 301},
+		&tg.ChannelParticipantAdmin{UserID: 302, PromotedBy: 301, Date: 1700000000},
+		&tg.ChannelParticipant{UserID: 303, Date: 1700000100},
+		&tg.ChannelParticipantBanned{Peer: &tg.PeerUser{UserID: 304}, KickedBy: 301, Date: 1700000200,
+			BannedRights: tg.ChatBannedRights{ViewMessages: true}},
+		&tg.ChannelParticipantBanned{Peer: &tg.PeerUser{UserID: 305}, KickedBy: 301, Date: 1700000300,
+			BannedRights: tg.ChatBannedRights{SendMessages: true}},
+		&tg.ChannelParticipantLeft{Peer: &tg.PeerUser{UserID: 306}},
+		// Ограниченный канал, а не пользователь, в список не попадает
+		&tg.ChannelParticipantBanned{Peer: &tg.PeerChannel{ChannelID: 9}, KickedBy: 301, Date: 1700000400},
+	}
+)
+
+// testParticipantsPage - размер страницы участников, которую отдает тестовый сервер
+const testParticipantsPage = 3
+
+// testServer - обработчики in-process MTProto сервера gotd
+type testServer struct {
+	authorized atomic.Bool
+	// targetHash - access hash из последнего запроса пользователя testTarget
+	targetHash atomic.Int64
+
+	mu sync.Mutex
+	// participantFilters - фильтры запросов channels.getParticipants по порядку
+	participantFilters []uint32
+}
+
+// takeParticipantFilters возвращает и сбрасывает фильтры запросов участников
+func (s *testServer) takeParticipantFilters() []uint32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	filters := s.participantFilters
+	s.participantFilters = nil
+	return filters
+}
+
+func (s *testServer) register(d *tgtest.Dispatcher) {
+	d.HandleFunc(tg.UsersGetUsersRequestTypeID, s.getUsers)
+	d.HandleFunc(tg.AuthSendCodeRequestTypeID, s.sendCode)
+	d.HandleFunc(tg.AuthSignInRequestTypeID, s.signIn)
+	d.HandleFunc(tg.MessagesGetDialogsRequestTypeID, s.getDialogs)
+	d.HandleFunc(tg.ContactsResolveUsernameRequestTypeID, s.resolveUsername)
+	d.HandleFunc(tg.ChannelsGetParticipantsRequestTypeID, s.getParticipants)
+	d.HandleFunc(tg.MessagesGetFullChatRequestTypeID, s.getFullChat)
+}
+
+func (s *testServer) getUsers(server *tgtest.Server, req *tgtest.Request) error {
+	var r tg.UsersGetUsersRequest
+	if err := r.Decode(req.Buf); err != nil {
+		return err
+	}
+	if !s.authorized.Load() {
+		return server.SendErr(req, tgerr.New(401, "AUTH_KEY_UNREGISTERED"))
+	}
+
+	var users []tg.UserClass
+	for _, id := range r.ID {
+		switch v := id.(type) {
+		case *tg.InputUserSelf:
+			users = append(users, testSelf)
+		case *tg.InputUser:
+			if v.UserID == testTarget.ID {
+				s.targetHash.Store(v.AccessHash)
+				users = append(users, testTarget)
+			}
+		}
+	}
+	return server.SendResult(req, &tg.UserClassVector{Elems: users})
+}
+
+func (s *testServer) sendCode(server *tgtest.Server, req *tgtest.Request) error {
+	var r tg.AuthSendCodeRequest
+	if err := r.Decode(req.Buf); err != nil {
+		return err
+	}
+	if r.PhoneNumber != testPhone {
+		return server.SendErr(req, tgerr.New(400, "PHONE_NUMBER_INVALID"))
+	}
+	return server.SendResult(req, &tg.AuthSentCode{
+		Type:          &tg.AuthSentCodeTypeApp{Length: len(testCode)},
+		PhoneCodeHash: "hash",
+	})
+}
+
+func (s *testServer) signIn(server *tgtest.Server, req *tgtest.Request) error {
+	var r tg.AuthSignInRequest
+	if err := r.Decode(req.Buf); err != nil {
+		return err
+	}
+	if r.PhoneCode != testCode || r.PhoneCodeHash != "hash" {
+		return server.SendErr(req, tgerr.New(400, "PHONE_CODE_INVALID"))
+	}
+	s.authorized.Store(true)
+	return server.SendResult(req, &tg.AuthAuthorization{User: testSelf})
+}
+
+func (s *testServer) getDialogs(server *tgtest.Server, req *tgtest.Request) error {
+	var r tg.MessagesGetDialogsRequest
+	if err := r.Decode(req.Buf); err != nil {
+		return err
+	}
+	return server.SendResult(req, &tg.MessagesDialogs{
+		Dialogs: []tg.DialogClass{
+			&tg.Dialog{Peer: &tg.PeerChat{ChatID: 1}},
+			&tg.Dialog{Peer: &tg.PeerChat{ChatID: 2}},
+		},
+		Chats: []tg.ChatClass{
+			&tg.Chat{ID: 1, Title: "Команда", Photo: &tg.ChatPhotoEmpty{}, ParticipantsCount: 5, Date: 1700000000},
+			&tg.ChatForbidden{ID: 2, Title: "Архив"},
+		},
+	})
+}
+
+func (s *testServer) resolveUsername(server *tgtest.Server, req *tgtest.Request) error {
+	var r tg.ContactsResolveUsernameRequest
+	if err := r.Decode(req.Buf); err != nil {
+		return err
+	}
+	chat, ok := testChats[r.Username]
+	if !ok {
+		return server.SendErr(req, tgerr.New(400, "USERNAME_NOT_OCCUPIED"))
+	}
+	var peer tg.PeerClass = &tg.PeerChat{ChatID: chat.GetID()}
+	if _, ok := chat.(*tg.Channel); ok {
+		peer = &tg.PeerChannel{ChannelID: chat.GetID()}
+	}
+	return server.SendResult(req, &tg.ContactsResolvedPeer{Peer: peer, Chats: []tg.ChatClass{chat}})
+}
+
+func (s *testServer) getParticipants(server *tgtest.Server, req *tgtest.Request) error {
+	var r tg.ChannelsGetParticipantsRequest
+	if err := r.Decode(req.Buf); err != nil {
+		return err
+	}
+	if input, ok := r.Channel.(*tg.InputChannel); !ok || input.ChannelID != 500 || input.AccessHash != 500 {
+		return server.SendErr(req, tgerr.New(400, "CHANNEL_INVALID"))
+	}
+	s.mu.Lock()
+	s.participantFilters = append(s.participantFilters, r.Filter.TypeID())
+	s.mu.Unlock()
+
+	from := min(r.Offset, len(testParticipants))
+	to := min(from+testParticipantsPage, len(testParticipants))
+	return server.SendResult(req, &tg.ChannelsChannelParticipants{
+		Count:        len(testParticipants),
+		Participants: testParticipants[from:to],
+		Users:        testMemberUsers,
+	})
+}
+
+func (s *testServer) getFullChat(server *tgtest.Server, req *tgtest.Request) error {
+	var r tg.MessagesGetFullChatRequest
+	if err := r.Decode(req.Buf); err != nil {
+		return err
+	}
+	group := testChats["group"]
+	if r.ChatID != group.GetID() {
+		return server.SendErr(req, tgerr.New(400, "CHAT_ID_INVALID"))
+	}
+	return server.SendResult(req, &tg.MessagesChatFull{
+		FullChat: &tg.ChatFull{
+			ID: group.GetID(),
+			Participants: &tg.ChatParticipants{
+				ChatID: group.GetID(),
+				Participants: []tg.ChatParticipantClass{
+					&tg.ChatParticipantCreator{UserID: 301},
+					&tg.ChatParticipantAdmin{UserID: 302, InviterID: 301, Date: 1700000000},
+					&tg.ChatParticipant{UserID: 303, InviterID: 301, Date: 1700000100},
+				},
+				Version: 1,
+			},
+		},
+		Chats: []tg.ChatClass{group},
+		Users: testMemberUsers,
+	})
+}
+
+// writePublicKey сохраняет ключ сервера в PEM файл для TELEGRAM_DC_PUBLIC_KEY
+func writePublicKey(t *testing.T, c *cluster.Cluster) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "server.pem")
+	var data []byte
+	for _, key := range c.Keys() {
+		data = append(data, pem.EncodeToMemory(&pem.Block{
+			Type:  "RSA PUBLIC KEY",
+			Bytes: x509.MarshalPKCS1PublicKey(key.RSA),
+		})...)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestClientIntegration(t *testing.T) {
+	logger.Log = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	c := cluster.NewCluster(cluster.Options{})
+	srv := &testServer{}
+	srv.register(c.Dispatch(testDC, "server"))
+
+	clusterErr := make(chan error, 1)
+	go func() { clusterErr <- c.Up(ctx) }()
+	select {
+	case <-c.Ready():
+	case err := <-clusterErr:
+		t.Fatalf("cluster: %v", err)
+	}
+
+	var addr string
+	for _, opt := range c.List().Options {
+		if opt.ID == testDC {
+			addr = net.JoinHostPort(opt.IPAddress, strconv.Itoa(opt.Port))
+		}
+	}
+
+	// Клиент настраивается через конфигурацию, как в режиме TELEGRAM_DC_MODE=custom
+	config.APIID = 1
+	config.APIHash = "hash"
+	config.DCMode = "custom"
+	config.DCID = testDC
+	config.DCAddr = addr
+	config.DCPublicKeyFile = writePublicKey(t, c)
+	config.ProxyURL = ""
+	config.MTProxyAddr = ""
+
+	client, err := NewClient(&session.StorageMemory{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	flow := auth.Constant(testPhone, "", auth.CodeAuthenticatorFunc(
+		func(ctx context.Context, sentCode *tg.AuthSentCode) (string, error) {
+			return testCode, nil
+		},
+	))
+
+	runCtx, stop := context.WithCancel(ctx)
+	runErr := make(chan error, 1)
+	go func() { runErr <- client.Run(runCtx, flow) }()
+
+	select {
+	case <-client.Ready():
+	case err := <-runErr:
+		t.Fatalf("run: %v", err)
+	case <-ctx.Done():
+		t.Fatal("client was not authorized in time")
+	}
+	if !srv.authorized.Load() {
+		t.Fatal("auth flow did not sign in")
+	}
+
+	t.Run("GetDialogs", func(t *testing.T) {
+		chats, err := client.GetDialogs(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []Dialog{
+			{ID: 1, Title: "Команда", Members: 5, Created: time.Unix(1700000000, 0)},
+			{ID: 2, Title: "Архив", Forbidden: true},
+		}
+		if len(chats) != len(want) {
+			t.Fatalf("got %v, want %v", chats, want)
+		}
+		for i := range want {
+			if chats[i] != want[i] {
+				t.Fatalf("chat %d = %+v, want %+v", i, chats[i], want[i])
+			}
+		}
+	})
+
+	t.Run("GetUser", func(t *testing.T) {
+		user, err := client.GetUser(ctx, testTarget.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if user.Username != testTarget.Username {
+			t.Fatalf("username = %q, want %q", user.Username, testTarget.Username)
+		}
+		status, ok := user.Status.(*tg.UserStatusOffline)
+		if !ok || status.WasOnline != 1700000000 {
+			t.Fatalf("unexpected status %#v", user.Status)
+		}
+
+		if _, err := client.GetUser(ctx, 404); err == nil {
+			t.Fatal("expected error for unknown user")
+		}
+	})
+
+	t.Run("GetUserStatus", func(t *testing.T) {
+		status, err := client.GetUserStatus(ctx, testTarget.ID, testTarget.AccessHash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := status.(*tg.UserStatusOffline); !ok {
+			t.Fatalf("unexpected status %#v", status)
+		}
+		if got := srv.targetHash.Load(); got != testTarget.AccessHash {
+			t.Fatalf("access hash = %d, want %d", got, testTarget.AccessHash)
+		}
+	})
+
+	t.Run("GetMembers", func(t *testing.T) {
+		// Фильтры команды соответствуют фильтрам channels.getParticipants,
+		// а участники запрашиваются постранично до общего числа
+		for filter, want := range map[ParticipantsFilter]uint32{
+			FilterRecent:     tg.ChannelParticipantsRecentTypeID,
+			FilterAdmins:     tg.ChannelParticipantsAdminsTypeID,
+			FilterBanned:     tg.ChannelParticipantsKickedTypeID,
+			FilterRestricted: tg.ChannelParticipantsBannedTypeID,
+			FilterBots:       tg.ChannelParticipantsBotsTypeID,
+		} {
+			res, err := client.GetMembers(ctx, "@channel", filter)
+			if err != nil {
+				t.Fatalf("%s: %v", filter, err)
+			}
+			if res.ChatID != 500 || res.Title != "Канал" || res.Filter != filter || len(res.Members) != 6 {
+				t.Fatalf("%s: result = %+v", filter, res)
+			}
+			filters := srv.takeParticipantFilters()
+			if len(filters) != 3 {
+				t.Fatalf("%s: %d pages requested, want 3", filter, len(filters))
+			}
+			for _, got := range filters {
+				if got != want {
+					t.Fatalf("%s: filter %#x, want %#x", filter, got, want)
+				}
+			}
+		}
+
+		res, err := client.GetMembers(ctx, "@channel", FilterRecent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		srv.takeParticipantFilters()
+		want := []Member{
+			{UserID: 301, Username: "anna", FirstName: "Анна", Role: RoleCreator},
+			{UserID: 302, Username: "helper_bot", FirstName: "Helper", Bot: true, Role: RoleAdmin, JoinedAt: time.Unix(1700000000, 0)},
+			{UserID: 303, FirstName: "Борис", LastName: "Петров", Role: RoleMember, JoinedAt: time.Unix(1700000100, 0)},
+			{UserID: 304, Role: RoleBanned, JoinedAt: time.Unix(1700000200, 0)},
+			{UserID: 305, Role: RoleRestricted, JoinedAt: time.Unix(1700000300, 0)},
+			{UserID: 306, Role: RoleLeft},
+		}
+		for i := range want {
+			if res.Members[i] != want[i] {
+				t.Fatalf("member %d = %+v, want %+v", i, res.Members[i], want[i])
+			}
+		}
+
+		// В обычной группе фильтр применяется к полному списку участников
+		for filter, wantIDs := range map[ParticipantsFilter][]int64{
+			FilterRecent: {301, 302, 303},
+			FilterAdmins: {301, 302},
+			FilterBots:   {302},
+			FilterBanned: nil,
+		} {
+			res, err := client.GetMembers(ctx, "@group", filter)
+			if err != nil {
+				t.Fatalf("group %s: %v", filter, err)
+			}
+			var ids []int64
+			for _, m := range res.Members {
+				ids = append(ids, m.UserID)
+			}
+			if res.ChatID != 3 || !slices.Equal(ids, wantIDs) {
+				t.Fatalf("group %s: chat %d, members %v, want %v", filter, res.ChatID, ids, wantIDs)
+			}
+		}
+		if filters := srv.takeParticipantFilters(); len(filters) != 0 {
+			t.Fatalf("group members requested with channels.getParticipants: %v", filters)
+		}
+
+		// Без прав администратора участники не запрашиваются
+		for _, ref := range []string{"@foreign", "1"} {
+			if _, err := client.GetMembers(ctx, ref, FilterRecent); !errors.Is(err, ErrNotChatAdmin) {
+				t.Fatalf("%s: err = %v, want ErrNotChatAdmin", ref, err)
+			}
+		}
+		if _, err := client.GetMembers(ctx, "999", FilterRecent); !errors.Is(err, ErrChatNotFound) {
+			t.Fatalf("unknown chat: err = %v", err)
+		}
+		if _, err := client.GetMembers(ctx, "@missing", FilterRecent); !tgerr.Is(err, "USERNAME_NOT_OCCUPIED") {
+			t.Fatalf("unknown username: err = %v", err)
+		}
+		if filters := srv.takeParticipantFilters(); len(filters) != 0 {
+			t.Fatalf("participants requested without admin rights: %v", filters)
+		}
+	})
+
+	stop()
+	if err := <-runErr; err != nil && !errors.Is(err, context.Canceled) {
+		t.Fatalf("run: %v", err)
+	}
+}
@@ -0,0 +1,42 @@
+package telegram
+
+import (
+	"context"
+
+	"github.com/gotd/td/tg"
+)
+
+// DialogLister получает список диалогов аккаунта
+type DialogLister interface {
+	GetDialogs(ctx context.Context) ([]Dialog, error)
+}
+
+// MemberLister получает участников администрируемых групп и каналов
+type MemberLister interface {
+	GetMembers(ctx context.Context, chatRef string, filter ParticipantsFilter) (*ChatMembers, error)
+}
+
+// UserResolver находит пользователя по ID или @username
+type UserResolver interface {
+	GetUser(ctx context.Context, userID int64) (*tg.User, error)
+	ResolveUser(ctx context.Context, ref string) (*tg.User, error)
+}
+
+// PresenceFetcher получает текущий статус присутствия пользователя.
+// accessHash - access hash пользователя из ResolveUser, 0 - неизвестен.
+type PresenceFetcher interface {
+	GetUserStatus(ctx context.Context, userID, accessHash int64) (tg.UserStatusClass, error)
+}
+
+// StatusProvider сообщает состояние подключения клиента
+type StatusProvider interface {
+	Status() ClientStatus
+}
+
+var (
+	_ DialogLister    = (*Client)(nil)
+	_ MemberLister    = (*Client)(nil)
+	_ UserResolver    = (*Client)(nil)
+	_ PresenceFetcher = (*Client)(nil)
+	_ StatusProvider  = (*Supervisor)(nil)
+)
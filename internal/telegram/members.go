@@ -0,0 +1,352 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gotd/td/telegram/query/dialogs"
+	"github.com/gotd/td/tg"
+)
+
+// participantsPageSize - максимальный размер страницы channels.getParticipants
+const participantsPageSize = 200
+
+// ParticipantsFilter определяет, каких участников запрашивать
+type ParticipantsFilter string
+
+const (
+	// FilterRecent - недавние участники (все участники для небольших чатов)
+	FilterRecent ParticipantsFilter = "recent"
+	// FilterAdmins - создатель и администраторы
+	FilterAdmins ParticipantsFilter = "admins"
+	// FilterBanned - исключенные (забаненные) участники
+	FilterBanned ParticipantsFilter = "banned"
+	// FilterRestricted - участники с ограничениями
+	FilterRestricted ParticipantsFilter = "restricted"
+	// FilterBots - боты
+	FilterBots ParticipantsFilter = "bots"
+)
+
+// ParticipantsFilters перечисляет все поддерживаемые фильтры
+var ParticipantsFilters = []ParticipantsFilter{
+	FilterRecent,
+	FilterAdmins,
+	FilterBanned,
+	FilterRestricted,
+	FilterBots,
+}
+
+// ParseParticipantsFilter преобразует строку в фильтр участников
+func ParseParticipantsFilter(s string) (ParticipantsFilter, error) {
+	if s == "" {
+		return FilterRecent, nil
+	}
+	for _, f := range ParticipantsFilters {
+		if string(f) == strings.ToLower(s) {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("неизвестный фильтр участников %q", s)
+}
+
+// MemberRole - роль участника в чате
+type MemberRole string
+
+const (
+	RoleCreator    MemberRole = "creator"
+	RoleAdmin      MemberRole = "admin"
+	RoleMember     MemberRole = "member"
+	RoleRestricted MemberRole = "restricted"
+	RoleBanned     MemberRole = "banned"
+	RoleLeft       MemberRole = "left"
+)
+
+var (
+	// ErrChatNotFound возвращается, если чат не найден среди диалогов аккаунта
+	ErrChatNotFound = errors.New("чат не найден")
+	// ErrNotChatAdmin возвращается, если аккаунт не администрирует чат
+	ErrNotChatAdmin = errors.New("аккаунт не является администратором чата")
+)
+
+// Member описывает участника группы или канала
+type Member struct {
+	UserID    int64
+	Username  string
+	FirstName string
+	LastName  string
+	Bot       bool
+	Role      MemberRole
+	JoinedAt  time.Time
+}
+
+// ChatMembers - список участников чата
+type ChatMembers struct {
+	ChatID  int64
+	Title   string
+	Filter  ParticipantsFilter
+	Members []Member
+}
+
+// GetMembers постранично получает участников администрируемой группы или канала.
+// chatRef - числовой ID чата из списка диалогов или @username.
+func (c *Client) GetMembers(ctx context.Context, chatRef string, filter ParticipantsFilter) (*ChatMembers, error) {
+	c.log.Info("Запрос участников чата", "chat", chatRef, "filter", filter)
+
+	chat, err := c.resolveChat(ctx, chatRef)
+	if err != nil {
+		c.log.Error("Ошибка поиска чата", "chat", chatRef, "error", err)
+		return nil, err
+	}
+
+	var result *ChatMembers
+	switch ch := chat.(type) {
+	case *tg.Channel:
+		if !ch.Creator && !hasAdminRights(ch.GetAdminRights) {
+			return nil, ErrNotChatAdmin
+		}
+		result, err = c.getChannelMembers(ctx, ch, filter)
+	case *tg.Chat:
+		if !ch.Creator && !hasAdminRights(ch.GetAdminRights) {
+			return nil, ErrNotChatAdmin
+		}
+		result, err = c.getChatMembers(ctx, ch, filter)
+	default:
+		return nil, fmt.Errorf("неподдерживаемый тип чата %T", chat)
+	}
+	if err != nil {
+		c.log.Error("Ошибка получения участников", "chat", chatRef, "error", err)
+		return nil, err
+	}
+
+	c.log.Info("Получен список участников",
+		"chat_id", result.ChatID,
+		"filter", filter,
+		"count", len(result.Members),
+	)
+	return result, nil
+}
+
+// resolveChat находит группу или канал по @username или ID из диалогов
+func (c *Client) resolveChat(ctx context.Context, chatRef string) (tg.ChatClass, error) {
+	api := c.api()
+
+	if username, ok := strings.CutPrefix(chatRef, "@"); ok {
+		res, err := api.ContactsResolveUsername(ctx, &tg.ContactsResolveUsernameRequest{
+			Username: username,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("ошибка ContactsResolveUsername: %w", err)
+		}
+		for _, chat := range res.Chats {
+			switch chat.(type) {
+			case *tg.Chat, *tg.Channel:
+				return chat, nil
+			}
+		}
+		return nil, ErrChatNotFound
+	}
+
+	chatID, err := strconv.ParseInt(chatRef, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("некорректный идентификатор чата %q", chatRef)
+	}
+
+	var found tg.ChatClass
+	errFound := errors.New("found")
+	err = dialogs.NewQueryBuilder(api).GetDialogs().BatchSize(100).ForEach(ctx,
+		func(ctx context.Context, elem dialogs.Elem) error {
+			if ch, ok := elem.Entities.Channels()[chatID]; ok {
+				found = ch
+				return errFound
+			}
+			if ch, ok := elem.Entities.Chats()[chatID]; ok {
+				found = ch
+				return errFound
+			}
+			return nil
+		})
+	if err != nil && !errors.Is(err, errFound) {
+		return nil, fmt.Errorf("ошибка получения диалогов: %w", err)
+	}
+	if found == nil {
+		return nil, ErrChatNotFound
+	}
+	return found, nil
+}
+
+// getChannelMembers постранично запрашивает участников супергруппы или канала
+func (c *Client) getChannelMembers(ctx context.Context, ch *tg.Channel, filter ParticipantsFilter) (*ChatMembers, error) {
+	api := c.api()
+	input := ch.AsInput()
+
+	var tgFilter tg.ChannelParticipantsFilterClass
+	switch filter {
+	case FilterRecent:
+		tgFilter = &tg.ChannelParticipantsRecent{}
+	case FilterAdmins:
+		tgFilter = &tg.ChannelParticipantsAdmins{}
+	case FilterBanned:
+		tgFilter = &tg.ChannelParticipantsKicked{}
+	case FilterRestricted:
+		tgFilter = &tg.ChannelParticipantsBanned{}
+	case FilterBots:
+		tgFilter = &tg.ChannelParticipantsBots{}
+	default:
+		return nil, fmt.Errorf("неизвестный фильтр участников %q", filter)
+	}
+
+	result := &ChatMembers{ChatID: ch.ID, Title: ch.Title, Filter: filter}
+	offset := 0
+	for {
+		res, err := api.ChannelsGetParticipants(ctx, &tg.ChannelsGetParticipantsRequest{
+			Channel: input,
+			Filter:  tgFilter,
+			Offset:  offset,
+			Limit:   participantsPageSize,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("ошибка ChannelsGetParticipants: %w", err)
+		}
+
+		page, ok := res.(*tg.ChannelsChannelParticipants)
+		if !ok {
+			return nil, fmt.Errorf("неожиданный тип результата %T", res)
+		}
+
+		users := tg.UserClassArray(page.Users).UserToMap()
+		for _, p := range page.Participants {
+			if m, ok := channelMember(p, users); ok {
+				result.Members = append(result.Members, m)
+			}
+		}
+
+		offset += len(page.Participants)
+		c.log.Debug("Получена страница участников",
+			"chat_id", ch.ID,
+			"offset", offset,
+			"total", page.Count,
+		)
+		if len(page.Participants) == 0 || offset >= page.Count {
+			break
+		}
+	}
+
+	return result, nil
+}
+
+// getChatMembers получает участников обычной группы и применяет фильтр локально
+func (c *Client) getChatMembers(ctx context.Context, chat *tg.Chat, filter ParticipantsFilter) (*ChatMembers, error) {
+	api := c.api()
+
+	res, err := api.MessagesGetFullChat(ctx, chat.ID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка MessagesGetFullChat: %w", err)
+	}
+
+	full, ok := res.FullChat.(*tg.ChatFull)
+	if !ok {
+		return nil, fmt.Errorf("неожиданный тип результата %T", res.FullChat)
+	}
+	participants, ok := full.Participants.(*tg.ChatParticipants)
+	if !ok {
+		return nil, fmt.Errorf("список участников группы недоступен")
+	}
+
+	result := &ChatMembers{ChatID: chat.ID, Title: chat.Title, Filter: filter}
+	users := tg.UserClassArray(res.Users).UserToMap()
+	for _, p := range participants.Participants {
+		m := Member{UserID: p.GetUserID(), Role: RoleMember}
+		switch v := p.(type) {
+		case *tg.ChatParticipantCreator:
+			m.Role = RoleCreator
+		case *tg.ChatParticipantAdmin:
+			m.Role = RoleAdmin
+			m.JoinedAt = unixTime(v.Date)
+		case *tg.ChatParticipant:
+			m.JoinedAt = unixTime(v.Date)
+		}
+		fillMemberUser(&m, users)
+
+		// В обычных группах нет списков исключенных и ограниченных участников
+		switch filter {
+		case FilterRecent:
+		case FilterAdmins:
+			if m.Role != RoleCreator && m.Role != RoleAdmin {
+				continue
+			}
+		case FilterBots:
+			if !m.Bot {
+				continue
+			}
+		default:
+			continue
+		}
+		result.Members = append(result.Members, m)
+	}
+
+	return result, nil
+}
+
+// channelMember преобразует участника канала в Member
+func channelMember(p tg.ChannelParticipantClass, users map[int64]*tg.User) (Member, bool) {
+	var m Member
+	switch v := p.(type) {
+	case *tg.ChannelParticipant:
+		m = Member{UserID: v.UserID, Role: RoleMember, JoinedAt: unixTime(v.Date)}
+	case *tg.ChannelParticipantSelf:
+		m = Member{UserID: v.UserID, Role: RoleMember, JoinedAt: unixTime(v.Date)}
+	case *tg.ChannelParticipantCreator:
+		m = Member{UserID: v.UserID, Role: RoleCreator}
+	case *tg.ChannelParticipantAdmin:
+		m = Member{UserID: v.UserID, Role: RoleAdmin, JoinedAt: unixTime(v.Date)}
+	case *tg.ChannelParticipantBanned:
+		user, ok := v.Peer.(*tg.PeerUser)
+		if !ok {
+			return Member{}, false
+		}
+		m = Member{UserID: user.UserID, Role: RoleRestricted, JoinedAt: unixTime(v.Date)}
+		if v.BannedRights.ViewMessages {
+			m.Role = RoleBanned
+		}
+	case *tg.ChannelParticipantLeft:
+		user, ok := v.Peer.(*tg.PeerUser)
+		if !ok {
+			return Member{}, false
+		}
+		m = Member{UserID: user.UserID, Role: RoleLeft}
+	default:
+		return Member{}, false
+	}
+	fillMemberUser(&m, users)
+	return m, true
+}
+
+// fillMemberUser дополняет участника данными пользователя
+func fillMemberUser(m *Member, users map[int64]*tg.User) {
+	user, ok := users[m.UserID]
+	if !ok {
+		return
+	}
+	m.Username = user.Username
+	m.FirstName = user.FirstName
+	m.LastName = user.LastName
+	m.Bot = user.Bot
+}
+
+// hasAdminRights проверяет наличие прав администратора
+func hasAdminRights(get func() (tg.ChatAdminRights, bool)) bool {
+	_, ok := get()
+	return ok
+}
+
+// unixTime преобразует unix-время Telegram в time.Time
+func unixTime(ts int) time.Time {
+	if ts == 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(ts), 0)
+}
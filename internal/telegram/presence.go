@@ -0,0 +1,53 @@
+package telegram
+
+import (
+	"context"
+
+	"github.com/gotd/td/tg"
+)
+
+// PresenceHandler получает изменения статуса пользователей из потока обновлений
+type PresenceHandler func(userID int64, status tg.UserStatusClass)
+
+// PresenceSubscriber позволяет подписаться на события UpdateUserStatus
+type PresenceSubscriber interface {
+	// SubscribePresence регистрирует обработчик и возвращает функцию отписки
+	SubscribePresence(h PresenceHandler) (unsubscribe func())
+}
+
+var _ PresenceSubscriber = (*Client)(nil)
+
+// SubscribePresence регистрирует обработчик событий UpdateUserStatus
+func (c *Client) SubscribePresence(h PresenceHandler) func() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.nextHandlerID++
+	id := c.nextHandlerID
+	c.presenceHandlers[id] = h
+
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		delete(c.presenceHandlers, id)
+	}
+}
+
+// handleUserStatus рассылает событие UpdateUserStatus подписчикам
+func (c *Client) handleUserStatus(ctx context.Context, e tg.Entities, update *tg.UpdateUserStatus) error {
+	c.mu.RLock()
+	handlers := make([]PresenceHandler, 0, len(c.presenceHandlers))
+	for _, h := range c.presenceHandlers {
+		handlers = append(handlers, h)
+	}
+	c.mu.RUnlock()
+
+	c.log.Debug("Получено обновление статуса пользователя",
+		"user_id", update.UserID,
+		"status", update.Status.TypeName(),
+	)
+	for _, h := range handlers {
+		h(update.UserID, update.Status)
+	}
+	return nil
+}
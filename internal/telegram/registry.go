@@ -0,0 +1,495 @@
+package telegram
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"telegram-api-with-go/internal/fileutil"
+	"telegram-api-with-go/internal/logger"
+	"telegram-api-with-go/internal/store"
+
+	"github.com/gotd/td/tg"
+)
+
+// ErrNotTracked возвращается, когда пользователь не отслеживается
+var ErrNotTracked = errors.New("пользователь не отслеживается")
+
+// ErrNoConsent возвращается при попытке следить за пользователем без его согласия
+var ErrNoConsent = errors.New("пользователь не давал согласия на отслеживание")
+
+// ConsentChecker сообщает, дал ли пользователь согласие на отслеживание
+type ConsentChecker interface {
+	Allowed(userID int64) bool
+}
+
+// TargetSettings - настройки слежения за отдельным пользователем
+type TargetSettings struct {
+	// PollInterval - интервал сверки статуса опросом, 0 - значение из конфигурации
+	PollInterval time.Duration `json:"poll_interval,omitempty"`
+}
+
+// Target - отслеживаемый пользователь
+type Target struct {
+	UserID    int64          `json:"user_id"`
+	Username  string         `json:"username,omitempty"`
+	FirstName string         `json:"first_name,omitempty"`
+	LastName  string         `json:"last_name,omitempty"`
+	Settings  TargetSettings `json:"settings"`
+	// AccessHash - access hash пользователя, без которого Telegram может не выдать его статус
+	AccessHash int64 `json:"access_hash,omitempty"`
+	// Subscribers - чаты, подписанные на пользователя
+	Subscribers []int64   `json:"subscribers"`
+	AddedAt     time.Time `json:"added_at"`
+	// Paused - слежение приостановлено командой /pause
+	Paused bool `json:"paused,omitempty"`
+}
+
+// Label возвращает имя пользователя для сообщений
+func (t Target) Label() string {
+	if t.Username != "" {
+		return fmt.Sprintf("@%s (%d)", t.Username, t.UserID)
+	}
+	name := strings.TrimSpace(t.FirstName + " " + t.LastName)
+	if name != "" {
+		return fmt.Sprintf("%s (%d)", name, t.UserID)
+	}
+	return strconv.FormatInt(t.UserID, 10)
+}
+
+// HasSubscriber сообщает, подписан ли чат на пользователя
+func (t Target) HasSubscriber(chatID int64) bool {
+	return slices.Contains(t.Subscribers, chatID)
+}
+
+// Matches сообщает, соответствует ли пользователь ссылке @username или ID
+func (t Target) Matches(ref string) bool {
+	if username, ok := strings.CutPrefix(ref, "@"); ok {
+		return t.Username != "" && strings.EqualFold(t.Username, username)
+	}
+	return strconv.FormatInt(t.UserID, 10) == ref
+}
+
+// Registry хранит отслеживаемых пользователей и их подписчиков
+// и управляет трекерами: по одному на пользователя.
+// Список сохраняется в файл, чтобы слежение возобновлялось после перезапуска.
+type Registry struct {
+	presence PresenceFetcher
+	updates  PresenceSubscriber
+	store    store.PresenceStore
+	path     string
+	log      *slog.Logger
+
+	mu       sync.Mutex
+	ctx      context.Context
+	targets  map[int64]*Target
+	trackers map[int64]*Tracker
+	// consent - nil, если согласие не проверяется
+	consent ConsentChecker
+
+	// Смены статуса рассылаются по копии списка пользователей под отдельной блокировкой:
+	// mu удерживается при остановке трекеров, которые в это время могут сообщать о переходе
+	dispatchMu   sync.RWMutex
+	subscribers  map[int64]Target
+	onTransition func(Target, Transition)
+}
+
+// NewRegistry создает реестр отслеживаемых пользователей с файлом path.
+// История статусов всех пользователей сохраняется в presenceStore.
+func NewRegistry(presence PresenceFetcher, updates PresenceSubscriber, presenceStore store.PresenceStore, path string) *Registry {
+	return &Registry{
+		presence: presence,
+		updates:  updates,
+		store:    presenceStore,
+		path:     path,
+		log:      logger.Log,
+		targets:  make(map[int64]*Target),
+		trackers: make(map[int64]*Tracker),
+	}
+}
+
+// Load читает сохраненный список пользователей. Отсутствие файла не является ошибкой.
+func (r *Registry) Load() error {
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("чтение реестра слежения: %w", err)
+	}
+
+	var targets []*Target
+	if err := json.Unmarshal(data, &targets); err != nil {
+		return fmt.Errorf("разбор реестра слежения %s: %w", r.path, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, t := range targets {
+		r.targets[t.UserID] = t
+	}
+	r.publishLocked()
+	r.log.Info("Загружен реестр слежения", "path", r.path, "targets", len(targets))
+	return nil
+}
+
+// OnTransition задает обработчик смен статуса. Обработчик получает пользователя
+// с актуальным списком подписчиков. Вызывается до Start.
+func (r *Registry) OnTransition(h func(Target, Transition)) {
+	r.dispatchMu.Lock()
+	defer r.dispatchMu.Unlock()
+	r.onTransition = h
+}
+
+// RequireConsent включает проверку согласия: трекеры пользователей без согласия
+// не запускаются, а Track возвращает ErrNoConsent. Вызывается до Start.
+func (r *Registry) RequireConsent(c ConsentChecker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.consent = c
+}
+
+// Start запускает слежение за всеми пользователями реестра, кроме приостановленных.
+// Пользователи, добавленные позже, отслеживаются сразу после добавления.
+// Повторный вызов не запускает дополнительных обработчиков.
+func (r *Registry) Start(ctx context.Context) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ctx = ctx
+	for _, t := range r.targets {
+		r.startLocked(t)
+	}
+}
+
+// Stop останавливает все трекеры и ждет завершения их обработчиков и записей
+func (r *Registry) Stop() {
+	r.mu.Lock()
+	trackers := make([]*Tracker, 0, len(r.trackers))
+	for _, tr := range r.trackers {
+		trackers = append(trackers, tr)
+	}
+	r.trackers = make(map[int64]*Tracker)
+	r.ctx = nil
+	r.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, tr := range trackers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tr.Stop()
+		}()
+	}
+	wg.Wait()
+	r.log.Info("Слежение за пользователями остановлено", "trackers", len(trackers))
+}
+
+// Pause приостанавливает слежение за пользователем, на которого подписан чат
+func (r *Registry) Pause(chatID int64, ref string) (Target, error) {
+	return r.setPaused(chatID, ref, true)
+}
+
+// Resume возобновляет приостановленное слежение за пользователем
+func (r *Registry) Resume(chatID int64, ref string) (Target, error) {
+	return r.setPaused(chatID, ref, false)
+}
+
+func (r *Registry) setPaused(chatID int64, ref string, paused bool) (Target, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t := r.findLocked(ref)
+	if t == nil || !t.HasSubscriber(chatID) {
+		return Target{}, ErrNotTracked
+	}
+	prev := t.Paused
+	t.Paused = paused
+	if err := r.saveLocked(); err != nil {
+		t.Paused = prev
+		return Target{}, err
+	}
+
+	if tr, ok := r.trackers[t.UserID]; ok {
+		if paused {
+			tr.Pause()
+		} else {
+			tr.Resume(r.ctx)
+		}
+	}
+	return t.clone(), nil
+}
+
+// Status возвращает состояние трекера пользователя.
+// Если реестр еще не запущен, трекер считается остановленным.
+func (r *Registry) Status(userID int64) TrackerStatus {
+	r.mu.Lock()
+	tr, ok := r.trackers[userID]
+	r.mu.Unlock()
+	if !ok {
+		return TrackerStatus{UserID: userID, State: TrackerStopped}
+	}
+	return tr.Status()
+}
+
+// Track подписывает чат на пользователя и начинает слежение, если оно еще не ведется.
+// added равно false, если чат уже был подписан.
+func (r *Registry) Track(chatID int64, user *tg.User, settings TargetSettings) (target Target, added bool, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.allowedLocked(user.ID) {
+		return Target{}, false, ErrNoConsent
+	}
+	t, existed := r.targets[user.ID]
+	var prev Target
+	if existed {
+		prev = t.clone()
+	} else {
+		t = &Target{UserID: user.ID, AddedAt: time.Now().UTC()}
+		r.targets[user.ID] = t
+	}
+	t.Username = user.Username
+	t.FirstName = user.FirstName
+	t.LastName = user.LastName
+	// Трекер, запущенный без access hash или с прежними настройками, перезапускается
+	restart := false
+	if user.AccessHash != 0 && user.AccessHash != t.AccessHash {
+		t.AccessHash = user.AccessHash
+		restart = true
+	}
+	if settings.PollInterval > 0 && settings != t.Settings {
+		t.Settings = settings
+		restart = true
+	}
+	if !t.HasSubscriber(chatID) {
+		t.Subscribers = append(t.Subscribers, chatID)
+		added = true
+	}
+
+	if err := r.saveLocked(); err != nil {
+		if existed {
+			*t = prev
+		} else {
+			delete(r.targets, user.ID)
+		}
+		return Target{}, false, err
+	}
+	if tr, ok := r.trackers[user.ID]; ok && restart {
+		tr.Stop()
+		delete(r.trackers, user.ID)
+	}
+	r.startLocked(t)
+	return t.clone(), added, nil
+}
+
+// Untrack отписывает чат от пользователя, найденного по @username или ID.
+// Слежение останавливается, когда у пользователя не остается подписчиков.
+func (r *Registry) Untrack(chatID int64, ref string) (Target, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t := r.findLocked(ref)
+	if t == nil || !t.HasSubscriber(chatID) {
+		return Target{}, ErrNotTracked
+	}
+	prev := t.clone()
+	t.Subscribers = slices.DeleteFunc(t.Subscribers, func(id int64) bool { return id == chatID })
+	last := len(t.Subscribers) == 0
+	if last {
+		delete(r.targets, t.UserID)
+	}
+
+	if err := r.saveLocked(); err != nil {
+		*t = prev
+		r.targets[t.UserID] = t
+		return Target{}, err
+	}
+	if last {
+		if tr, ok := r.trackers[t.UserID]; ok {
+			tr.Stop()
+			delete(r.trackers, t.UserID)
+		}
+		r.log.Info("Слежение за пользователем остановлено", "user_id", t.UserID)
+	}
+	return t.clone(), nil
+}
+
+// Activate запускает трекер пользователя из реестра, например после получения согласия.
+// Для пользователя, которого нет в реестре, ничего не делает.
+func (r *Registry) Activate(userID int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if t, ok := r.targets[userID]; ok {
+		r.startLocked(t)
+	}
+}
+
+// Remove прекращает слежение за пользователем во всех чатах и удаляет его из реестра.
+// Возвращает пользователя с подписчиками, которые были у него до удаления.
+func (r *Registry) Remove(userID int64) (Target, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t, ok := r.targets[userID]
+	if !ok {
+		return Target{}, ErrNotTracked
+	}
+	delete(r.targets, userID)
+	if err := r.saveLocked(); err != nil {
+		r.targets[userID] = t
+		return Target{}, err
+	}
+	if tr, ok := r.trackers[userID]; ok {
+		tr.Stop()
+		delete(r.trackers, userID)
+	}
+	r.log.Info("Пользователь удален из реестра слежения", "user_id", userID, "subscribers", len(t.Subscribers))
+	return t.clone(), nil
+}
+
+// Targets возвращает всех отслеживаемых пользователей, упорядоченных по ID
+func (r *Registry) Targets() []Target {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.sortedLocked()
+}
+
+// ForChat возвращает пользователей, на которых подписан чат
+func (r *Registry) ForChat(chatID int64) []Target {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var result []Target
+	for _, t := range r.sortedLocked() {
+		if t.HasSubscriber(chatID) {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+// Find возвращает пользователя, найденного по @username или ID, на которого подписан чат
+func (r *Registry) Find(chatID int64, ref string) (Target, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t := r.findLocked(ref)
+	if t == nil || !t.HasSubscriber(chatID) {
+		return Target{}, ErrNotTracked
+	}
+	return t.clone(), nil
+}
+
+// Metrics возвращает суммарные счетчики всех сервисов слежения
+func (r *Registry) Metrics() PresenceMetrics {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var total PresenceMetrics
+	for _, tr := range r.trackers {
+		m := tr.spy.Metrics()
+		total.Events += m.Events
+		total.Polls += m.Polls
+		total.EventTransitions += m.EventTransitions
+		total.PollTransitions += m.PollTransitions
+		total.ExpiryTransitions += m.ExpiryTransitions
+		total.DroppedEvents += m.DroppedEvents
+	}
+	return total
+}
+
+// startLocked создает трекер пользователя, если его еще нет, и запускает его
+func (r *Registry) startLocked(t *Target) {
+	if r.ctx == nil {
+		return
+	}
+	if !r.allowedLocked(t.UserID) {
+		r.log.Warn("Слежение не запущено: пользователь не давал согласия", "user_id", t.UserID)
+		return
+	}
+	tr, ok := r.trackers[t.UserID]
+	if !ok {
+		spy := NewSpyService(r.presence, r.updates, r.store, t.UserID)
+		spy.accessHash = t.AccessHash
+		if t.Settings.PollInterval > 0 {
+			spy.pollInterval = t.Settings.PollInterval
+		}
+		spy.onTransition = r.dispatchTransition
+		tr = NewTracker(spy)
+		r.trackers[t.UserID] = tr
+	}
+	if t.Paused {
+		tr.Pause()
+		return
+	}
+	tr.Start(r.ctx)
+}
+
+// dispatchTransition передает смену статуса обработчику вместе с подписчиками пользователя
+func (r *Registry) dispatchTransition(tr Transition) {
+	r.dispatchMu.RLock()
+	h := r.onTransition
+	target, ok := r.subscribers[tr.UserID]
+	r.dispatchMu.RUnlock()
+
+	if h != nil && ok {
+		h(target, tr)
+	}
+}
+
+// publishLocked обновляет копию списка пользователей для рассылки смен статуса
+func (r *Registry) publishLocked() {
+	subscribers := make(map[int64]Target, len(r.targets))
+	for id, t := range r.targets {
+		subscribers[id] = t.clone()
+	}
+	r.dispatchMu.Lock()
+	r.subscribers = subscribers
+	r.dispatchMu.Unlock()
+}
+
+func (r *Registry) allowedLocked(userID int64) bool {
+	return r.consent == nil || r.consent.Allowed(userID)
+}
+
+func (r *Registry) findLocked(ref string) *Target {
+	for _, t := range r.targets {
+		if t.Matches(ref) {
+			return t
+		}
+	}
+	return nil
+}
+
+func (r *Registry) sortedLocked() []Target {
+	result := make([]Target, 0, len(r.targets))
+	for _, t := range r.targets {
+		result = append(result, t.clone())
+	}
+	slices.SortFunc(result, func(a, b Target) int {
+		return cmp.Compare(a.UserID, b.UserID)
+	})
+	return result
+}
+
+// saveLocked атомарно перезаписывает файл реестра и после успешной записи
+// обновляет копию для рассылки. При ошибке вызывающий восстанавливает прежнее состояние.
+func (r *Registry) saveLocked() error {
+	if err := fileutil.WriteJSONAtomic(r.path, r.sortedLocked()); err != nil {
+		return fmt.Errorf("сохранение реестра слежения: %w", err)
+	}
+	r.publishLocked()
+	return nil
+}
+
+func (t *Target) clone() Target {
+	c := *t
+	c.Subscribers = slices.Clone(t.Subscribers)
+	return c
+}
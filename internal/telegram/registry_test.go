@@ -0,0 +1,257 @@
+package telegram_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"telegram-api-with-go/internal/logger"
+	"telegram-api-with-go/internal/presence"
+	"telegram-api-with-go/internal/store"
+	"telegram-api-with-go/internal/telegram"
+	"telegram-api-with-go/internal/telegram/telegramtest"
+
+	"github.com/gotd/td/tg"
+)
+
+// consentSet - согласия, заданные списком пользователей
+type consentSet map[int64]bool
+
+func (c consentSet) Allowed(userID int64) bool {
+	return c[userID]
+}
+
+func TestRegistryRequiresConsent(t *testing.T) {
+	logger.Log = slog.New(slog.NewTextHandler(io.Discard, nil))
+	path := filepath.Join(t.TempDir(), "trackers.json")
+	client := telegramtest.NewClient()
+	alice := &tg.User{ID: 2002, Username: "alice"}
+	bob := &tg.User{ID: 3003, Username: "bob"}
+
+	// Реестр без проверки согласия, как до ее появления
+	legacy := telegram.NewRegistry(client, client, nil, path)
+	for _, u := range []*tg.User{alice, bob} {
+		if _, _, err := legacy.Track(1, u, telegram.TargetSettings{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	consent := consentSet{alice.ID: true}
+	r := telegram.NewRegistry(client, client, nil, path)
+	r.RequireConsent(consent)
+	if err := r.Load(); err != nil {
+		t.Fatal(err)
+	}
+	r.Start(context.Background())
+	t.Cleanup(r.Stop)
+
+	if got := r.Status(alice.ID).State; got != telegram.TrackerRunning {
+		t.Fatalf("alice tracker = %s, want running", got)
+	}
+	if got := r.Status(bob.ID).State; got != telegram.TrackerStopped {
+		t.Fatalf("bob tracker = %s, want stopped without consent", got)
+	}
+
+	carol := &tg.User{ID: 4004, Username: "carol"}
+	if _, _, err := r.Track(1, carol, telegram.TargetSettings{}); !errors.Is(err, telegram.ErrNoConsent) {
+		t.Fatalf("Track without consent: err = %v", err)
+	}
+
+	consent[bob.ID] = true
+	r.Activate(bob.ID)
+	if got := r.Status(bob.ID).State; got != telegram.TrackerRunning {
+		t.Fatalf("bob tracker after consent = %s", got)
+	}
+
+	removed, err := r.Remove(bob.ID)
+	if err != nil || len(removed.Subscribers) != 1 {
+		t.Fatalf("Remove = %+v, %v", removed, err)
+	}
+	if got := r.Status(bob.ID).State; got != telegram.TrackerStopped {
+		t.Fatalf("bob tracker after remove = %s", got)
+	}
+	if _, err := r.Remove(bob.ID); !errors.Is(err, telegram.ErrNotTracked) {
+		t.Fatalf("second Remove: err = %v", err)
+	}
+}
+
+func TestRegistrySaveFailure(t *testing.T) {
+	logger.Log = slog.New(slog.NewTextHandler(io.Discard, nil))
+	dir := filepath.Join(t.TempDir(), "state")
+	if err := os.Mkdir(dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	client := telegramtest.NewClient()
+	client.SetUserStatus(2002, &tg.UserStatusOffline{WasOnline: 100})
+	alice := &tg.User{ID: 2002, Username: "alice"}
+	bob := &tg.User{ID: 3003, Username: "bob"}
+
+	r := telegram.NewRegistry(client, client, nil, filepath.Join(dir, "trackers.json"))
+	r.Start(context.Background())
+	t.Cleanup(r.Stop)
+	if _, _, err := r.Track(1, alice, telegram.TargetSettings{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Каталог реестра пропал: изменения не сохраняются и не применяются
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := r.Track(2, alice, telegram.TargetSettings{PollInterval: time.Minute}); err == nil {
+		t.Fatal("Track: save error expected")
+	}
+	if _, _, err := r.Track(1, bob, telegram.TargetSettings{}); err == nil {
+		t.Fatal("Track new user: save error expected")
+	}
+	if _, err := r.Untrack(1, "@alice"); err == nil {
+		t.Fatal("Untrack: save error expected")
+	}
+	if _, err := r.Pause(1, "@alice"); err == nil {
+		t.Fatal("Pause: save error expected")
+	}
+	if _, err := r.Remove(alice.ID); err == nil {
+		t.Fatal("Remove: save error expected")
+	}
+
+	targets := r.Targets()
+	if len(targets) != 1 || targets[0].UserID != alice.ID || !slices.Equal(targets[0].Subscribers, []int64{1}) ||
+		targets[0].Settings.PollInterval != 0 || targets[0].Paused {
+		t.Fatalf("targets after failed saves = %+v", targets)
+	}
+	if got := r.Status(alice.ID).State; got != telegram.TrackerRunning {
+		t.Fatalf("alice tracker = %s, want running", got)
+	}
+	if got := r.Status(bob.ID).State; got != telegram.TrackerStopped {
+		t.Fatalf("bob tracker = %s, want stopped", got)
+	}
+}
+
+func TestRegistryAccessHash(t *testing.T) {
+	logger.Log = slog.New(slog.NewTextHandler(io.Discard, nil))
+	path := filepath.Join(t.TempDir(), "trackers.json")
+	client := telegramtest.NewClient()
+	client.SetUserStatus(2002, &tg.UserStatusOffline{WasOnline: 100})
+	alice := &tg.User{ID: 2002, AccessHash: 77, Username: "alice"}
+
+	if _, _, err := telegram.NewRegistry(client, client, nil, path).Track(1, alice, telegram.TargetSettings{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Access hash сохраняется в реестре и передается при опросе статуса после перезапуска
+	r := telegram.NewRegistry(client, client, nil, path)
+	if err := r.Load(); err != nil {
+		t.Fatal(err)
+	}
+	target, err := r.Find(1, "@alice")
+	if err != nil || target.AccessHash != alice.AccessHash {
+		t.Fatalf("loaded target = %+v, %v", target, err)
+	}
+	r.Start(context.Background())
+	t.Cleanup(r.Stop)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !slices.Contains(client.CallLog(), "GetUserStatus:2002:77") {
+		if time.Now().After(deadline) {
+			t.Fatalf("calls = %v", client.CallLog())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// gatedStore задерживает запись статуса online, пока тест не откроет gate.
+// Запись идет в горутине трекера непосредственно перед рассылкой смены статуса.
+type gatedStore struct {
+	store.PresenceStore
+	entered chan struct{}
+	gate    chan struct{}
+}
+
+func (s *gatedStore) Append(rec store.Record) error {
+	if rec.Kind == presence.KindOnline {
+		s.entered <- struct{}{}
+		<-s.gate
+	}
+	return s.PresenceStore.Append(rec)
+}
+
+// TestRegistryStopDuringTransition проверяет, что остановка трекера под блокировкой
+// реестра не ждет вечно горутину трекера, которая в это время рассылает смену статуса
+func TestRegistryStopDuringTransition(t *testing.T) {
+	logger.Log = slog.New(slog.NewTextHandler(io.Discard, nil))
+	for _, tc := range []struct {
+		name string
+		stop func(r *telegram.Registry) error
+	}{
+		{"untrack", func(r *telegram.Registry) error { _, err := r.Untrack(1, "@alice"); return err }},
+		{"pause", func(r *telegram.Registry) error { _, err := r.Pause(1, "@alice"); return err }},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			history, err := store.OpenJSONL(filepath.Join(dir, "presence"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { history.Close() })
+			gated := &gatedStore{PresenceStore: history, entered: make(chan struct{}), gate: make(chan struct{})}
+
+			client := telegramtest.NewClient()
+			alice := &tg.User{ID: 2002, Username: "alice", Status: &tg.UserStatusOffline{WasOnline: 100}}
+			client.SetUser(alice)
+			r := telegram.NewRegistry(client, client, gated, filepath.Join(dir, "trackers.json"))
+			delivered := make(chan telegram.Transition, 4)
+			r.OnTransition(func(_ telegram.Target, tr telegram.Transition) { delivered <- tr })
+			r.Start(context.Background())
+			// При взаимоблокировке Stop не вернется: не даем ему задержать завершение теста
+			t.Cleanup(func() {
+				stopped := make(chan struct{})
+				go func() {
+					r.Stop()
+					close(stopped)
+				}()
+				select {
+				case <-stopped:
+				case <-time.After(5 * time.Second):
+				}
+			})
+			if _, _, err := r.Track(1, alice, telegram.TargetSettings{}); err != nil {
+				t.Fatal(err)
+			}
+
+			deadline := time.Now().Add(5 * time.Second)
+			for client.Subscribers() == 0 {
+				if time.Now().After(deadline) {
+					t.Fatal("tracker did not subscribe to presence events")
+				}
+				time.Sleep(5 * time.Millisecond)
+			}
+			go client.EmitStatus(alice.ID, &tg.UserStatusOnline{Expires: int(time.Now().Add(time.Hour).Unix())})
+			select {
+			case <-gated.entered:
+			case <-time.After(5 * time.Second):
+				t.Fatal("transition was not recorded")
+			}
+
+			// Остановка захватывает блокировку реестра и ждет горутину трекера,
+			// которая после записи истории рассылает смену статуса
+			done := make(chan error, 1)
+			go func() { done <- tc.stop(r) }()
+			time.Sleep(50 * time.Millisecond)
+			close(gated.gate)
+
+			select {
+			case err := <-done:
+				if err != nil {
+					t.Fatal(err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("deadlock: tracker stop is waiting for a transition blocked on the registry lock")
+			}
+		})
+	}
+}
@@ -2,170 +2,307 @@ package telegram
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
 	"log/slog"
-	"os"
+	"math/rand/v2"
+	"sync"
 	"time"
 
+	"telegram-api-with-go/internal/config"
 	"telegram-api-with-go/internal/logger"
+	"telegram-api-with-go/internal/presence"
+	"telegram-api-with-go/internal/store"
 
 	"github.com/gotd/td/tg"
 )
 
-// SpyService представляет сервис для слежения за пользователем
-type SpyService struct {
-	client *Client
-	userID int64
-	log    *slog.Logger
-	lastOnline uint8
+// PresenceSource - источник сведений о статусе пользователя
+type PresenceSource string
+
+const (
+	// SourceEvent - событие UpdateUserStatus из потока обновлений MTProto
+	SourceEvent PresenceSource = "event"
+	// SourcePoll - периодическая сверка через UsersGetUsers
+	SourcePoll PresenceSource = "poll"
+	// SourceExpiry - истечение срока статуса online без нового события
+	SourceExpiry PresenceSource = "expiry"
+)
+
+// PresenceMetrics - счетчики сервиса слежения по источникам
+type PresenceMetrics struct {
+	// Events - количество полученных событий UpdateUserStatus
+	Events int64
+	// Polls - количество выполненных опросов
+	Polls int64
+	// EventTransitions - смены статуса, обнаруженные по событиям
+	EventTransitions int64
+	// PollTransitions - смены статуса, обнаруженные при опросе
+	PollTransitions int64
+	// ExpiryTransitions - переходы в offline по истечении срока статуса online
+	ExpiryTransitions int64
+	// DroppedEvents - события, отброшенные из-за переполнения очереди
+	DroppedEvents int64
 }
 
-type StoredUserEvent struct {
-	UserID     int64 `json:"user_id"`
-	LastOnline int64 `json:"last_online"`
+// Transition - смена статуса пользователя
+type Transition struct {
+	UserID int64
+	// From - предыдущий статус; нулевой, если статус еще не был известен
+	From   presence.Status
+	To     presence.Status
+	Source PresenceSource
+	At     time.Time
 }
 
-// NewSpyService создает новый сервис слежения
-func NewSpyService(client *Client, userID int64) *SpyService {
+// TransitionHandler получает смены статуса пользователя
+type TransitionHandler func(Transition)
+
+// SpyService представляет сервис для слежения за пользователем.
+// Статус обновляется по событиям UpdateUserStatus, а периодический опрос
+// используется только для сверки и восстановления пропущенных событий.
+type SpyService struct {
+	presence PresenceFetcher
+	updates  PresenceSubscriber
+	store    store.PresenceStore
+	userID   int64
+	log      *slog.Logger
+	// accessHash - access hash пользователя для опроса статуса, 0 - неизвестен
+	accessHash int64
+
+	pollInterval time.Duration
+	pollJitter   time.Duration
+
+	mu         sync.Mutex
+	metrics    PresenceMetrics
+	lastStatus presence.Status
+	lastCheck  time.Time
+	lastErr    error
+
+	now          func() time.Time
+	onTransition TransitionHandler
+}
+
+// NewSpyService создает новый сервис слежения.
+// updates может быть nil, тогда статус отслеживается только опросом.
+// История сохраняется в presenceStore; nil отключает сохранение.
+func NewSpyService(presence PresenceFetcher, updates PresenceSubscriber, presenceStore store.PresenceStore, userID int64) *SpyService {
 	return &SpyService{
-		client: client,
-		userID: userID,
-		log:    logger.Log,
+		presence:     presence,
+		updates:      updates,
+		store:        presenceStore,
+		userID:       userID,
+		log:          logger.Log,
+		pollInterval: config.PresencePollInterval,
+		pollJitter:   config.PresencePollJitter,
+		now:          time.Now,
 	}
 }
 
 // StartSpying начинает слежение за пользователем
 func (s *SpyService) StartSpying(ctx context.Context) {
-	s.log.Info("Начало слежения за пользователем", "user_id", s.userID)
+	s.log.Info("Начало слежения за пользователем",
+		"user_id", s.userID,
+		"poll_interval", s.pollInterval,
+		"poll_jitter", s.pollJitter,
+	)
+
+	events := make(chan tg.UserStatusClass, 16)
+	if s.updates != nil {
+		unsubscribe := s.updates.SubscribePresence(func(userID int64, status tg.UserStatusClass) {
+			if userID != s.userID {
+				return
+			}
+			select {
+			case events <- status:
+			default:
+				s.mu.Lock()
+				s.metrics.DroppedEvents++
+				s.mu.Unlock()
+				s.log.Warn("Очередь событий статуса переполнена", "user_id", userID)
+			}
+		})
+		defer unsubscribe()
+	}
+
+	// Первичная сверка, чтобы узнать текущий статус до первого события
+	s.checkUserStatus(ctx)
 
-	ticker := time.NewTicker(10 * time.Second)
-	defer ticker.Stop()
+	timer := time.NewTimer(s.nextPollDelay())
+	defer timer.Stop()
+
+	// expiry срабатывает, когда истекает срок статуса online
+	expiry := time.NewTimer(0)
+	defer expiry.Stop()
+	resetExpiry := func() {
+		if !expiry.Stop() {
+			select {
+			case <-expiry.C:
+			default:
+			}
+		}
+		if delay, ok := s.expiryDelay(); ok {
+			expiry.Reset(delay)
+		}
+	}
+	resetExpiry()
 
 	for {
 		select {
 		case <-ctx.Done():
 			s.log.Info("Завершение слежения за пользователем", "user_id", s.userID)
 			return
-		case <-ticker.C:
+		case status := <-events:
+			s.mu.Lock()
+			s.metrics.Events++
+			s.mu.Unlock()
+			s.observe(status, SourceEvent)
+			resetExpiry()
+		case <-timer.C:
 			s.checkUserStatus(ctx)
+			timer.Reset(s.nextPollDelay())
+			resetExpiry()
+		case <-expiry.C:
+			s.expire()
 		}
 	}
 }
 
-// checkUserStatus проверяет статус пользователя
-func (s *SpyService) checkUserStatus(ctx context.Context) {
-	api := s.client.client.API()
-	
-	// Получаем информацию о пользователе
-	user, err := api.UsersGetUsers(ctx, []tg.InputUserClass{
-		&tg.InputUser{
-			UserID: s.userID,
-		},
-	})
+// nextPollDelay возвращает интервал до следующей сверки со случайным отклонением
+func (s *SpyService) nextPollDelay() time.Duration {
+	delay := s.pollInterval
+	if s.pollJitter > 0 {
+		delay += time.Duration(rand.Int64N(int64(2*s.pollJitter))) - s.pollJitter
+	}
+	if delay < time.Second {
+		delay = time.Second
+	}
+	return delay
+}
 
+// checkUserStatus сверяет статус пользователя опросом
+func (s *SpyService) checkUserStatus(ctx context.Context) {
+	// Получаем статус пользователя
+	status, err := s.presence.GetUserStatus(ctx, s.userID, s.accessHash)
+	s.mu.Lock()
+	s.lastCheck = time.Now()
+	s.lastErr = err
+	s.mu.Unlock()
 	if err != nil {
-		s.log.Error("Ошибка получения информации о пользователе", 
+		s.log.Error("Ошибка получения информации о пользователе",
 			"user_id", s.userID,
 			"error", err,
 		)
 		return
 	}
 
-	userStatuses, _ := user[0].AsNotEmpty()
-	isOffline, ok := userStatuses.Status.(*tg.UserStatusOffline)
+	s.mu.Lock()
+	s.metrics.Polls++
+	s.mu.Unlock()
+	s.observe(status, SourcePoll)
+}
 
-	if !ok {
-		s.log.Info("Пользователь онлайн", "user_id", s.userID)
-	} else {
-		s.lastOnline = uint8(isOffline.GetWasOnline())
-		fmt.Println("Пользователь offline", s.lastOnline)
-		go saveStatusToFile(s.userID, int64(isOffline.GetWasOnline()))
+// observe обрабатывает статус из указанного источника и учитывает смену статуса
+func (s *SpyService) observe(raw tg.UserStatusClass, source PresenceSource) {
+	now := s.now()
+	status := presence.FromTG(raw, now)
+	// Опрос может вернуть online с уже истекшим сроком: это offline с момента истечения
+	if offline, ok := status.Expired(now); ok {
+		status = offline
 	}
+	s.apply(status, source)
 }
 
-// GetUserID возвращает ID отслеживаемого пользователя
-func (s *SpyService) GetUserID() int64 {
-	return s.userID
-} 
+// expire переводит пользователя в offline, если срок статуса online истек без нового события
+func (s *SpyService) expire() {
+	s.mu.Lock()
+	current := s.lastStatus
+	s.mu.Unlock()
 
-func getLastStatus(fileName string) (*StoredUserEvent, error) {
-	file, err := os.Open(fileName)
-	if err != nil {
-		// Если файл не существует, возвращаем nil без ошибки.
-		if os.IsNotExist(err) {
-			return nil, nil
-		}
-		return nil, err
-	}
-	defer file.Close()
-
-	var event StoredUserEvent
-	if err := json.NewDecoder(file).Decode(&event); err != nil {
-		return nil, err
+	if offline, ok := current.Expired(s.now()); ok {
+		s.apply(offline, SourceExpiry)
 	}
-	return &event, nil
 }
 
-// updateLastStatus обновляет файл с последним статусом.
-func updateLastStatus(fileName string, event StoredUserEvent) error {
-	file, err := os.OpenFile(fileName, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
-	if err != nil {
-		return err
+// apply сохраняет и записывает в историю статус, если состояние пользователя изменилось
+func (s *SpyService) apply(status presence.Status, source PresenceSource) {
+	s.mu.Lock()
+	previous := s.lastStatus
+	changed := !previous.SameState(status)
+	if changed {
+		switch source {
+		case SourceEvent:
+			s.metrics.EventTransitions++
+		case SourcePoll:
+			s.metrics.PollTransitions++
+		case SourceExpiry:
+			s.metrics.ExpiryTransitions++
+		}
 	}
-	defer file.Close()
+	// Online сохраняется и без смены состояния, чтобы учитывать продленный Expires
+	s.lastStatus = status
+	s.mu.Unlock()
 
-	return json.NewEncoder(file).Encode(event)
-}
+	if !changed {
+		return
+	}
 
-// saveStatusToFile добавляет новую запись в историю статусов,
-// только если новый lastOnline отличается от предыдущего.
-func saveStatusToFile(userID int64, lastOnline int64) {
-	const (
-		historyFile   = "user_status.json"
-		lastStatusFile = "last_status.json"
+	s.log.Info("Изменился статус пользователя",
+		"user_id", s.userID,
+		"status", string(status.Kind),
+		"previous", string(previous.Kind),
+		"description", status.String(),
+		"source", source,
 	)
+	s.saveStatus(status)
 
-	// Проверяем последний статус из отдельного файла.
-	lastStatus, err := getLastStatus(lastStatusFile)
-	if err != nil {
-		panic(err)
-	}
-
-	// Если последний статус уже соответствует новому, выходим.
-	if lastStatus != nil && lastStatus.LastOnline == lastOnline {
-		return
+	if s.onTransition != nil {
+		s.onTransition(Transition{
+			UserID: s.userID,
+			From:   previous,
+			To:     status,
+			Source: source,
+			At:     s.now(),
+		})
 	}
+}
 
-	// Формируем новый статус.
-	newEvent := StoredUserEvent{
-		UserID:     userID,
-		LastOnline: lastOnline,
+// expiryDelay возвращает время до истечения статуса online; ok равно false для остальных статусов
+func (s *SpyService) expiryDelay() (time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lastStatus.Kind != presence.KindOnline || s.lastStatus.Expires.IsZero() {
+		return 0, false
 	}
+	return max(s.lastStatus.Expires.Sub(s.now()), 0), true
+}
 
-	// Открываем файл истории для дозаписи.
-	history, err := os.OpenFile(historyFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0666)
-	if err != nil {
-		panic(err)
+// saveStatus добавляет запись в историю, если она отличается от последней сохраненной.
+// Запись выполняется в горутине обработчика, поэтому остановка трекера дожидается ее завершения.
+func (s *SpyService) saveStatus(status presence.Status) {
+	if s.store == nil {
+		return
 	}
-	defer history.Close()
-
-	// Записываем новый статус в файл истории.
-	// Запишем как одну JSON-строку с переводом строки в конце.
-	eventData, err := json.Marshal(newEvent)
+	last, ok, err := s.store.Last(s.userID)
 	if err != nil {
-		panic(err)
-	}
-	if _, err := history.Write(eventData); err != nil {
-		panic(err)
+		s.log.Error("Ошибка чтения истории статусов", "user_id", s.userID, "error", err)
+		return
 	}
-	if _, err := history.Write([]byte("\n")); err != nil {
-		panic(err)
+	if ok && last.Status().SameState(status) {
+		return
 	}
 
-	// Обновляем файл с последним статусом.
-	if err := updateLastStatus(lastStatusFile, newEvent); err != nil {
-		panic(err)
+	if err := s.store.Append(store.NewRecord(s.userID, status, s.now())); err != nil {
+		s.log.Error("Ошибка сохранения статуса", "user_id", s.userID, "error", err)
 	}
 }
 
+// Metrics возвращает счетчики сервиса слежения
+func (s *SpyService) Metrics() PresenceMetrics {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.metrics
+}
+
+// GetUserID возвращает ID отслеживаемого пользователя
+func (s *SpyService) GetUserID() int64 {
+	return s.userID
+}
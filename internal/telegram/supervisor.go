@@ -0,0 +1,192 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"telegram-api-with-go/internal/config"
+	"telegram-api-with-go/internal/logger"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/gotd/td/telegram/auth"
+	"github.com/gotd/td/tgerr"
+)
+
+// ClientState - состояние клиента Telegram под управлением супервизора
+type ClientState int
+
+const (
+	// StateStarting - первое подключение еще не завершено
+	StateStarting ClientState = iota
+	// StateReady - клиент подключен и авторизован
+	StateReady
+	// StateReconnecting - клиент остановился с временной ошибкой и ждет перезапуска
+	StateReconnecting
+	// StateFailed - клиент остановлен из-за неустранимой ошибки
+	StateFailed
+	// StateStopped - супервизор завершил работу
+	StateStopped
+)
+
+func (s ClientState) String() string {
+	switch s {
+	case StateStarting:
+		return "starting"
+	case StateReady:
+		return "ready"
+	case StateReconnecting:
+		return "reconnecting"
+	case StateFailed:
+		return "failed"
+	case StateStopped:
+		return "stopped"
+	default:
+		return "unknown"
+	}
+}
+
+// fatalErrors - ошибки Telegram, после которых перезапуск не имеет смысла
+var fatalErrors = []string{
+	"AUTH_KEY_UNREGISTERED",
+	"AUTH_KEY_INVALID",
+	"AUTH_KEY_DUPLICATED",
+	"SESSION_REVOKED",
+	"SESSION_EXPIRED",
+	"USER_DEACTIVATED",
+	"USER_DEACTIVATED_BAN",
+	"PHONE_NUMBER_BANNED",
+	"API_ID_INVALID",
+	"API_ID_PUBLISHED_FLOOD",
+}
+
+// IsFatal сообщает, является ли ошибка клиента неустранимой
+// (отозванная сессия, блокировка аккаунта, неверные учетные данные API)
+func IsFatal(err error) bool {
+	if err == nil {
+		return false
+	}
+	var authErr *AuthError
+	if errors.As(err, &authErr) {
+		return true
+	}
+	return tgerr.Is(err, fatalErrors...)
+}
+
+// ClientStatus - снимок состояния клиента для отображения пользователям
+type ClientStatus struct {
+	State ClientState
+	// Err - последняя ошибка клиента
+	Err error
+	// Restarts - количество перезапусков подряд без успешного подключения
+	Restarts int
+	// NextRestart - время следующей попытки в состоянии StateReconnecting
+	NextRestart time.Time
+}
+
+// Supervisor перезапускает клиент Telegram с экспоненциальной задержкой и джиттером
+type Supervisor struct {
+	// run - один запуск клиента до его остановки
+	run func(ctx context.Context) error
+	log *slog.Logger
+
+	initialDelay time.Duration
+	maxDelay     time.Duration
+
+	mu     sync.RWMutex
+	status ClientStatus
+}
+
+// NewSupervisor создает супервизор клиента Telegram
+func NewSupervisor(client *Client, clientAuth auth.UserAuthenticator) *Supervisor {
+	s := &Supervisor{
+		run: func(ctx context.Context) error {
+			return client.Run(ctx, clientAuth)
+		},
+		log:          logger.Log,
+		initialDelay: config.RestartInitialDelay,
+		maxDelay:     config.RestartMaxDelay,
+	}
+	client.OnReady(s.markReady)
+	return s
+}
+
+// Status возвращает текущее состояние клиента
+func (s *Supervisor) Status() ClientStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.status
+}
+
+// Run запускает клиент и перезапускает его после временных ошибок.
+// Возвращает неустранимую ошибку клиента или ошибку контекста.
+func (s *Supervisor) Run(ctx context.Context) error {
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = s.initialDelay
+	b.MaxInterval = s.maxDelay
+	b.RandomizationFactor = 0.5
+	b.MaxElapsedTime = 0
+	b.Reset()
+
+	for {
+		s.log.Info("Запуск Telegram клиента", "restarts", s.Status().Restarts)
+		err := s.run(ctx)
+
+		if ctx.Err() != nil {
+			s.setStatus(ClientStatus{State: StateStopped})
+			return ctx.Err()
+		}
+		if err == nil {
+			err = errors.New("клиент остановился без ошибки")
+		}
+		if IsFatal(err) {
+			s.log.Error("Неустранимая ошибка Telegram клиента, перезапуск отменен", "error", err)
+			s.setStatus(ClientStatus{State: StateFailed, Err: err})
+			return err
+		}
+
+		// Если клиент успел подключиться, считаем сбой новым и начинаем задержки сначала
+		status := s.Status()
+		if status.State == StateReady {
+			b.Reset()
+			status.Restarts = 0
+		}
+
+		delay := b.NextBackOff()
+		status = ClientStatus{
+			State:       StateReconnecting,
+			Err:         err,
+			Restarts:    status.Restarts + 1,
+			NextRestart: time.Now().Add(delay),
+		}
+		s.setStatus(status)
+		s.log.Warn("Telegram клиент остановлен, перезапуск",
+			"error", err,
+			"restarts", status.Restarts,
+			"delay", delay.Round(time.Millisecond),
+		)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			s.setStatus(ClientStatus{State: StateStopped})
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// markReady отмечает успешное подключение клиента
+func (s *Supervisor) markReady() {
+	s.setStatus(ClientStatus{State: StateReady})
+	s.log.Info("Telegram клиент готов к работе")
+}
+
+func (s *Supervisor) setStatus(status ClientStatus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = status
+}
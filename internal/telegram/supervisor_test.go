@@ -0,0 +1,165 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/gotd/td/telegram/auth"
+	"github.com/gotd/td/tgerr"
+)
+
+// scriptedSupervisor возвращает супервизор, каждый запуск клиента которого
+// сообщается в calls и завершается ошибкой, отправленной в results
+func scriptedSupervisor() (s *Supervisor, calls chan struct{}, results chan error) {
+	calls = make(chan struct{})
+	results = make(chan error)
+	s = &Supervisor{
+		run: func(ctx context.Context) error {
+			select {
+			case calls <- struct{}{}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			select {
+			case err := <-results:
+				return err
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+		log:          slog.New(slog.NewTextHandler(io.Discard, nil)),
+		initialDelay: 10 * time.Millisecond,
+		maxDelay:     40 * time.Millisecond,
+	}
+	return s, calls, results
+}
+
+func waitCall(t *testing.T, calls chan struct{}) {
+	t.Helper()
+	select {
+	case <-calls:
+	case <-time.After(5 * time.Second):
+		t.Fatal("client was not restarted")
+	}
+}
+
+func TestIsFatal(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		err   error
+		fatal bool
+	}{
+		{"nil", nil, false},
+		{"network", errors.New("connection reset by peer"), false},
+		{"flood wait", fmt.Errorf("авторизация: %w", tgerr.New(420, "FLOOD_WAIT_5")), false},
+		{"canceled login", fmt.Errorf("авторизация: %w", context.Canceled), false},
+		{"revoked session", tgerr.New(401, "SESSION_REVOKED"), true},
+		{"auth rejection", &AuthError{Err: tgerr.New(400, "PHONE_CODE_INVALID")}, true},
+	} {
+		if got := IsFatal(tc.err); got != tc.fatal {
+			t.Errorf("%s: IsFatal = %v, want %v", tc.name, got, tc.fatal)
+		}
+	}
+}
+
+func TestIsAuthRejection(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want bool
+	}{
+		{tgerr.New(400, "PHONE_CODE_INVALID"), true},
+		{tgerr.New(401, "AUTH_KEY_UNREGISTERED"), true},
+		{fmt.Errorf("sign in: %w", auth.ErrPasswordInvalid), true},
+		{tgerr.New(420, "FLOOD_WAIT_30"), false},
+		{io.ErrUnexpectedEOF, false},
+		{context.DeadlineExceeded, false},
+	} {
+		if got := isAuthRejection(tc.err); got != tc.want {
+			t.Errorf("isAuthRejection(%v) = %v, want %v", tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestSupervisorBackoff(t *testing.T) {
+	s, calls, results := scriptedSupervisor()
+	done := make(chan error, 1)
+	go func() { done <- s.Run(context.Background()) }()
+
+	// Временные ошибки увеличивают счетчик перезапусков
+	waitCall(t, calls)
+	results <- errors.New("connection reset by peer")
+	waitCall(t, calls)
+	if st := s.Status(); st.State != StateReconnecting || st.Restarts != 1 {
+		t.Fatalf("status after network error = %+v", st)
+	}
+	results <- tgerr.New(420, "FLOOD_WAIT_5")
+	waitCall(t, calls)
+	st := s.Status()
+	if st.State != StateReconnecting || st.Restarts != 2 || !tgerr.Is(st.Err, "FLOOD_WAIT") {
+		t.Fatalf("status after flood wait = %+v", st)
+	}
+	// Задержка не превышает максимальную с учетом джиттера
+	if wait := time.Until(st.NextRestart); wait > 60*time.Millisecond {
+		t.Fatalf("next restart in %s", wait)
+	}
+
+	// После успешного подключения задержки начинаются сначала
+	s.markReady()
+	results <- errors.New("connection lost")
+	waitCall(t, calls)
+	if st := s.Status(); st.State != StateReconnecting || st.Restarts != 1 {
+		t.Fatalf("status after reset = %+v", st)
+	}
+
+	// Отказ в авторизации останавливает перезапуски
+	revoked := tgerr.New(401, "SESSION_REVOKED")
+	results <- revoked
+	select {
+	case err := <-done:
+		if !errors.Is(err, revoked) {
+			t.Fatalf("run = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("supervisor restarted after a fatal error")
+	}
+	if st := s.Status(); st.State != StateFailed {
+		t.Fatalf("status after fatal error = %+v", st)
+	}
+}
+
+func TestSupervisorStop(t *testing.T) {
+	s, calls, results := scriptedSupervisor()
+	s.initialDelay = time.Hour
+	s.maxDelay = time.Hour
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Run(ctx) }()
+
+	// Отмена во время ожидания перезапуска останавливает супервизор
+	waitCall(t, calls)
+	results <- fmt.Errorf("авторизация: %w", tgerr.New(420, "FLOOD_WAIT_5"))
+	deadline := time.Now().Add(5 * time.Second)
+	for s.Status().State != StateReconnecting {
+		if time.Now().After(deadline) {
+			t.Fatalf("status = %+v", s.Status())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("run = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("supervisor did not stop")
+	}
+	if st := s.Status(); st.State != StateStopped {
+		t.Fatalf("status after stop = %+v", st)
+	}
+}
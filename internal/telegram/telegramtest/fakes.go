@@ -0,0 +1,218 @@
+package telegramtest
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"telegram-api-with-go/internal/telegram"
+
+	"github.com/gotd/td/tg"
+)
+
+// Client - фейковый MTProto клиент, реализующий интерфейсы пакета telegram
+type Client struct {
+	mu sync.Mutex
+
+	// Dialogs - результат GetDialogs
+	Dialogs []telegram.Dialog
+	// DialogsErr - ошибка GetDialogs
+	DialogsErr error
+	// Members - результаты GetMembers по ссылке на чат
+	Members map[string]*telegram.ChatMembers
+	// MembersErr - ошибка GetMembers
+	MembersErr error
+	// Users - пользователи для GetUser и GetUserStatus
+	Users map[int64]*tg.User
+	// Calls - журнал вызовов в формате "Метод:аргумент"
+	Calls []string
+
+	handlers      map[int]telegram.PresenceHandler
+	nextHandlerID int
+}
+
+// NewClient создает пустой фейковый клиент
+func NewClient() *Client {
+	return &Client{
+		Members:  make(map[string]*telegram.ChatMembers),
+		Users:    make(map[int64]*tg.User),
+		handlers: make(map[int]telegram.PresenceHandler),
+	}
+}
+
+// GetDialogs возвращает заданный список чатов
+func (c *Client) GetDialogs(ctx context.Context) ([]telegram.Dialog, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Calls = append(c.Calls, "GetDialogs")
+	if c.DialogsErr != nil {
+		return nil, c.DialogsErr
+	}
+	return append([]telegram.Dialog(nil), c.Dialogs...), nil
+}
+
+// GetMembers возвращает участников, заданных для chatRef
+func (c *Client) GetMembers(ctx context.Context, chatRef string, filter telegram.ParticipantsFilter) (*telegram.ChatMembers, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Calls = append(c.Calls, fmt.Sprintf("GetMembers:%s:%s", chatRef, filter))
+	if c.MembersErr != nil {
+		return nil, c.MembersErr
+	}
+	members, ok := c.Members[chatRef]
+	if !ok {
+		return nil, telegram.ErrChatNotFound
+	}
+	result := *members
+	result.Filter = filter
+	return &result, nil
+}
+
+// GetUser возвращает заданного пользователя
+func (c *Client) GetUser(ctx context.Context, userID int64) (*tg.User, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Calls = append(c.Calls, fmt.Sprintf("GetUser:%d", userID))
+	user, ok := c.Users[userID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", telegram.ErrUserNotFound, userID)
+	}
+	copied := *user
+	return &copied, nil
+}
+
+// ResolveUser находит заданного пользователя по @username или ID
+func (c *Client) ResolveUser(ctx context.Context, ref string) (*tg.User, error) {
+	if username, ok := strings.CutPrefix(ref, "@"); ok {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.Calls = append(c.Calls, "ResolveUser:"+ref)
+		for _, user := range c.Users {
+			if strings.EqualFold(user.Username, username) {
+				copied := *user
+				return &copied, nil
+			}
+		}
+		return nil, telegram.ErrUserNotFound
+	}
+	userID, err := strconv.ParseInt(ref, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("некорректный идентификатор пользователя %q", ref)
+	}
+	return c.GetUser(ctx, userID)
+}
+
+// GetUserStatus возвращает статус заданного пользователя
+func (c *Client) GetUserStatus(ctx context.Context, userID, accessHash int64) (tg.UserStatusClass, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Calls = append(c.Calls, fmt.Sprintf("GetUserStatus:%d:%d", userID, accessHash))
+	user, ok := c.Users[userID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", telegram.ErrUserNotFound, userID)
+	}
+	if user.Status == nil {
+		return &tg.UserStatusEmpty{}, nil
+	}
+	return user.Status, nil
+}
+
+// SetUser добавляет или заменяет пользователя
+func (c *Client) SetUser(user *tg.User) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Users[user.ID] = user
+}
+
+// SetUserStatus меняет статус присутствия пользователя
+func (c *Client) SetUserStatus(userID int64, status tg.UserStatusClass) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	user, ok := c.Users[userID]
+	if !ok {
+		user = &tg.User{ID: userID}
+		c.Users[userID] = user
+	}
+	user.Status = status
+}
+
+// SubscribePresence регистрирует обработчик событий статуса
+func (c *Client) SubscribePresence(h telegram.PresenceHandler) func() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.nextHandlerID++
+	id := c.nextHandlerID
+	c.handlers[id] = h
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		delete(c.handlers, id)
+	}
+}
+
+// EmitStatus меняет статус пользователя и рассылает событие подписчикам,
+// как это делает поток обновлений MTProto
+func (c *Client) EmitStatus(userID int64, status tg.UserStatusClass) {
+	c.SetUserStatus(userID, status)
+
+	c.mu.Lock()
+	handlers := make([]telegram.PresenceHandler, 0, len(c.handlers))
+	for _, h := range c.handlers {
+		handlers = append(handlers, h)
+	}
+	c.mu.Unlock()
+
+	for _, h := range handlers {
+		h(userID, status)
+	}
+}
+
+// Subscribers возвращает количество активных подписчиков на события статуса
+func (c *Client) Subscribers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.handlers)
+}
+
+// CallLog возвращает копию журнала вызовов
+func (c *Client) CallLog() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.Calls...)
+}
+
+// Status - фейковый источник состояния клиента
+type Status struct {
+	mu     sync.Mutex
+	status telegram.ClientStatus
+}
+
+// NewStatus создает источник состояния с заданным состоянием
+func NewStatus(state telegram.ClientState) *Status {
+	return &Status{status: telegram.ClientStatus{State: state}}
+}
+
+// Status возвращает текущее состояние
+func (s *Status) Status() telegram.ClientStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
+
+// Set меняет состояние
+func (s *Status) Set(status telegram.ClientStatus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = status
+}
+
+var (
+	_ telegram.DialogLister       = (*Client)(nil)
+	_ telegram.MemberLister       = (*Client)(nil)
+	_ telegram.UserResolver       = (*Client)(nil)
+	_ telegram.PresenceFetcher    = (*Client)(nil)
+	_ telegram.PresenceSubscriber = (*Client)(nil)
+	_ telegram.StatusProvider     = (*Status)(nil)
+)
@@ -0,0 +1,164 @@
+package telegram
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"telegram-api-with-go/internal/presence"
+)
+
+// TrackerState - состояние слежения за пользователем
+type TrackerState int
+
+const (
+	// TrackerStopped - слежение не запущено или остановлено
+	TrackerStopped TrackerState = iota
+	// TrackerRunning - слежение ведется
+	TrackerRunning
+	// TrackerPaused - слежение приостановлено и может быть возобновлено
+	TrackerPaused
+)
+
+func (s TrackerState) String() string {
+	switch s {
+	case TrackerStopped:
+		return "stopped"
+	case TrackerRunning:
+		return "running"
+	case TrackerPaused:
+		return "paused"
+	default:
+		return "unknown"
+	}
+}
+
+// TrackerStatus - снимок состояния слежения за пользователем
+type TrackerStatus struct {
+	UserID int64
+	State  TrackerState
+	// Since - время последней смены состояния
+	Since time.Time
+	// LastStatus - последний известный статус пользователя, нулевой если неизвестен
+	LastStatus presence.Status
+	// LastCheck - время последнего опроса
+	LastCheck time.Time
+	// LastErr - ошибка последнего опроса
+	LastErr error
+	Metrics PresenceMetrics
+}
+
+// Tracker управляет жизненным циклом сервиса слежения за одним пользователем.
+// Все методы идемпотентны: повторный Start не запускает второй обработчик,
+// а Stop и Pause дожидаются завершения обработчика и незавершенных записей.
+type Tracker struct {
+	spy *SpyService
+
+	mu     sync.Mutex
+	state  TrackerState
+	since  time.Time
+	parent context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewTracker создает остановленный трекер для сервиса слежения
+func NewTracker(spy *SpyService) *Tracker {
+	return &Tracker{spy: spy, since: time.Now()}
+}
+
+// Start запускает слежение. Если трекер уже запущен или приостановлен, ничего не делает.
+func (t *Tracker) Start(ctx context.Context) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.state != TrackerStopped {
+		return
+	}
+	t.parent = ctx
+	t.runLocked()
+	t.setStateLocked(TrackerRunning)
+}
+
+// Stop останавливает слежение и ждет завершения обработчика
+func (t *Tracker) Stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.state == TrackerStopped {
+		return
+	}
+	t.haltLocked()
+	t.parent = nil
+	t.setStateLocked(TrackerStopped)
+}
+
+// Pause приостанавливает слежение и ждет завершения обработчика.
+// Приостановить можно и остановленный трекер, тогда Start его не запустит.
+func (t *Tracker) Pause() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.state == TrackerPaused {
+		return
+	}
+	t.haltLocked()
+	t.setStateLocked(TrackerPaused)
+}
+
+// Resume возобновляет приостановленное слежение с контекстом последнего Start
+func (t *Tracker) Resume(ctx context.Context) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.state != TrackerPaused {
+		return
+	}
+	if t.parent == nil {
+		t.parent = ctx
+	}
+	t.runLocked()
+	t.setStateLocked(TrackerRunning)
+}
+
+// Status возвращает текущее состояние трекера
+func (t *Tracker) Status() TrackerStatus {
+	t.mu.Lock()
+	status := TrackerStatus{
+		UserID: t.spy.userID,
+		State:  t.state,
+		Since:  t.since,
+	}
+	t.mu.Unlock()
+
+	t.spy.mu.Lock()
+	defer t.spy.mu.Unlock()
+	status.LastStatus = t.spy.lastStatus
+	status.LastCheck = t.spy.lastCheck
+	status.LastErr = t.spy.lastErr
+	status.Metrics = t.spy.metrics
+	return status
+}
+
+func (t *Tracker) runLocked() {
+	ctx, cancel := context.WithCancel(t.parent)
+	done := make(chan struct{})
+	t.cancel = cancel
+	t.done = done
+	go func() {
+		defer close(done)
+		t.spy.StartSpying(ctx)
+	}()
+}
+
+// haltLocked отменяет обработчик и ждет его завершения
+func (t *Tracker) haltLocked() {
+	if t.cancel == nil {
+		return
+	}
+	t.cancel()
+	<-t.done
+	t.cancel = nil
+	t.done = nil
+}
+
+func (t *Tracker) setStateLocked(state TrackerState) {
+	t.state = state
+	t.since = time.Now()
+}
@@ -0,0 +1,197 @@
+package telegram_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"telegram-api-with-go/internal/logger"
+	"telegram-api-with-go/internal/presence"
+	"telegram-api-with-go/internal/store"
+	"telegram-api-with-go/internal/telegram"
+	"telegram-api-with-go/internal/telegram/telegramtest"
+
+	"github.com/gotd/td/tg"
+)
+
+func newTestTracker(t *testing.T) (*telegram.Tracker, *telegramtest.Client) {
+	tracker, client, _ := newTestTrackerWithStore(t)
+	return tracker, client
+}
+
+func newTestTrackerWithStore(t *testing.T) (*telegram.Tracker, *telegramtest.Client, store.PresenceStore) {
+	t.Helper()
+	logger.Log = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	presenceStore, err := store.OpenJSONL(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := telegramtest.NewClient()
+	client.SetUserStatus(1001, &tg.UserStatusOffline{WasOnline: 100})
+	tracker := telegram.NewTracker(telegram.NewSpyService(client, client, presenceStore, 1001))
+	t.Cleanup(tracker.Stop)
+	return tracker, client, presenceStore
+}
+
+func waitSubscribers(t *testing.T, client *telegramtest.Client, want int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for client.Subscribers() != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("subscribers = %d, want %d", client.Subscribers(), want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestTrackerLifecycle(t *testing.T) {
+	tracker, client := newTestTracker(t)
+	ctx := context.Background()
+
+	if got := tracker.Status().State; got != telegram.TrackerStopped {
+		t.Fatalf("initial state = %s", got)
+	}
+
+	tracker.Start(ctx)
+	tracker.Start(ctx)
+	waitSubscribers(t, client, 1)
+	if got := tracker.Status().State; got != telegram.TrackerRunning {
+		t.Fatalf("state after Start = %s", got)
+	}
+
+	// Pause дожидается завершения обработчика, поэтому отписка уже произошла
+	tracker.Pause()
+	tracker.Pause()
+	if n := client.Subscribers(); n != 0 {
+		t.Fatalf("subscribers after Pause = %d", n)
+	}
+	if got := tracker.Status().State; got != telegram.TrackerPaused {
+		t.Fatalf("state after Pause = %s", got)
+	}
+
+	// Start не запускает приостановленный трекер
+	tracker.Start(ctx)
+	if got := tracker.Status().State; got != telegram.TrackerPaused {
+		t.Fatalf("state after Start on paused tracker = %s", got)
+	}
+
+	tracker.Resume(ctx)
+	tracker.Resume(ctx)
+	waitSubscribers(t, client, 1)
+
+	tracker.Stop()
+	tracker.Stop()
+	if n := client.Subscribers(); n != 0 {
+		t.Fatalf("subscribers after Stop = %d", n)
+	}
+	status := tracker.Status()
+	if status.State != telegram.TrackerStopped {
+		t.Fatalf("state after Stop = %s", status.State)
+	}
+	if status.Metrics.Polls < 2 || status.LastCheck.IsZero() {
+		t.Fatalf("status = %+v, want at least one poll per run", status)
+	}
+}
+
+func TestTrackerStopsWithContext(t *testing.T) {
+	tracker, client := newTestTracker(t)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	tracker.Start(ctx)
+	waitSubscribers(t, client, 1)
+	cancel()
+	waitSubscribers(t, client, 0)
+
+	// Stop после отмены контекста не блокируется
+	tracker.Stop()
+}
+
+func TestTrackerRecordsEveryStatusKind(t *testing.T) {
+	tracker, client, presenceStore := newTestTrackerWithStore(t)
+	tracker.Start(context.Background())
+	waitSubscribers(t, client, 1)
+
+	expires := int(time.Now().Add(time.Hour).Unix())
+	for _, status := range []tg.UserStatusClass{
+		&tg.UserStatusOnline{Expires: expires},
+		&tg.UserStatusOnline{Expires: expires + 60}, // продление, не переход
+		&tg.UserStatusRecently{},
+		&tg.UserStatusRecently{}, // повтор, не переход
+		&tg.UserStatusLastWeek{},
+		&tg.UserStatusLastMonth{},
+		&tg.UserStatusEmpty{},
+		&tg.UserStatusOffline{WasOnline: 1_700_000_000},
+	} {
+		client.EmitStatus(1001, status)
+	}
+
+	want := []presence.Kind{
+		presence.KindOffline, // первичный опрос
+		presence.KindOnline,
+		presence.KindRecently,
+		presence.KindLastWeek,
+		presence.KindLastMonth,
+		presence.KindEmpty,
+		presence.KindOffline,
+	}
+	records := waitRecords(t, presenceStore, len(want))
+	for i, rec := range records {
+		if rec.Kind != want[i] {
+			t.Fatalf("record %d kind = %s, want %s (all: %+v)", i, rec.Kind, want[i], records)
+		}
+	}
+	if last := records[len(records)-1]; last.LastOnline != 1_700_000_000 {
+		t.Fatalf("last_online = %d, timestamp must not be truncated", last.LastOnline)
+	}
+	if rec := records[2]; rec.LastOnline != 0 || rec.SeenFrom == 0 || rec.SeenTo <= rec.SeenFrom {
+		t.Fatalf("recently record = %+v, want approximate range", rec)
+	}
+	if m := tracker.Status().Metrics; m.EventTransitions != 6 || m.Events != 8 {
+		t.Fatalf("metrics = %+v", m)
+	}
+}
+
+func TestTrackerExpiresOnlineStatus(t *testing.T) {
+	tracker, client, presenceStore := newTestTrackerWithStore(t)
+	tracker.Start(context.Background())
+	waitSubscribers(t, client, 1)
+
+	// Telegram может не прислать переход в offline, тогда статус истекает по Expires
+	expires := time.Now().Add(time.Second).Truncate(time.Second).Add(time.Second)
+	client.EmitStatus(1001, &tg.UserStatusOnline{Expires: int(expires.Unix())})
+
+	records := waitRecords(t, presenceStore, 3)
+	if records[1].Kind != presence.KindOnline || records[2].Kind != presence.KindOffline {
+		t.Fatalf("records = %+v", records)
+	}
+	if records[2].LastOnline != expires.Unix() {
+		t.Fatalf("expired offline at %d, want %d", records[2].LastOnline, expires.Unix())
+	}
+	// Истечение фиксирует либо таймер, либо опрос, вернувший online с истекшим сроком,
+	// но переход в offline записывается один раз
+	if m := tracker.Status().Metrics; m.ExpiryTransitions+m.PollTransitions != 2 {
+		t.Fatalf("metrics = %+v", m)
+	}
+}
+
+func waitRecords(t *testing.T, s store.PresenceStore, n int) []store.Record {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		records, err := s.History(1001, time.Time{}, time.Time{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(records) >= n {
+			return records
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d records, want %d: %+v", len(records), n, records)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}